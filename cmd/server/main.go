@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 
 	"wallet-tracker/internal/config"
@@ -10,6 +12,7 @@ import (
 	"wallet-tracker/internal/service"
 	"wallet-tracker/pkg/cache"
 	"wallet-tracker/pkg/database"
+	"wallet-tracker/pkg/price"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,27 +30,97 @@ func main() {
 		log.Fatal("Failed to connect to database: ", err)
 	}
 
-	// 连接 Redis
-	redisClient, err := cache.NewRedisClient(&cfg.Redis, &cfg.Cache)
+	// 初始化缓存，memory 模式不需要 Redis
+	balanceCache, err := cache.New(&cfg.Redis, &cfg.Cache)
 	if err != nil {
-		log.Fatal("Failed to connect to Redis: ", err)
+		log.Fatal("Failed to initialize cache: ", err)
+	}
+
+	// 清理旧版本命名空间留下的缓存
+	if purger, ok := balanceCache.(cache.Purger); ok {
+		go func() {
+			deleted, err := purger.PurgeStaleKeys(context.Background())
+			if err != nil {
+				log.Printf("Failed to purge stale cache keys: %v", err)
+				return
+			}
+			if deleted > 0 {
+				log.Printf("Purged %d stale cache keys", deleted)
+			}
+		}()
 	}
 
 	// 初始化 repositories
 	userRepo := repository.NewUserRepository(db)
 	walletRepo := repository.NewWalletRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	tokenListRepo := repository.NewTokenListRepository(db)
+	discoveryRepo := repository.NewDiscoveryRepository(db)
+	nftRepo := repository.NewNFTRepository(db)
+	snapshotRepo := repository.NewSnapshotRepository(db)
 
 	// 初始化 services
 	userService := service.NewUserService(userRepo)
-	walletService := service.NewWalletService(walletRepo, redisClient)
-	blockchainService, err := service.NewBlockchainService(&cfg.Blockchain, redisClient)
+	walletService := service.NewWalletService(walletRepo, tokenListRepo, balanceCache)
+	blockchainService, err := service.NewBlockchainService(&cfg.Blockchain, &cfg.Cache, balanceCache)
 	if err != nil {
 		log.Fatal("Failed to initialize blockchain service: ", err)
 	}
+	defer blockchainService.Close()
+	spamService, err := service.NewSpamService(&cfg.Spam, tokenRepo, tokenListRepo, blockchainService)
+	if err != nil {
+		log.Fatal("Failed to initialize spam detection: ", err)
+	}
+	tokenService := service.NewTokenService(tokenRepo, blockchainService, spamService)
+	tokenListService := service.NewTokenListService(tokenListRepo, tokenRepo, blockchainService, spamService)
+
+	// 导入配置中的 Token List，版本没有更新的列表跳过
+	for _, path := range cfg.TokenLists.Files {
+		result, err := tokenListService.ImportFile(path)
+		if err != nil {
+			if !errors.Is(err, service.ErrTokenListNotNewer) {
+				log.Printf("Failed to import token list %s: %v", path, err)
+			}
+			continue
+		}
+		log.Printf("Imported token list %s %s: %d tokens, %d skipped", result.List.Name, result.List.Version, result.Imported, result.Skipped)
+	}
+
+	// 配置中的名单可能有变化，启动时给所有 token 重新打分
+	if changed, err := spamService.RescoreAll(); err != nil {
+		log.Printf("Failed to rescore tokens: %v", err)
+	} else if changed > 0 {
+		log.Printf("Rescored %d tokens", changed)
+	}
+
+	discoveryService := service.NewDiscoveryService(&cfg.Discovery, discoveryRepo, walletRepo, userRepo, tokenService, walletService, blockchainService)
+	defer discoveryService.Close()
+	nftService, err := service.NewNFTService(&cfg.NFT, nftRepo, tokenRepo, walletService, spamService, blockchainService)
+	if err != nil {
+		log.Fatal("Failed to initialize NFT service: ", err)
+	}
+	defer nftService.Close()
+	snapshotService := service.NewSnapshotService(&cfg.Snapshots, snapshotRepo, walletRepo, walletService, blockchainService)
+	defer snapshotService.Close()
+	priceProvider, err := price.New(&cfg.Prices, cfg.Blockchain.Chains, blockchainService)
+	if err != nil {
+		log.Fatal("Failed to initialize price provider: ", err)
+	}
+	// 价格和余额共用缓存后端，当前价格按 cache.price_ttl 过期，历史价格按 cache.history_price_ttl 过期
+	priceProvider = price.NewCachedProvider(priceProvider, balanceCache)
+	blockchainService.SetPriceProvider(priceProvider)
+	portfolioService := service.NewPortfolioService(snapshotRepo, userRepo, blockchainService, priceProvider)
 
 	// 初始化 handlers
 	authHandler := handler.NewAuthHandler(userService)
-	walletHandler := handler.NewWalletHandler(walletService, blockchainService)
+	walletHandler := handler.NewWalletHandler(walletService, blockchainService, tokenService)
+	tokenHandler := handler.NewTokenHandler(tokenService)
+	tokenListHandler := handler.NewTokenListHandler(tokenListService)
+	discoveryHandler := handler.NewDiscoveryHandler(discoveryService, walletService)
+	userHandler := handler.NewUserHandler(userService)
+	nftHandler := handler.NewNFTHandler(nftService, walletService)
+	snapshotHandler := handler.NewSnapshotHandler(snapshotService, walletService)
+	portfolioHandler := handler.NewPortfolioHandler(portfolioService)
 
 	// 设置 Gin 模式
 	gin.SetMode(cfg.Server.Mode)
@@ -60,6 +133,7 @@ func main() {
 	{
 		public.POST("/register", authHandler.Register)
 		public.POST("/login", authHandler.Login)
+		public.GET("/chains", walletHandler.GetChains)
 	}
 
 	// 需要认证的路由
@@ -69,10 +143,37 @@ func main() {
 		protected.POST("/wallets", walletHandler.AddWallet)
 		protected.GET("/wallets", walletHandler.GetWallets)
 		protected.POST("/wallets/:wallet_id/tokens", walletHandler.AddToken)
+		protected.POST("/wallets/:wallet_id/token-lists", walletHandler.SubscribeTokenList)
+		protected.DELETE("/wallets/:wallet_id/token-lists/:list_id", walletHandler.UnsubscribeTokenList)
+		protected.GET("/token-lists", tokenListHandler.ListTokenLists)
+		protected.POST("/wallets/:wallet_id/discover", discoveryHandler.Discover)
+		protected.GET("/wallets/:wallet_id/suggestions", discoveryHandler.GetSuggestions)
+		protected.POST("/wallets/:wallet_id/suggestions/:suggestion_id/accept", discoveryHandler.AcceptSuggestion)
+		protected.POST("/wallets/:wallet_id/suggestions/:suggestion_id/dismiss", discoveryHandler.DismissSuggestion)
+		protected.POST("/wallets/:wallet_id/nfts", nftHandler.AddCollection)
+		protected.GET("/nfts", nftHandler.GetNFTs)
+		protected.GET("/preferences", userHandler.GetPreferences)
+		protected.PATCH("/preferences", userHandler.UpdatePreferences)
 		protected.GET("/balances", walletHandler.GetBalances)
+		protected.GET("/wallets/:wallet_id/history", snapshotHandler.GetWalletHistory)
+		protected.GET("/history", snapshotHandler.GetHistory)
+		protected.GET("/portfolio/history", portfolioHandler.GetHistory)
 		protected.POST("/refresh-cache", walletHandler.RefreshCache)
 	}
 
+	// 管理员路由
+	admin := r.Group("/api/v1/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware(userRepo))
+	{
+		admin.GET("/tokens", tokenHandler.ListTokens)
+		admin.PATCH("/tokens/:token_id", tokenHandler.UpdateToken)
+		admin.POST("/tokens/:token_id/refresh", tokenHandler.RefreshToken)
+		admin.POST("/tokens/rescore", tokenHandler.RescoreTokens)
+		admin.POST("/token-lists", tokenListHandler.ImportTokenList)
+		admin.GET("/chains/:chain_id/endpoints", walletHandler.GetChainEndpoints)
+		admin.GET("/cache/stats", walletHandler.GetCacheStats)
+	}
+
 	// 启动服务器
 	log.Printf("Server starting on port %s", cfg.Server.Port)
 	if err := r.Run(":" + cfg.Server.Port); err != nil {
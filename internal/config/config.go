@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
+	"github.com/ethereum/go-ethereum/common"
+
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 )
@@ -13,6 +17,12 @@ type Config struct {
 	Redis      RedisConfig      `mapstructure:"redis"`
 	Blockchain BlockchainConfig `mapstructure:"blockchain"`
 	Cache      CacheConfig      `mapstructure:"cache"`
+	TokenLists TokenListConfig  `mapstructure:"token_lists"`
+	Discovery  DiscoveryConfig  `mapstructure:"discovery"`
+	Spam       SpamConfig       `mapstructure:"spam"`
+	NFT        NFTConfig        `mapstructure:"nft"`
+	Snapshots  SnapshotConfig   `mapstructure:"snapshots"`
+	Prices     PriceConfig      `mapstructure:"prices"`
 }
 
 type ServerConfig struct {
@@ -36,17 +46,153 @@ type RedisConfig struct {
 }
 
 type BlockchainConfig struct {
-	Ethereum ChainConfig `mapstructure:"ethereum"`
-	BSC      ChainConfig `mapstructure:"bsc"`
-	Polygon  ChainConfig `mapstructure:"polygon"`
+	Chains  []ChainConfig `mapstructure:"chains"`
+	RPCPool RPCPoolConfig `mapstructure:"rpc_pool"`
+	// 一次余额请求的总耗时预算，超时后返回已完成的部分结果
+	RequestTimeout string `mapstructure:"request_timeout"`
+	// 并发到达的余额查询在该时间窗口内合并为一个批次
+	BatchWindow string `mapstructure:"batch_window"`
+}
+
+// RPCPoolConfig 控制每条链多个 RPC 端点之间的健康检查、熔断与重试，未设置的项使用默认值
+type RPCPoolConfig struct {
+	FailureThreshold    int    `mapstructure:"failure_threshold"`
+	Cooldown            string `mapstructure:"cooldown"`
+	MaxBlockLag         uint64 `mapstructure:"max_block_lag"`
+	HealthCheckInterval string `mapstructure:"health_check_interval"`
+	MaxRetries          int    `mapstructure:"max_retries"`
+	RetryBackoff        string `mapstructure:"retry_backoff"`
 }
 
 type ChainConfig struct {
-	RPCURL string `mapstructure:"rpc_url"`
+	ChainID        int      `mapstructure:"chain_id"`
+	Name           string   `mapstructure:"name"`
+	NativeSymbol   string   `mapstructure:"native_symbol"`
+	NativeName     string   `mapstructure:"native_name"`
+	NativeDecimals int      `mapstructure:"native_decimals"`
+	RPCURLs        []string `mapstructure:"rpc_urls"`
+	ExplorerURL    string   `mapstructure:"explorer_url"`
+	// 读取历史余额的归档节点，为空时使用 rpc_urls
+	ArchiveRPCURLs []string `mapstructure:"archive_rpc_urls"`
+	// 为空表示该链没有 Multicall3，余额逐个查询
+	MulticallAddress string `mapstructure:"multicall_address"`
+	// 单次 RPC 调用的超时时间
+	CallTimeout string `mapstructure:"call_timeout"`
+	// 同时在途的批次数上限，以及每个批次最多包含的余额查询数
+	MaxConcurrency int `mapstructure:"max_concurrency"`
+	BatchSize      int `mapstructure:"batch_size"`
+	// eth_getLogs 每次查询的最大区块数，节点报告超限时会自动缩小
+	LogRange uint64 `mapstructure:"log_range"`
+	// 发现 token 时从该区块开始扫描，通常是链上第一个 ERC-20 出现的高度
+	DiscoveryStartBlock uint64 `mapstructure:"discovery_start_block"`
+	// CoinGecko 中该链的 asset platform（例如 ethereum）和原生币的 coin id，为空时不从 CoinGecko 取价格
+	CoinGeckoPlatform string `mapstructure:"coingecko_platform"`
+	CoinGeckoID       string `mapstructure:"coingecko_id"`
 }
 
 type CacheConfig struct {
+	// 条目的最长保存时间（hard TTL）
 	TokenBalanceTTL string `mapstructure:"token_balance_ttl"`
+	// 超过 soft TTL 的条目仍然返回并标记为 stale，同时在后台刷新
+	SoftTTL string `mapstructure:"soft_ttl"`
+	// key 前缀和版本号，提升版本号会让所有旧缓存失效
+	Namespace string `mapstructure:"namespace"`
+	Version   int    `mapstructure:"version"`
+	// redis（默认）、memory 或 tiered
+	Backend string `mapstructure:"backend"`
+	// 进程内缓存的条目上限，以及 tiered 模式下本地层的 TTL
+	MaxEntries int    `mapstructure:"max_entries"`
+	LocalTTL   string `mapstructure:"local_ttl"`
+	// 当前价格的缓存时间，默认 5 分钟
+	PriceTTL string `mapstructure:"price_ttl"`
+	// 历史价格的缓存时间，默认 30 天
+	HistoryPriceTTL string        `mapstructure:"history_price_ttl"`
+	Refresh         RefreshConfig `mapstructure:"refresh"`
+}
+
+// RefreshConfig 控制后台刷新的 worker 和活跃用户的预刷新
+type RefreshConfig struct {
+	Workers   int `mapstructure:"workers"`
+	QueueSize int `mapstructure:"queue_size"`
+	// 每隔 interval 检查一次在 active_window 内访问过余额的用户
+	Interval     string `mapstructure:"interval"`
+	ActiveWindow string `mapstructure:"active_window"`
+}
+
+// TokenListConfig 列出启动时导入的本地 Token List 文件
+type TokenListConfig struct {
+	Files []string `mapstructure:"files"`
+}
+
+// DiscoveryConfig 控制从 Transfer 日志中发现钱包持有的 token
+type DiscoveryConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// 后台扫描所有钱包的间隔
+	Interval string `mapstructure:"interval"`
+	// 每个钱包每次最多扫描的区块数，未扫完的部分下次继续
+	MaxBlocksPerRun uint64 `mapstructure:"max_blocks_per_run"`
+	// 新钱包从最新区块往前 lookback_blocks 开始扫描，0 表示从 discovery_start_block 开始
+	LookbackBlocks uint64 `mapstructure:"lookback_blocks"`
+}
+
+// SpamConfig 是本地的垃圾 token 名单，条目格式为 "chainId:address"。
+// allow 中的 token 从不标记，deny 中的 token 总是标记
+type SpamConfig struct {
+	Allow []string `mapstructure:"allow"`
+	Deny  []string `mapstructure:"deny"`
+}
+
+// NFTConfig 控制 NFT 持仓的读取和元数据下载
+type NFTConfig struct {
+	// ipfs:// 的 URI 改写为 <ipfs_gateway>/ipfs/<cid>
+	IPFSGateway    string `mapstructure:"ipfs_gateway"`
+	ArweaveGateway string `mapstructure:"arweave_gateway"`
+	// 下载单个元数据文件的超时时间
+	MetadataTimeout string `mapstructure:"metadata_timeout"`
+	// 每个钱包每个合集最多记录的 token 数
+	MaxTokensPerCollection int `mapstructure:"max_tokens_per_collection"`
+	// 持仓超过这个时间后，查询时重新从链上读取
+	RefreshInterval string `mapstructure:"refresh_interval"`
+	// 不支持枚举的合集每次同步最多扫描的区块数，其余的下次同步继续
+	MaxBlocksPerSync uint64 `mapstructure:"max_blocks_per_sync"`
+}
+
+// SnapshotConfig 控制定时记录所有钱包余额的快照，以及旧快照的降采样
+type SnapshotConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// 记录间隔，按整点对齐，例如 1h 在每个整点记录
+	Interval string `mapstructure:"interval"`
+	// 超过 raw_retention 的快照每小时保留一条，超过 hourly_retention 的每天保留一条
+	RawRetention    string `mapstructure:"raw_retention"`
+	HourlyRetention string `mapstructure:"hourly_retention"`
+}
+
+// PriceConfig 是 token 价格的来源
+type PriceConfig struct {
+	// 按顺序尝试的来源：coingecko、onchain、static，前面的来源没有价格时使用后面的。
+	// 为空时只使用 file
+	Providers []string `mapstructure:"providers"`
+	// JSON 格式的价格文件，见 price.StaticFile
+	File      string            `mapstructure:"file"`
+	CoinGecko CoinGeckoConfig   `mapstructure:"coingecko"`
+	Feeds     []PriceFeedConfig `mapstructure:"feeds"`
+}
+
+// CoinGeckoConfig 是 CoinGecko 兼容的行情接口
+type CoinGeckoConfig struct {
+	// 默认 https://api.coingecko.com/api/v3
+	BaseURL string `mapstructure:"base_url"`
+	APIKey  string `mapstructure:"api_key"`
+	// 携带 api_key 的请求头，默认 x-cg-demo-api-key
+	APIKeyHeader string `mapstructure:"api_key_header"`
+	Timeout      string `mapstructure:"timeout"`
+}
+
+// PriceFeedConfig 把一种资产对应到同一条链上的 Chainlink 美元价格源，address 为空表示原生币
+type PriceFeedConfig struct {
+	ChainID int    `mapstructure:"chain_id"`
+	Address string `mapstructure:"address"`
+	Feed    string `mapstructure:"feed"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -66,9 +212,64 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if err := config.Blockchain.Validate(); err != nil {
+		return nil, err
+	}
+
 	// 从环境变量获取敏感信息
 	config.Database.Username = os.Getenv("DB_USERNAME")
 	config.Database.Password = os.Getenv("DB_PASSWORD")
 
 	return &config, nil
 }
+
+// Validate 检查链配置并填充默认值
+func (bc *BlockchainConfig) Validate() error {
+	if len(bc.Chains) == 0 {
+		return errors.New("blockchain.chains: at least one chain must be configured")
+	}
+
+	seen := make(map[int]bool)
+	for i := range bc.Chains {
+		chain := &bc.Chains[i]
+
+		if chain.ChainID <= 0 {
+			return fmt.Errorf("blockchain.chains[%d]: chain_id must be positive", i)
+		}
+		if seen[chain.ChainID] {
+			return fmt.Errorf("blockchain.chains[%d]: duplicate chain_id %d", i, chain.ChainID)
+		}
+		seen[chain.ChainID] = true
+
+		if chain.Name == "" {
+			return fmt.Errorf("blockchain.chains[%d]: name is required", i)
+		}
+		if chain.NativeSymbol == "" {
+			return fmt.Errorf("blockchain.chains[%d]: native_symbol is required", i)
+		}
+		if len(chain.RPCURLs) == 0 {
+			return fmt.Errorf("blockchain.chains[%d]: at least one rpc_url is required", i)
+		}
+		if chain.MulticallAddress != "" && !common.IsHexAddress(chain.MulticallAddress) {
+			return fmt.Errorf("blockchain.chains[%d]: invalid multicall_address %q", i, chain.MulticallAddress)
+		}
+
+		if chain.NativeName == "" {
+			chain.NativeName = chain.NativeSymbol
+		}
+		if chain.NativeDecimals == 0 {
+			chain.NativeDecimals = 18
+		}
+		if chain.MaxConcurrency <= 0 {
+			chain.MaxConcurrency = 4
+		}
+		if chain.BatchSize <= 0 {
+			chain.BatchSize = 100
+		}
+		if chain.LogRange == 0 {
+			chain.LogRange = 2000
+		}
+	}
+
+	return nil
+}
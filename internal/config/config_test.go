@@ -0,0 +1,51 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validChain() ChainConfig {
+	return ChainConfig{
+		ChainID:      42161,
+		Name:         "Arbitrum",
+		NativeSymbol: "ETH",
+		RPCURLs:      []string{"https://arb1.arbitrum.io/rpc"},
+	}
+}
+
+func TestBlockchainConfig_Validate(t *testing.T) {
+	t.Run("Fills Defaults", func(t *testing.T) {
+		cfg := BlockchainConfig{Chains: []ChainConfig{validChain()}}
+
+		assert.NoError(t, cfg.Validate())
+		assert.Equal(t, 18, cfg.Chains[0].NativeDecimals)
+		assert.Equal(t, "ETH", cfg.Chains[0].NativeName)
+		assert.Equal(t, uint64(2000), cfg.Chains[0].LogRange)
+	})
+
+	t.Run("No Chains", func(t *testing.T) {
+		cfg := BlockchainConfig{}
+		assert.Error(t, cfg.Validate())
+	})
+
+	t.Run("Duplicate Chain ID", func(t *testing.T) {
+		cfg := BlockchainConfig{Chains: []ChainConfig{validChain(), validChain()}}
+		assert.ErrorContains(t, cfg.Validate(), "duplicate chain_id")
+	})
+
+	t.Run("Missing RPC URL", func(t *testing.T) {
+		chain := validChain()
+		chain.RPCURLs = nil
+		cfg := BlockchainConfig{Chains: []ChainConfig{chain}}
+		assert.ErrorContains(t, cfg.Validate(), "rpc_url")
+	})
+
+	t.Run("Invalid Multicall Address", func(t *testing.T) {
+		chain := validChain()
+		chain.MulticallAddress = "not-an-address"
+		cfg := BlockchainConfig{Chains: []ChainConfig{chain}}
+		assert.ErrorContains(t, cfg.Validate(), "multicall_address")
+	})
+}
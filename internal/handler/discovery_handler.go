@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"wallet-tracker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DiscoveryHandler 提供从转账记录中发现 token 的接口
+type DiscoveryHandler struct {
+	discoveryService *service.DiscoveryService
+	walletService    *service.WalletService
+}
+
+func NewDiscoveryHandler(discoveryService *service.DiscoveryService, walletService *service.WalletService) *DiscoveryHandler {
+	return &DiscoveryHandler{
+		discoveryService: discoveryService,
+		walletService:    walletService,
+	}
+}
+
+// Discover 立即为钱包扫描一段区块，未扫完的部分可以再次调用继续
+func (dh *DiscoveryHandler) Discover(c *gin.Context) {
+	wallet, ok := ownedWallet(c, dh.walletService)
+	if !ok {
+		return
+	}
+
+	result, err := dh.discoveryService.DiscoverWallet(c.Request.Context(), wallet)
+	if err != nil {
+		if result == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		// 扫描中途失败，已完成的部分照常返回
+		c.JSON(http.StatusOK, gin.H{"result": result, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+func (dh *DiscoveryHandler) GetSuggestions(c *gin.Context) {
+	wallet, ok := ownedWallet(c, dh.walletService)
+	if !ok {
+		return
+	}
+
+	suggestions, err := dh.discoveryService.ListSuggestions(wallet.ID, c.DefaultQuery("status", "pending"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	cursor, err := dh.discoveryService.GetCursor(wallet.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions": suggestions,
+		"cursor":      cursor,
+	})
+}
+
+func (dh *DiscoveryHandler) AcceptSuggestion(c *gin.Context) {
+	wallet, suggestionID, ok := dh.suggestionParams(c)
+	if !ok {
+		return
+	}
+
+	token, err := dh.discoveryService.AcceptSuggestion(wallet, suggestionID)
+	if err != nil {
+		suggestionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"token": token})
+}
+
+func (dh *DiscoveryHandler) DismissSuggestion(c *gin.Context) {
+	wallet, suggestionID, ok := dh.suggestionParams(c)
+	if !ok {
+		return
+	}
+
+	if err := dh.discoveryService.DismissSuggestion(wallet, suggestionID); err != nil {
+		suggestionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Suggestion dismissed"})
+}
+
+func (dh *DiscoveryHandler) suggestionParams(c *gin.Context) (uint, uint, bool) {
+	wallet, ok := ownedWallet(c, dh.walletService)
+	if !ok {
+		return 0, 0, false
+	}
+
+	suggestionID, err := strconv.ParseUint(c.Param("suggestion_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid suggestion ID"})
+		return 0, 0, false
+	}
+
+	return wallet.ID, uint(suggestionID), true
+}
+
+func suggestionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSuggestionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSuggestionNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"wallet-tracker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NFTHandler 提供 NFT 合集的跟踪和持仓查询接口
+type NFTHandler struct {
+	nftService    *service.NFTService
+	walletService *service.WalletService
+}
+
+func NewNFTHandler(nftService *service.NFTService, walletService *service.WalletService) *NFTHandler {
+	return &NFTHandler{
+		nftService:    nftService,
+		walletService: walletService,
+	}
+}
+
+// AddCollection 让钱包跟踪一个 ERC-721 合集，返回立即读取到的持仓
+func (nh *NFTHandler) AddCollection(c *gin.Context) {
+	wallet, ok := ownedWallet(c, nh.walletService)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ContractAddress string `json:"contract_address" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	holding, err := nh.nftService.AddCollection(c.Request.Context(), wallet, req.ContractAddress)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTokenAddress):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNotContract), errors.Is(err, service.ErrNotNFTCollection):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, holding)
+}
+
+// GetNFTs 返回用户所有钱包保存的 NFT 持仓，按合集分组。过期或 refresh=true 时在后台重新读取，
+// 垃圾合集默认隐藏，include_spam=true 时一并返回
+func (nh *NFTHandler) GetNFTs(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	refresh := c.Query("refresh") == "true"
+	includeSpam := c.Query("include_spam") == "true"
+
+	holdings, hidden, err := nh.nftService.Holdings(userID, refresh, includeSpam)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"collections": holdings,
+		"hidden_spam": hidden,
+	})
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"wallet-tracker/internal/service"
+	"wallet-tracker/pkg/price"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PortfolioHandler 提供用户资产总值的查询接口
+type PortfolioHandler struct {
+	portfolioService *service.PortfolioService
+}
+
+func NewPortfolioHandler(portfolioService *service.PortfolioService) *PortfolioHandler {
+	return &PortfolioHandler{portfolioService: portfolioService}
+}
+
+// GetHistory 返回 from 和 to 之间资产总值的时间序列，以用户的计价货币计。
+// interval 为空时按范围自动选择
+func (ph *PortfolioHandler) GetHistory(c *gin.Context) {
+	from, to, err := historyRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var interval time.Duration
+	if value := c.Query("interval"); value != "" {
+		if interval, err = service.ParseInterval(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	history, err := ph.portfolioService.History(c.Request.Context(), c.GetUint("user_id"), from, to, interval)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidInterval):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, price.ErrUnsupportedCurrency):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"wallet-tracker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 未指定 from 时返回最近 7 天的历史
+const defaultHistoryRange = 7 * 24 * time.Hour
+
+// SnapshotHandler 提供定时快照记录的余额历史查询接口
+type SnapshotHandler struct {
+	snapshotService *service.SnapshotService
+	walletService   *service.WalletService
+}
+
+func NewSnapshotHandler(snapshotService *service.SnapshotService, walletService *service.WalletService) *SnapshotHandler {
+	return &SnapshotHandler{
+		snapshotService: snapshotService,
+		walletService:   walletService,
+	}
+}
+
+// GetWalletHistory 返回钱包在 from 和 to 之间的余额快照
+func (sh *SnapshotHandler) GetWalletHistory(c *gin.Context) {
+	wallet, ok := ownedWallet(c, sh.walletService)
+	if !ok {
+		return
+	}
+
+	from, to, err := historyRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	snapshots, err := sh.snapshotService.WalletHistory(wallet.ID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"wallet_id": wallet.ID,
+		"from":      from,
+		"to":        to,
+		"snapshots": snapshots,
+	})
+}
+
+// GetHistory 返回用户所有钱包在 from 和 to 之间的余额快照
+func (sh *SnapshotHandler) GetHistory(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	from, to, err := historyRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	snapshots, err := sh.snapshotService.UserHistory(userID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":      from,
+		"to":        to,
+		"snapshots": snapshots,
+	})
+}
+
+// historyRange 解析 RFC3339 格式的 from 和 to，to 默认为当前时间，from 默认为 to 之前 7 天
+func historyRange(c *gin.Context) (time.Time, time.Time, error) {
+	to := time.Now().UTC()
+	if value := c.Query("to"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to, expected RFC3339: %q", value)
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultHistoryRange)
+	if value := c.Query("from"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from, expected RFC3339: %q", value)
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("from must not be after to")
+	}
+	return from, to, nil
+}
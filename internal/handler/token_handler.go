@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"wallet-tracker/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TokenHandler 提供管理员维护 token 元数据表的接口
+type TokenHandler struct {
+	tokenService *service.TokenService
+}
+
+func NewTokenHandler(tokenService *service.TokenService) *TokenHandler {
+	return &TokenHandler{tokenService: tokenService}
+}
+
+func (th *TokenHandler) ListTokens(c *gin.Context) {
+	chainID := 0
+	if raw := c.Query("chain_id"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chain ID"})
+			return
+		}
+		chainID = parsed
+	}
+
+	tokens, err := th.tokenService.ListTokens(chainID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+func (th *TokenHandler) UpdateToken(c *gin.Context) {
+	tokenID, ok := parseTokenID(c)
+	if !ok {
+		return
+	}
+
+	var req service.TokenOverride
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := th.tokenService.OverrideToken(tokenID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		case errors.Is(err, service.ErrInvalidTokenStatus), errors.Is(err, service.ErrInvalidTokenDecimals), errors.Is(err, service.ErrInvalidSpamList):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// RefreshToken 重新从链上读取元数据，撤销之前的人工修改
+func (th *TokenHandler) RefreshToken(c *gin.Context) {
+	tokenID, ok := parseTokenID(c)
+	if !ok {
+		return
+	}
+
+	token, err := th.tokenService.RefreshToken(c.Request.Context(), tokenID)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		case errors.Is(err, service.ErrNotContract), errors.Is(err, service.ErrNonStandardToken), errors.Is(err, service.ErrNFTCollection), errors.Is(err, service.ErrNotNFTCollection):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// RescoreTokens 给所有 token 重新打垃圾分
+func (th *TokenHandler) RescoreTokens(c *gin.Context) {
+	changed, err := th.tokenService.RescoreTokens()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"changed": changed})
+}
+
+func parseTokenID(c *gin.Context) (uint, bool) {
+	tokenID, err := strconv.ParseUint(c.Param("token_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return 0, false
+	}
+	return uint(tokenID), true
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"wallet-tracker/internal/service"
+	"wallet-tracker/pkg/tokenlist"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上传的 Token List 大小上限，10000 个 token 的列表约为 3MB
+const maxTokenListSize = 16 << 20
+
+type TokenListHandler struct {
+	tokenListService *service.TokenListService
+}
+
+func NewTokenListHandler(tokenListService *service.TokenListService) *TokenListHandler {
+	return &TokenListHandler{tokenListService: tokenListService}
+}
+
+// ImportTokenList 导入请求体中的 Token List JSON
+func (lh *TokenListHandler) ImportTokenList(c *gin.Context) {
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxTokenListSize))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := lh.tokenListService.Import(data, "upload")
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTokenListNotNewer):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case tokenlist.IsValidationError(err):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+func (lh *TokenListHandler) ListTokenLists(c *gin.Context) {
+	lists, err := lh.tokenListService.ListTokenLists()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"token_lists": lists})
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"wallet-tracker/internal/model"
+	"wallet-tracker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler 提供当前用户的设置接口
+type UserHandler struct {
+	userService *service.UserService
+}
+
+func NewUserHandler(userService *service.UserService) *UserHandler {
+	return &UserHandler{userService: userService}
+}
+
+func (uh *UserHandler) GetPreferences(c *gin.Context) {
+	user, err := uh.userService.GetUser(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences(user))
+}
+
+// UpdatePreferences 只修改请求中给出的设置
+func (uh *UserHandler) UpdatePreferences(c *gin.Context) {
+	var req struct {
+		AutoAddTokens *bool   `json:"auto_add_tokens"`
+		BaseCurrency  *string `json:"base_currency"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := uh.userService.UpdatePreferences(c.GetUint("user_id"), service.Preferences{
+		AutoAddTokens: req.AutoAddTokens,
+		BaseCurrency:  req.BaseCurrency,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCurrency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences(user))
+}
+
+func preferences(user *model.User) gin.H {
+	return gin.H{
+		"auto_add_tokens": user.AutoAddTokens,
+		"base_currency":   user.BaseCurrency,
+	}
+}
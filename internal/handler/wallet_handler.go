@@ -1,23 +1,30 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"wallet-tracker/internal/model"
 	"wallet-tracker/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type WalletHandler struct {
 	walletService     *service.WalletService
 	blockchainService *service.BlockchainService
+	tokenService      *service.TokenService
 }
 
-func NewWalletHandler(walletService *service.WalletService, blockchainService *service.BlockchainService) *WalletHandler {
+func NewWalletHandler(walletService *service.WalletService, blockchainService *service.BlockchainService, tokenService *service.TokenService) *WalletHandler {
 	return &WalletHandler{
 		walletService:     walletService,
 		blockchainService: blockchainService,
+		tokenService:      tokenService,
 	}
 }
 
@@ -27,7 +34,7 @@ func (wh *WalletHandler) AddWallet(c *gin.Context) {
 	var req struct {
 		Address   string `json:"address" binding:"required"`
 		ChainID   int    `json:"chain_id" binding:"required"`
-		ChainName string `json:"chain_name" binding:"required"`
+		ChainName string `json:"chain_name"`
 		Name      string `json:"name"`
 	}
 
@@ -36,7 +43,15 @@ func (wh *WalletHandler) AddWallet(c *gin.Context) {
 		return
 	}
 
-	wallet, err := wh.walletService.AddWallet(userID, req.Address, req.ChainID, req.ChainName, req.Name)
+	if !wh.blockchainService.IsSupportedChain(req.ChainID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported chain ID"})
+		return
+	}
+
+	// 链名称以服务端配置为准
+	chainName := wh.blockchainService.GetChainName(req.ChainID)
+
+	wallet, err := wh.walletService.AddWallet(userID, req.Address, req.ChainID, chainName, req.Name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -45,19 +60,77 @@ func (wh *WalletHandler) AddWallet(c *gin.Context) {
 	c.JSON(http.StatusCreated, wallet)
 }
 
+// AddToken 只需要 token 地址，symbol / name / decimals 取自 token 表，第一次出现的 token 从链上读取。
+// 调用方提供的元数据仅用于核对，不一致的字段在 mismatches 中返回
 func (wh *WalletHandler) AddToken(c *gin.Context) {
-	walletIDStr := c.Param("wallet_id")
-	walletID, err := strconv.ParseUint(walletIDStr, 10, 32)
+	var req struct {
+		TokenAddress string `json:"token_address" binding:"required"`
+		Symbol       string `json:"symbol"`
+		Name         string `json:"name"`
+		Decimals     *int   `json:"decimals"`
+	}
+
+	wallet, ok := ownedWallet(c, wh.walletService)
+	if !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	metadata, err := wh.tokenService.Resolve(c.Request.Context(), wallet.ChainID, req.TokenAddress)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTokenAddress):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNotContract), errors.Is(err, service.ErrNonStandardToken), errors.Is(err, service.ErrNFTCollection):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	token, err := wh.walletService.AddTokenToWallet(wallet.ID, metadata)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":      token,
+		"mismatches": service.TokenMismatches(metadata, req.Symbol, req.Name, req.Decimals),
+	})
+}
+
+// ownedWallet 读取路径中的钱包，钱包不属于当前用户时按不存在处理
+func ownedWallet(c *gin.Context, walletService *service.WalletService) (*model.Wallet, bool) {
+	walletID, err := strconv.ParseUint(c.Param("wallet_id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet ID"})
+		return nil, false
+	}
+
+	wallet, err := walletService.GetWallet(uint(walletID))
+	if err != nil || wallet.UserID != c.GetUint("user_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+		return nil, false
+	}
+
+	return wallet, true
+}
+
+// SubscribeTokenList 让钱包跟踪列表中的所有 token，只有余额不为 0 的 token 出现在余额中
+func (wh *WalletHandler) SubscribeTokenList(c *gin.Context) {
+	wallet, ok := ownedWallet(c, wh.walletService)
+	if !ok {
 		return
 	}
 
 	var req struct {
-		TokenAddress string `json:"token_address" binding:"required"`
-		Symbol       string `json:"symbol" binding:"required"`
-		Name         string `json:"name" binding:"required"`
-		Decimals     int    `json:"decimals" binding:"required"`
+		TokenListID uint `json:"token_list_id" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -65,13 +138,36 @@ func (wh *WalletHandler) AddToken(c *gin.Context) {
 		return
 	}
 
-	token, err := wh.walletService.AddTokenToWallet(uint(walletID), req.TokenAddress, req.Symbol, req.Name, req.Decimals)
+	if err := wh.walletService.SubscribeTokenList(wallet.ID, req.TokenListID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Token list not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token list subscribed"})
+}
+
+func (wh *WalletHandler) UnsubscribeTokenList(c *gin.Context) {
+	wallet, ok := ownedWallet(c, wh.walletService)
+	if !ok {
+		return
+	}
+
+	listID, err := strconv.ParseUint(c.Param("list_id"), 10, 32)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token list ID"})
+		return
+	}
+
+	if err := wh.walletService.UnsubscribeTokenList(wallet.ID, uint(listID)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, token)
+	c.JSON(http.StatusOK, gin.H{"message": "Token list unsubscribed"})
 }
 
 func (wh *WalletHandler) GetWallets(c *gin.Context) {
@@ -86,9 +182,41 @@ func (wh *WalletHandler) GetWallets(c *gin.Context) {
 	c.JSON(http.StatusOK, wallets)
 }
 
+func (wh *WalletHandler) GetChains(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"chains": wh.blockchainService.SupportedChains()})
+}
+
+func (wh *WalletHandler) GetChainEndpoints(c *gin.Context) {
+	chainID, err := strconv.Atoi(c.Param("chain_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chain ID"})
+		return
+	}
+
+	if !wh.blockchainService.IsSupportedChain(chainID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unsupported chain ID"})
+		return
+	}
+
+	endpoints, err := wh.blockchainService.EndpointStatus(chainID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"chain_id":  chainID,
+		"endpoints": endpoints,
+	})
+}
+
+// GetBalances 返回用户所有钱包的余额，垃圾 token 默认隐藏，include_spam=true 时一并返回。
+// 指定 at（RFC3339 时间）或 block 时返回该时间点的历史余额；consistent=true 时每条链的余额
+// 都在同一个最新区块读取，不使用缓存。这两种情况下响应中的 blocks 给出每条链读取的区块
 func (wh *WalletHandler) GetBalances(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	forceRefresh := c.Query("force_refresh") == "true"
+	includeSpam := c.Query("include_spam") == "true"
 
 	wallets, err := wh.walletService.GetUserWallets(userID)
 	if err != nil {
@@ -96,25 +224,121 @@ func (wh *WalletHandler) GetBalances(c *gin.Context) {
 		return
 	}
 
-	balances, err := wh.blockchainService.GetMultipleTokenBalances(wallets, forceRefresh)
+	if err := wh.walletService.AttachListTokens(wallets); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var chainIDs []int
+	for _, wallet := range wallets {
+		chainIDs = append(chainIDs, wallet.ChainID)
+	}
+
+	at, pinned, err := balanceAt(c, chainIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var balances []model.TokenBalance
+	var blocks []model.ChainBlock
+	if pinned {
+		balances, blocks, err = wh.blockchainService.GetHistoricalBalances(c.Request.Context(), wallets, at)
+		if err == nil {
+			wh.blockchainService.ValueHistoricalBalances(c.Request.Context(), balances, blocks, at)
+		}
+	} else {
+		wh.blockchainService.TrackActiveUser(userID, wallets)
+		balances, err = wh.blockchainService.GetMultipleTokenBalances(c.Request.Context(), wallets, forceRefresh)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"balances": balances,
-		"cached":   !forceRefresh,
-	})
+	hiddenSpam := 0
+	if !includeSpam {
+		visible := make([]model.TokenBalance, 0, len(balances))
+		for _, balance := range balances {
+			if balance.Spam {
+				hiddenSpam++
+				continue
+			}
+			visible = append(visible, balance)
+		}
+		balances = visible
+	}
+
+	// 所有余额都来自缓存时 cached 为 true；有条目因预算耗尽缺失时 partial 为 true
+	cached := len(balances) > 0
+	partial := false
+	for _, balance := range balances {
+		if !balance.FromCache {
+			cached = false
+		}
+		if balance.Status == model.BalanceStatusTimeout {
+			partial = true
+		}
+	}
+
+	response := gin.H{
+		"balances":           balances,
+		"totals":             service.TotalBalances(balances),
+		"portfolio":          service.TotalValue(balances),
+		"cached":             cached,
+		"partial":            partial,
+		"hidden_spam":        hiddenSpam,
+		"unavailable_chains": wh.blockchainService.UnavailableChains(chainIDs),
+	}
+	if pinned {
+		response["blocks"] = blocks
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// balanceAt 解析 at、block 和 consistent 参数，返回余额是否需要固定在一个区块读取
+func balanceAt(c *gin.Context, chainIDs []int) (service.BalanceAt, bool, error) {
+	var at service.BalanceAt
+
+	atParam, blockParam := c.Query("at"), c.Query("block")
+	if atParam == "" && blockParam == "" {
+		return at, c.Query("consistent") == "true", nil
+	}
+
+	if atParam != "" {
+		t, err := time.Parse(time.RFC3339, atParam)
+		if err != nil {
+			return at, false, fmt.Errorf("invalid at, expected RFC3339: %q", atParam)
+		}
+		if t.After(time.Now()) {
+			return at, false, errors.New("at must not be in the future")
+		}
+		at.Time = &t
+	}
+
+	if blockParam != "" {
+		blocks, err := service.ParseBlocks(blockParam, chainIDs)
+		if err != nil {
+			return at, false, err
+		}
+		at.Blocks = blocks
+	}
+
+	return at, true, nil
 }
 
 func (wh *WalletHandler) RefreshCache(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
-	if err := wh.walletService.RefreshUserCache(userID); err != nil {
+	if err := wh.walletService.RefreshUserCache(c.Request.Context(), userID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Cache refreshed successfully"})
 }
+
+// GetCacheStats 返回后台刷新队列和 worker 的运行状况
+func (wh *WalletHandler) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"refresh": wh.blockchainService.RefreshStats()})
+}
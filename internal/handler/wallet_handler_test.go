@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/internal/service"
+	"wallet-tracker/pkg/cache"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceAt(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	query := func(rawQuery string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/balances?"+rawQuery, nil)
+		return c
+	}
+
+	at, pinned, err := balanceAt(query(""), []int{1})
+	require.NoError(t, err)
+	assert.False(t, pinned)
+	assert.Nil(t, at.Time)
+
+	// consistent=true 固定在最新区块，不指定时间和区块
+	at, pinned, err = balanceAt(query("consistent=true"), []int{1, 56})
+	require.NoError(t, err)
+	assert.True(t, pinned)
+	assert.Nil(t, at.Time)
+	assert.Empty(t, at.Blocks)
+
+	at, pinned, err = balanceAt(query("at=2024-01-01T00:00:00Z&block=56:38000000"), []int{1, 56})
+	require.NoError(t, err)
+	assert.True(t, pinned)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), *at.Time)
+	assert.Equal(t, map[int]uint64{56: 38000000}, at.Blocks)
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	for _, rawQuery := range []string{"at=yesterday", "at=" + future, "block=100"} {
+		_, _, err := balanceAt(query(rawQuery), []int{1, 56})
+		assert.Error(t, err, rawQuery)
+	}
+}
+
+func TestGetChains_HidesRPCURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 端口 1 上没有服务，连接失败的错误中带有完整的 URL
+	cfg := &config.BlockchainConfig{Chains: []config.ChainConfig{{
+		ChainID: 1, Name: "Ethereum", NativeSymbol: "ETH", NativeDecimals: 18,
+		RPCURLs: []string{"http://127.0.0.1:1/v3/secret-api-key"},
+	}}}
+	cacheCfg := &config.CacheConfig{}
+	blockchainService, err := service.NewBlockchainService(cfg, cacheCfg, cache.NewMemoryCache(cacheCfg))
+	require.NoError(t, err)
+	defer blockchainService.Close()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/chains", nil)
+	NewWalletHandler(nil, blockchainService, nil).GetChains(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"available":false`)
+	assert.NotContains(t, w.Body.String(), "secret-api-key")
+	assert.NotContains(t, w.Body.String(), "127.0.0.1")
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"wallet-tracker/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware 只允许管理员访问，需要放在 AuthMiddleware 之后
+func AdminMiddleware(userRepo repository.UserRepositoryInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := userRepo.GetByID(c.GetUint("user_id"))
+		if err != nil || !user.IsAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
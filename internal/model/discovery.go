@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// DiscoveryCursor 记录钱包的 Transfer 日志已经扫描到的位置，下次从 NextBlock 继续
+type DiscoveryCursor struct {
+	ID        uint       `json:"-" gorm:"primarykey"`
+	WalletID  uint       `json:"wallet_id" gorm:"not null;uniqueIndex"`
+	NextBlock uint64     `json:"next_block"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// 建议添加的 token 的处理状态
+const (
+	SuggestionStatusPending   = "pending"
+	SuggestionStatusAccepted  = "accepted"
+	SuggestionStatusDismissed = "dismissed"
+)
+
+// TokenSuggestion 是从钱包的转账记录中发现、但还没有添加到钱包的 token
+type TokenSuggestion struct {
+	ID       uint   `json:"id" gorm:"primarykey"`
+	WalletID uint   `json:"wallet_id" gorm:"not null;uniqueIndex:idx_suggestions_wallet_token"`
+	TokenID  uint   `json:"token_id" gorm:"not null;uniqueIndex:idx_suggestions_wallet_token"`
+	Token    *Token `json:"token,omitempty" gorm:"foreignKey:TokenID"`
+	// 发现时的余额，读取失败时为空
+	RawBalance string    `json:"raw_balance,omitempty"`
+	Balance    string    `json:"balance,omitempty"`
+	Status     string    `json:"status" gorm:"size:16;not null;default:pending"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
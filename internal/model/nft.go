@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// token 的标准：同质化的 ERC-20 和 NFT 合集，NFT 的取值与 blockchain.StandardERC721 / StandardERC1155 相同
+const (
+	TokenStandardERC20   = "erc20"
+	TokenStandardERC721  = "erc721"
+	TokenStandardERC1155 = "erc1155"
+)
+
+// NFTItem 是钱包在一个合集中持有的一个 NFT（ERC-1155 为一个 id），CollectionID 指向 token 表中的合集
+type NFTItem struct {
+	ID           uint `json:"id" gorm:"primarykey"`
+	WalletID     uint `json:"wallet_id" gorm:"not null;uniqueIndex:idx_nft_items_wallet_token"`
+	CollectionID uint `json:"collection_id" gorm:"not null;uniqueIndex:idx_nft_items_wallet_token"`
+	// 十进制的 uint256 tokenId
+	TokenID string `json:"token_id" gorm:"size:78;not null;uniqueIndex:idx_nft_items_wallet_token"`
+	// 持有数量，ERC-721 总是 1
+	Balance     string `json:"balance" gorm:"size:78;not null;default:'1'"`
+	TokenURI    string `json:"token_uri,omitempty" gorm:"type:text"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty" gorm:"type:text"`
+	ImageURL    string `json:"image_url,omitempty" gorm:"type:text"`
+	// 元数据中的 attributes，原样保存
+	Attributes        string     `json:"attributes,omitempty" gorm:"type:text"`
+	MetadataError     string     `json:"metadata_error,omitempty"`
+	MetadataFetchedAt *time.Time `json:"metadata_fetched_at,omitempty"`
+	// 连续下载失败的次数，决定下次重试的时间
+	MetadataAttempts int       `json:"-" gorm:"not null;default:0"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// NFTSync 记录钱包在一个合集中的持仓最近一次从链上读取的结果
+type NFTSync struct {
+	ID           uint `json:"-" gorm:"primarykey"`
+	WalletID     uint `json:"wallet_id" gorm:"not null;uniqueIndex:idx_nft_syncs_wallet_collection"`
+	CollectionID uint `json:"collection_id" gorm:"not null;uniqueIndex:idx_nft_syncs_wallet_collection"`
+	// balanceOf 的结果，可能多于记录的 token 数；ERC-1155 为各 id 持有数量之和
+	Balance string `json:"balance"`
+	// 不支持枚举的合集从 Transfer 日志中找 token，NextBlock 是下一个尚未扫描的区块
+	NextBlock uint64 `json:"next_block,omitempty"`
+	// Transfer 日志还没有扫描到最新区块，持仓可能不完整
+	Partial   bool       `json:"partial"`
+	SyncedAt  *time.Time `json:"synced_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
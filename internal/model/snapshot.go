@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// 快照的精度：最近的快照全部保留，较早的按小时、再早的按天只保留每个区间的第一条
+const (
+	SnapshotResolutionRaw    = "raw"
+	SnapshotResolutionHourly = "hourly"
+	SnapshotResolutionDaily  = "daily"
+)
+
+// BalanceSnapshot 是定时任务记录的一个钱包在某个时间点持有的一种资产，
+// 同一次任务中同一条链上的余额都在同一个区块读取
+type BalanceSnapshot struct {
+	ID       uint `json:"-" gorm:"primarykey"`
+	UserID   uint `json:"user_id" gorm:"not null;index:idx_balance_snapshots_user_time"`
+	WalletID uint `json:"wallet_id" gorm:"not null;index:idx_balance_snapshots_wallet_time"`
+	ChainID  int  `json:"chain_id" gorm:"not null"`
+	// 原生币的 TokenAddress 为空
+	AssetType    string    `json:"asset_type" gorm:"size:10;not null"`
+	TokenAddress string    `json:"token_address,omitempty" gorm:"size:42"`
+	Symbol       string    `json:"symbol"`
+	Decimals     int       `json:"decimals"`
+	RawBalance   string    `json:"raw_balance" gorm:"size:78;not null"`
+	Balance      string    `json:"balance" gorm:"size:100;not null"`
+	BlockNumber  uint64    `json:"block_number"`
+	BlockTime    time.Time `json:"block_time"`
+	// 任务的计划时间，同一次任务的所有快照相同
+	TakenAt    time.Time `json:"taken_at" gorm:"not null;index:idx_balance_snapshots_user_time;index:idx_balance_snapshots_wallet_time"`
+	Resolution string    `json:"resolution" gorm:"size:10;not null;default:'raw';index"`
+	CreatedAt  time.Time `json:"-"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// token 元数据的核验状态
+const (
+	TokenStatusUnverified = "unverified"
+	TokenStatusVerified   = "verified"
+)
+
+// Token 是全局的 token 元数据，按 (链, 合约地址) 唯一，所有用户共用
+type Token struct {
+	ID       uint   `json:"id" gorm:"primarykey"`
+	ChainID  int    `json:"chain_id" gorm:"not null;uniqueIndex:idx_tokens_chain_address"`
+	Address  string `json:"address" gorm:"size:42;not null;uniqueIndex:idx_tokens_chain_address"`
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Decimals int    `json:"decimals"`
+	LogoURL  string `json:"logo_url"`
+	// erc20 或 NFT 合集的标准，见 TokenStandard*
+	Standard string `json:"standard" gorm:"size:8;not null;default:erc20"`
+	Status   string `json:"status" gorm:"size:16;not null;default:unverified"`
+	// 管理员修改过的元数据不会被链上数据覆盖
+	Overridden bool `json:"overridden" gorm:"default:false"`
+	// 链上缺失、使用了默认值的元数据，例如 decimals_missing
+	Warnings []string `json:"warnings,omitempty" gorm:"serializer:json"`
+	// 垃圾 token 评分，见 pkg/spam；Spam 的 token 默认不出现在余额中
+	SpamScore   int      `json:"spam_score"`
+	SpamReasons []string `json:"spam_reasons,omitempty" gorm:"serializer:json"`
+	Spam        bool     `json:"spam" gorm:"index;default:false"`
+	// 管理员名单：allow、deny 或空
+	SpamList string `json:"spam_list,omitempty" gorm:"size:8"`
+	// 在转账记录中观察到的行为，见 spam.Signal*
+	Signals     []string  `json:"signals,omitempty" gorm:"serializer:json"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
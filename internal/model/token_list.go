@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// TokenList 是导入的 Token List（tokenlists.org 格式），按名称唯一，
+// 重新导入同名列表时只接受更高的版本
+type TokenList struct {
+	ID           uint      `json:"id" gorm:"primarykey"`
+	Name         string    `json:"name" gorm:"size:64;uniqueIndex;not null"`
+	Source       string    `json:"source"`
+	Version      string    `json:"version"`
+	VersionMajor int       `json:"-"`
+	VersionMinor int       `json:"-"`
+	VersionPatch int       `json:"-"`
+	Timestamp    time.Time `json:"timestamp"`
+	LogoURL      string    `json:"logo_url"`
+	TokenCount   int       `json:"token_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Tokens       []Token   `json:"-" gorm:"many2many:token_list_entries"`
+}
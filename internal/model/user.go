@@ -3,18 +3,25 @@ package model
 import (
 	"time"
 
+	"wallet-tracker/pkg/decimal"
+
 	"gorm.io/gorm"
 )
 
 type User struct {
-	ID        uint           `json:"id" gorm:"primarykey"`
-	Username  string         `json:"username" gorm:"uniqueIndex;not null"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	Password  string         `json:"-" gorm:"not null"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
-	Wallets   []Wallet       `json:"wallets" gorm:"foreignKey:UserID"`
+	ID       uint   `json:"id" gorm:"primarykey"`
+	Username string `json:"username" gorm:"uniqueIndex;not null"`
+	Email    string `json:"email" gorm:"uniqueIndex;not null"`
+	Password string `json:"-" gorm:"not null"`
+	IsAdmin  bool   `json:"is_admin" gorm:"default:false"`
+	// 发现的 token 直接添加到钱包，而不是作为建议等待确认
+	AutoAddTokens bool `json:"auto_add_tokens" gorm:"default:false"`
+	// 资产价值的计价货币，例如 usd、eur
+	BaseCurrency string         `json:"base_currency" gorm:"size:10;not null;default:'usd'"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	Wallets      []Wallet       `json:"wallets" gorm:"foreignKey:UserID"`
 }
 
 type Wallet struct {
@@ -28,29 +35,102 @@ type Wallet struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 	Tokens    []WalletToken  `json:"tokens" gorm:"foreignKey:WalletID"`
+	// 钱包订阅的 Token List，列表中的 token 只在余额不为 0 时显示
+	TokenLists []TokenList `json:"token_lists" gorm:"many2many:wallet_token_lists"`
+	// 订阅的列表中属于该钱包所在链、且未单独添加的 token，不落库
+	ListTokens []Token `json:"-" gorm:"-"`
 }
 
+// WalletToken 记录钱包跟踪的 token，元数据保存在 Token 中
 type WalletToken struct {
-	ID            uint           `json:"id" gorm:"primarykey"`
-	WalletID      uint           `json:"wallet_id" gorm:"not null"`
-	TokenAddress  string         `json:"token_address" gorm:"not null"`
-	TokenSymbol   string         `json:"token_symbol"`
-	TokenName     string         `json:"token_name"`
-	TokenDecimals int            `json:"token_decimals"`
-	IsActive      bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	ID           uint           `json:"id" gorm:"primarykey"`
+	WalletID     uint           `json:"wallet_id" gorm:"not null"`
+	TokenID      uint           `json:"token_id" gorm:"not null;default:0;index"`
+	TokenAddress string         `json:"token_address" gorm:"not null"`
+	IsActive     bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	Token        *Token         `json:"token,omitempty" gorm:"foreignKey:TokenID"`
+}
+
+// Chain 描述服务端支持的一条链
+type Chain struct {
+	ChainID        int    `json:"chain_id"`
+	Name           string `json:"name"`
+	NativeSymbol   string `json:"native_symbol"`
+	NativeDecimals int    `json:"native_decimals"`
+	ExplorerURL    string `json:"explorer_url,omitempty"`
+	Available      bool   `json:"available"`
+	Error          string `json:"error,omitempty"`
 }
 
+// 资产类型：链原生币（ETH/BNB/MATIC）或 ERC-20 代币
+const (
+	AssetTypeNative = "native"
+	AssetTypeERC20  = "erc20"
+)
+
 type TokenBalance struct {
-	WalletAddress string  `json:"wallet_address"`
-	TokenAddress  string  `json:"token_address"`
-	Balance       string  `json:"balance"`
-	Symbol        string  `json:"symbol"`
-	Name          string  `json:"name"`
-	Decimals      int     `json:"decimals"`
-	ChainID       int     `json:"chain_id"`
-	ChainName     string  `json:"chain_name"`
-	USDValue      float64 `json:"usd_value,omitempty"`
+	WalletAddress string `json:"wallet_address"`
+	AssetType     string `json:"asset_type"`
+	TokenAddress  string `json:"token_address,omitempty"`
+	// RawBalance 是最小单位的整数余额，Balance 是按 Decimals 换算后的精确小数
+	RawBalance string `json:"raw_balance,omitempty"`
+	Balance    string `json:"balance"`
+	Symbol     string `json:"symbol"`
+	Name       string `json:"name"`
+	Decimals   int    `json:"decimals"`
+	ChainID    int    `json:"chain_id"`
+	ChainName  string `json:"chain_name"`
+	// token 元数据使用了默认值时的警告，见 Token.Warnings
+	Warnings []string `json:"warnings,omitempty"`
+	// token 被标记为垃圾 token，默认不出现在余额接口中
+	Spam bool `json:"spam,omitempty"`
+	// 精确小数的字符串，避免浮点误差
+	USDValue string `json:"usd_value,omitempty"`
+	// 未舍入的 USDValue，汇总时先相加再舍入
+	USDValueExact *decimal.Decimal `json:"-"`
+	// 查询状态，失败时 Error 说明原因
+	Status    string     `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+	FromCache bool       `json:"from_cache"`
+	// 读取余额的区块，查询最新余额时为空
+	BlockNumber uint64 `json:"block_number,omitempty"`
+}
+
+// ChainBlock 是一条链上读取余额使用的区块
+type ChainBlock struct {
+	ChainID     int       `json:"chain_id"`
+	ChainName   string    `json:"chain_name"`
+	BlockNumber uint64    `json:"block_number"`
+	Timestamp   time.Time `json:"timestamp"`
+	Error       string    `json:"error,omitempty"`
 }
+
+// BalanceTotal 是同一条链上同一资产在所有钱包中的余额合计
+type BalanceTotal struct {
+	ChainID      int    `json:"chain_id"`
+	ChainName    string `json:"chain_name"`
+	AssetType    string `json:"asset_type"`
+	TokenAddress string `json:"token_address,omitempty"`
+	Symbol       string `json:"symbol"`
+	Decimals     int    `json:"decimals"`
+	RawBalance   string `json:"raw_balance"`
+	Balance      string `json:"balance"`
+	Wallets      int    `json:"wallets"`
+}
+
+// 余额条目的查询状态
+const (
+	BalanceStatusOK               = "ok"
+	BalanceStatusCached           = "cached"
+	BalanceStatusStale            = "stale" // RPC 失败，返回的是旧的缓存值
+	BalanceStatusRPCError         = "rpc_error"
+	BalanceStatusUnsupportedChain = "unsupported_chain"
+	BalanceStatusNonStandardToken = "non_standard_token"
+	BalanceStatusTimeout          = "timeout"
+	BalanceStatusArchiveRequired  = "archive_required" // 节点没有该历史区块的状态
+	BalanceStatusInvalidBlock     = "invalid_block"    // 区块不存在或时间早于创世区块
+)
@@ -13,7 +13,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	assert.NoError(t, err)
 
-	err = db.AutoMigrate(&User{}, &Wallet{}, &WalletToken{})
+	err = db.AutoMigrate(&User{}, &Wallet{}, &Token{}, &WalletToken{}, &TokenList{})
 	assert.NoError(t, err)
 
 	return db
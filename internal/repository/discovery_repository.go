@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"wallet-tracker/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DiscoveryRepository struct {
+	db *gorm.DB
+}
+
+func NewDiscoveryRepository(db *gorm.DB) *DiscoveryRepository {
+	return &DiscoveryRepository{db: db}
+}
+
+func (dr *DiscoveryRepository) GetCursor(walletID uint) (*model.DiscoveryCursor, error) {
+	var cursor model.DiscoveryCursor
+	if err := dr.db.Where("wallet_id = ?", walletID).First(&cursor).Error; err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+func (dr *DiscoveryRepository) SaveCursor(cursor *model.DiscoveryCursor) error {
+	return dr.db.Save(cursor).Error
+}
+
+// CreateSuggestion 添加建议，同一个钱包和 token 的建议已存在时保留原有记录
+func (dr *DiscoveryRepository) CreateSuggestion(suggestion *model.TokenSuggestion) (bool, error) {
+	result := dr.db.Clauses(clause.OnConflict{DoNothing: true}).Create(suggestion)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ListSuggestions 返回钱包的建议，status 为空时不过滤
+func (dr *DiscoveryRepository) ListSuggestions(walletID uint, status string) ([]model.TokenSuggestion, error) {
+	query := dr.db.Preload("Token").Where("wallet_id = ?", walletID).Order("id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var suggestions []model.TokenSuggestion
+	if err := query.Find(&suggestions).Error; err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+func (dr *DiscoveryRepository) GetSuggestion(id uint) (*model.TokenSuggestion, error) {
+	var suggestion model.TokenSuggestion
+	if err := dr.db.Preload("Token").First(&suggestion, id).Error; err != nil {
+		return nil, err
+	}
+	return &suggestion, nil
+}
+
+func (dr *DiscoveryRepository) UpdateSuggestionStatus(id uint, status string) error {
+	return dr.db.Model(&model.TokenSuggestion{}).Where("id = ?", id).Update("status", status).Error
+}
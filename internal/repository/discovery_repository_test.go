@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"testing"
+
+	"wallet-tracker/internal/model"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type DiscoveryRepositoryTestSuite struct {
+	suite.Suite
+	repo  *DiscoveryRepository
+	token *model.Token
+}
+
+func (suite *DiscoveryRepositoryTestSuite) SetupTest() {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	err = db.AutoMigrate(&model.Token{}, &model.DiscoveryCursor{}, &model.TokenSuggestion{})
+	suite.Require().NoError(err)
+
+	suite.repo = NewDiscoveryRepository(db)
+	suite.token, err = NewTokenRepository(db).FirstOrCreate(&model.Token{ChainID: 1, Address: "0x1", Symbol: "A"})
+	suite.Require().NoError(err)
+}
+
+func (suite *DiscoveryRepositoryTestSuite) TestCursor() {
+	_, err := suite.repo.GetCursor(1)
+	suite.ErrorIs(err, gorm.ErrRecordNotFound)
+
+	suite.Require().NoError(suite.repo.SaveCursor(&model.DiscoveryCursor{WalletID: 1, NextBlock: 100}))
+
+	cursor, err := suite.repo.GetCursor(1)
+	suite.Require().NoError(err)
+	suite.Equal(uint64(100), cursor.NextBlock)
+
+	cursor.NextBlock = 200
+	suite.Require().NoError(suite.repo.SaveCursor(cursor))
+
+	cursor, err = suite.repo.GetCursor(1)
+	suite.Require().NoError(err)
+	suite.Equal(uint64(200), cursor.NextBlock)
+}
+
+func (suite *DiscoveryRepositoryTestSuite) TestSuggestions() {
+	created, err := suite.repo.CreateSuggestion(&model.TokenSuggestion{WalletID: 1, TokenID: suite.token.ID, Balance: "5", Status: model.SuggestionStatusPending})
+	suite.Require().NoError(err)
+	suite.True(created)
+
+	// 同一个 token 不重复建议
+	created, err = suite.repo.CreateSuggestion(&model.TokenSuggestion{WalletID: 1, TokenID: suite.token.ID, Status: model.SuggestionStatusPending})
+	suite.Require().NoError(err)
+	suite.False(created)
+
+	pending, err := suite.repo.ListSuggestions(1, model.SuggestionStatusPending)
+	suite.Require().NoError(err)
+	suite.Require().Len(pending, 1)
+	suite.Equal("5", pending[0].Balance)
+	suite.Equal("A", pending[0].Token.Symbol)
+
+	suite.Require().NoError(suite.repo.UpdateSuggestionStatus(pending[0].ID, model.SuggestionStatusDismissed))
+
+	pending, err = suite.repo.ListSuggestions(1, model.SuggestionStatusPending)
+	suite.Require().NoError(err)
+	suite.Empty(pending)
+
+	all, err := suite.repo.ListSuggestions(1, "")
+	suite.Require().NoError(err)
+	suite.Require().Len(all, 1)
+	suite.Equal(model.SuggestionStatusDismissed, all[0].Status)
+}
+
+func TestDiscoveryRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(DiscoveryRepositoryTestSuite))
+}
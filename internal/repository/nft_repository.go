@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"wallet-tracker/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NFTRepository struct {
+	db *gorm.DB
+}
+
+func NewNFTRepository(db *gorm.DB) *NFTRepository {
+	return &NFTRepository{db: db}
+}
+
+// ListItems 返回钱包在合集中持有的 NFT，按记录顺序排列
+func (nr *NFTRepository) ListItems(walletID, collectionID uint) ([]model.NFTItem, error) {
+	var items []model.NFTItem
+	err := nr.db.Where("wallet_id = ? AND collection_id = ?", walletID, collectionID).Order("id").Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ReplaceItems 让钱包在合集中的记录与 items 一致：不再持有的删除，新持有的添加，
+// 已有记录更新持有数量，元数据保留
+func (nr *NFTRepository) ReplaceItems(walletID, collectionID uint, items []model.NFTItem) error {
+	return nr.db.Transaction(func(tx *gorm.DB) error {
+		tokenIDs := make([]string, len(items))
+		for i := range items {
+			items[i].WalletID = walletID
+			items[i].CollectionID = collectionID
+			tokenIDs[i] = items[i].TokenID
+		}
+
+		stale := tx.Where("wallet_id = ? AND collection_id = ?", walletID, collectionID)
+		if len(tokenIDs) > 0 {
+			stale = stale.Where("token_id NOT IN ?", tokenIDs)
+		}
+		if err := stale.Delete(&model.NFTItem{}).Error; err != nil {
+			return err
+		}
+
+		if len(items) == 0 {
+			return nil
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "wallet_id"}, {Name: "collection_id"}, {Name: "token_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"balance", "updated_at"}),
+		}).CreateInBatches(items, 100).Error
+	})
+}
+
+func (nr *NFTRepository) UpdateItem(item *model.NFTItem) error {
+	return nr.db.Save(item).Error
+}
+
+func (nr *NFTRepository) GetSync(walletID, collectionID uint) (*model.NFTSync, error) {
+	var sync model.NFTSync
+	if err := nr.db.Where("wallet_id = ? AND collection_id = ?", walletID, collectionID).First(&sync).Error; err != nil {
+		return nil, err
+	}
+	return &sync, nil
+}
+
+func (nr *NFTRepository) SaveSync(sync *model.NFTSync) error {
+	return nr.db.Save(sync).Error
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"testing"
+
+	"wallet-tracker/internal/model"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type NFTRepositoryTestSuite struct {
+	suite.Suite
+	repo *NFTRepository
+}
+
+func (suite *NFTRepositoryTestSuite) SetupTest() {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	err = db.AutoMigrate(&model.NFTItem{}, &model.NFTSync{})
+	suite.Require().NoError(err)
+
+	suite.repo = NewNFTRepository(db)
+}
+
+func nftItems(tokenIDs ...string) []model.NFTItem {
+	items := make([]model.NFTItem, len(tokenIDs))
+	for i, tokenID := range tokenIDs {
+		items[i] = model.NFTItem{TokenID: tokenID, Balance: "1"}
+	}
+	return items
+}
+
+func (suite *NFTRepositoryTestSuite) TestReplaceItems() {
+	suite.Require().NoError(suite.repo.ReplaceItems(1, 10, nftItems("1", "2")))
+	// 另一个合集的记录不受影响
+	suite.Require().NoError(suite.repo.ReplaceItems(1, 11, nftItems("1")))
+
+	items, err := suite.repo.ListItems(1, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(items, 2)
+
+	// 已有记录的元数据保留
+	items[1].Name = "Token #2"
+	suite.Require().NoError(suite.repo.UpdateItem(&items[1]))
+
+	// ERC-1155 的持有数量随同步更新
+	suite.Require().NoError(suite.repo.ReplaceItems(1, 10, []model.NFTItem{{TokenID: "2", Balance: "5"}, {TokenID: "3", Balance: "1"}}))
+	items, err = suite.repo.ListItems(1, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(items, 2)
+	suite.Equal("2", items[0].TokenID)
+	suite.Equal("Token #2", items[0].Name)
+	suite.Equal("5", items[0].Balance)
+	suite.Equal("3", items[1].TokenID)
+
+	suite.Require().NoError(suite.repo.ReplaceItems(1, 10, nil))
+	items, err = suite.repo.ListItems(1, 10)
+	suite.NoError(err)
+	suite.Empty(items)
+
+	other, err := suite.repo.ListItems(1, 11)
+	suite.NoError(err)
+	suite.Len(other, 1)
+}
+
+func (suite *NFTRepositoryTestSuite) TestSync() {
+	_, err := suite.repo.GetSync(1, 10)
+	suite.ErrorIs(err, gorm.ErrRecordNotFound)
+
+	suite.Require().NoError(suite.repo.SaveSync(&model.NFTSync{WalletID: 1, CollectionID: 10, Balance: "3", NextBlock: 100}))
+
+	sync, err := suite.repo.GetSync(1, 10)
+	suite.Require().NoError(err)
+	suite.Equal("3", sync.Balance)
+	suite.Equal(uint64(100), sync.NextBlock)
+}
+
+func TestNFTRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(NFTRepositoryTestSuite))
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"time"
+
+	"wallet-tracker/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 一次删除的快照 ID 数，避免 IN 列表过长
+const snapshotDeleteBatch = 500
+
+type SnapshotRepository struct {
+	db *gorm.DB
+}
+
+func NewSnapshotRepository(db *gorm.DB) *SnapshotRepository {
+	return &SnapshotRepository{db: db}
+}
+
+func (sr *SnapshotRepository) Create(snapshots []model.BalanceSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return sr.db.CreateInBatches(snapshots, 100).Error
+}
+
+// ListByWallet 返回钱包在 [from, to] 内的快照，按时间排列
+func (sr *SnapshotRepository) ListByWallet(walletID uint, from, to time.Time) ([]model.BalanceSnapshot, error) {
+	var snapshots []model.BalanceSnapshot
+	err := sr.db.Where("wallet_id = ? AND taken_at BETWEEN ? AND ?", walletID, from, to).
+		Order("taken_at, id").Find(&snapshots).Error
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// ListByUser 返回用户所有钱包在 [from, to] 内的快照，按时间排列
+func (sr *SnapshotRepository) ListByUser(userID uint, from, to time.Time) ([]model.BalanceSnapshot, error) {
+	var snapshots []model.BalanceSnapshot
+	err := sr.db.Where("user_id = ? AND taken_at BETWEEN ? AND ?", userID, from, to).
+		Order("taken_at, id").Find(&snapshots).Error
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// Downsample 把 before 之前精度为 from 的快照降为 to：每个钱包在每个 bucket 内只保留最早的
+// 一次记录的全部快照，其余删除，保留下来的点仍然是同一区块上一致的余额。
+// before 按 bucket 对齐，只处理完整的区间，返回删除的条数
+func (sr *SnapshotRepository) Downsample(from, to string, bucket time.Duration, before time.Time) (int64, error) {
+	before = before.Truncate(bucket)
+
+	var rows []model.BalanceSnapshot
+	err := sr.db.Select("id", "wallet_id", "taken_at").
+		Where("resolution = ? AND taken_at < ?", from, before).
+		Order("taken_at, id").Find(&rows).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	type bucketKey struct {
+		walletID uint
+		start    int64
+	}
+
+	// 按时间排列，bucket 内第一次出现的时间就是最早的一次记录
+	kept := make(map[bucketKey]time.Time)
+	var keep, drop []uint
+	for _, row := range rows {
+		key := bucketKey{row.WalletID, row.TakenAt.Truncate(bucket).UnixNano()}
+		takenAt, exists := kept[key]
+		if !exists {
+			kept[key] = row.TakenAt
+			takenAt = row.TakenAt
+		}
+		if row.TakenAt.Equal(takenAt) {
+			keep = append(keep, row.ID)
+		} else {
+			drop = append(drop, row.ID)
+		}
+	}
+
+	var deleted int64
+	err = sr.db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(drop); start += snapshotDeleteBatch {
+			end := min(start+snapshotDeleteBatch, len(drop))
+			result := tx.Delete(&model.BalanceSnapshot{}, drop[start:end])
+			if result.Error != nil {
+				return result.Error
+			}
+			deleted += result.RowsAffected
+		}
+
+		for start := 0; start < len(keep); start += snapshotDeleteBatch {
+			end := min(start+snapshotDeleteBatch, len(keep))
+			err := tx.Model(&model.BalanceSnapshot{}).Where("id IN ?", keep[start:end]).
+				Update("resolution", to).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/model"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type SnapshotRepositoryTestSuite struct {
+	suite.Suite
+	repo *SnapshotRepository
+	base time.Time
+}
+
+func (suite *SnapshotRepositoryTestSuite) SetupTest() {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	err = db.AutoMigrate(&model.BalanceSnapshot{})
+	suite.Require().NoError(err)
+
+	suite.repo = NewSnapshotRepository(db)
+	suite.base = time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// snapshots 为钱包 1 的原生币每 interval 生成一条快照
+func (suite *SnapshotRepositoryTestSuite) snapshots(count int, interval time.Duration) []model.BalanceSnapshot {
+	snapshots := make([]model.BalanceSnapshot, count)
+	for i := range snapshots {
+		snapshots[i] = model.BalanceSnapshot{
+			UserID:     1,
+			WalletID:   1,
+			ChainID:    1,
+			AssetType:  model.AssetTypeNative,
+			RawBalance: "1",
+			Balance:    "0.000000000000000001",
+			TakenAt:    suite.base.Add(time.Duration(i) * interval),
+			Resolution: model.SnapshotResolutionRaw,
+		}
+	}
+	return snapshots
+}
+
+func (suite *SnapshotRepositoryTestSuite) TestList() {
+	snapshots := suite.snapshots(4, time.Hour)
+	snapshots[3].WalletID = 2
+	snapshots[3].UserID = 2
+	suite.Require().NoError(suite.repo.Create(snapshots))
+
+	wallet, err := suite.repo.ListByWallet(1, suite.base.Add(time.Hour), suite.base.Add(3*time.Hour))
+	suite.Require().NoError(err)
+	suite.Require().Len(wallet, 2)
+	suite.True(wallet[0].TakenAt.Equal(suite.base.Add(time.Hour)))
+
+	user, err := suite.repo.ListByUser(2, suite.base, suite.base.Add(24*time.Hour))
+	suite.Require().NoError(err)
+	suite.Len(user, 1)
+}
+
+func (suite *SnapshotRepositoryTestSuite) TestDownsample() {
+	// 3 小时内每 15 分钟一条；另一种资产出现在 00:00 和 00:15 的两次记录中，
+	// 另一个钱包只在 00:30 有记录
+	snapshots := suite.snapshots(12, 15*time.Minute)
+	for _, i := range []int{0, 1} {
+		token := snapshots[i]
+		token.TokenAddress = "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"
+		snapshots = append(snapshots, token)
+	}
+	other := snapshots[2]
+	other.WalletID = 2
+	snapshots = append(snapshots, other)
+	suite.Require().NoError(suite.repo.Create(snapshots))
+
+	// 只处理 cutoff 之前完整的小时：cutoff 对齐到 02:00
+	deleted, err := suite.repo.Downsample(model.SnapshotResolutionRaw, model.SnapshotResolutionHourly, time.Hour, suite.base.Add(2*time.Hour+30*time.Minute))
+	suite.Require().NoError(err)
+	suite.Equal(int64(7), deleted)
+
+	remaining, err := suite.repo.ListByWallet(1, suite.base, suite.base.Add(24*time.Hour))
+	suite.Require().NoError(err)
+	suite.Require().Len(remaining, 7)
+	// 第一个小时保留 00:00 的整次记录，两种资产来自同一次记录
+	for _, snapshot := range remaining[:2] {
+		suite.True(snapshot.TakenAt.Equal(suite.base))
+		suite.Equal(model.SnapshotResolutionHourly, snapshot.Resolution)
+	}
+	suite.NotEqual(remaining[0].TokenAddress, remaining[1].TokenAddress)
+	suite.True(remaining[2].TakenAt.Equal(suite.base.Add(time.Hour)))
+	suite.Equal(model.SnapshotResolutionRaw, remaining[3].Resolution)
+
+	// 每个钱包单独选择保留的记录
+	otherRemaining, err := suite.repo.ListByWallet(2, suite.base, suite.base.Add(24*time.Hour))
+	suite.Require().NoError(err)
+	suite.Require().Len(otherRemaining, 1)
+	suite.True(otherRemaining[0].TakenAt.Equal(suite.base.Add(30 * time.Minute)))
+
+	// 再次执行没有变化
+	deleted, err = suite.repo.Downsample(model.SnapshotResolutionRaw, model.SnapshotResolutionHourly, time.Hour, suite.base.Add(2*time.Hour+30*time.Minute))
+	suite.Require().NoError(err)
+	suite.Zero(deleted)
+
+	deleted, err = suite.repo.Downsample(model.SnapshotResolutionHourly, model.SnapshotResolutionDaily, 24*time.Hour, suite.base.Add(48*time.Hour))
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), deleted)
+
+	remaining, err = suite.repo.ListByWallet(1, suite.base, suite.base.Add(24*time.Hour))
+	suite.Require().NoError(err)
+	suite.Require().Len(remaining, 6)
+	suite.Equal(model.SnapshotResolutionDaily, remaining[0].Resolution)
+	suite.Equal(model.SnapshotResolutionDaily, remaining[1].Resolution)
+}
+
+func TestSnapshotRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(SnapshotRepositoryTestSuite))
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"wallet-tracker/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type TokenListRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenListRepository(db *gorm.DB) *TokenListRepository {
+	return &TokenListRepository{db: db}
+}
+
+func (lr *TokenListRepository) GetByID(id uint) (*model.TokenList, error) {
+	var list model.TokenList
+	if err := lr.db.First(&list, id).Error; err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (lr *TokenListRepository) GetByName(name string) (*model.TokenList, error) {
+	var list model.TokenList
+	if err := lr.db.Where("name = ?", name).First(&list).Error; err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (lr *TokenListRepository) List() ([]model.TokenList, error) {
+	var lists []model.TokenList
+	if err := lr.db.Order("name").Find(&lists).Error; err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// Save 写入列表，并把列表包含的 token 替换为 tokens
+func (lr *TokenListRepository) Save(list *model.TokenList, tokens []model.Token) (*model.TokenList, error) {
+	err := lr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(list).Error; err != nil {
+			return err
+		}
+		return tx.Model(list).Association("Tokens").Replace(tokens)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (lr *TokenListRepository) Subscribe(walletID, listID uint) error {
+	wallet := model.Wallet{ID: walletID}
+	return lr.db.Model(&wallet).Association("TokenLists").Append(&model.TokenList{ID: listID})
+}
+
+func (lr *TokenListRepository) Unsubscribe(walletID, listID uint) error {
+	wallet := model.Wallet{ID: walletID}
+	return lr.db.Model(&wallet).Association("TokenLists").Delete(&model.TokenList{ID: listID})
+}
+
+// SubscribedToken 是钱包通过订阅的列表跟踪的 token
+type SubscribedToken struct {
+	WalletID uint
+	model.Token
+}
+
+// SubscribedTokens 返回各钱包订阅的列表中与钱包在同一条链上的 token，
+// 同一个 token 出现在多个列表中时只返回一次
+func (lr *TokenListRepository) SubscribedTokens(walletIDs []uint) ([]SubscribedToken, error) {
+	var tokens []SubscribedToken
+	if len(walletIDs) == 0 {
+		return tokens, nil
+	}
+
+	err := lr.db.Table("tokens").
+		Select("DISTINCT wallet_token_lists.wallet_id, tokens.*").
+		Joins("JOIN token_list_entries ON token_list_entries.token_id = tokens.id").
+		Joins("JOIN wallet_token_lists ON wallet_token_lists.token_list_id = token_list_entries.token_list_id").
+		Joins("JOIN wallets ON wallets.id = wallet_token_lists.wallet_id AND wallets.chain_id = tokens.chain_id").
+		Where("wallet_token_lists.wallet_id IN ?", walletIDs).
+		Order("wallet_token_lists.wallet_id, tokens.id").
+		Scan(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// ListedTokenIDs 返回出现在任意一个已导入列表中的 token
+func (lr *TokenListRepository) ListedTokenIDs() ([]uint, error) {
+	var ids []uint
+	if err := lr.db.Table("token_list_entries").Distinct("token_id").Order("token_id").Pluck("token_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
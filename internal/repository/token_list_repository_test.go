@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/model"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type TokenListRepositoryTestSuite struct {
+	suite.Suite
+	repo       *TokenListRepository
+	tokenRepo  *TokenRepository
+	walletRepo *WalletRepository
+}
+
+func (suite *TokenListRepositoryTestSuite) SetupTest() {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	err = db.AutoMigrate(&model.User{}, &model.Wallet{}, &model.Token{}, &model.WalletToken{}, &model.TokenList{})
+	suite.Require().NoError(err)
+
+	suite.repo = NewTokenListRepository(db)
+	suite.tokenRepo = NewTokenRepository(db)
+	suite.walletRepo = NewWalletRepository(db)
+}
+
+func (suite *TokenListRepositoryTestSuite) createTokens(tokens ...model.Token) []model.Token {
+	var created []model.Token
+	for i := range tokens {
+		token, err := suite.tokenRepo.FirstOrCreate(&tokens[i])
+		suite.Require().NoError(err)
+		created = append(created, *token)
+	}
+	return created
+}
+
+func (suite *TokenListRepositoryTestSuite) TestSaveReplacesTokens() {
+	tokens := suite.createTokens(
+		model.Token{ChainID: 1, Address: "0x1", Symbol: "A"},
+		model.Token{ChainID: 1, Address: "0x2", Symbol: "B"},
+	)
+
+	list := &model.TokenList{Name: "Test List", Version: "1.0.0", VersionMajor: 1, Timestamp: time.Now(), TokenCount: 2}
+	_, err := suite.repo.Save(list, tokens)
+	suite.Require().NoError(err)
+
+	found, err := suite.repo.GetByName("Test List")
+	suite.Require().NoError(err)
+	suite.Equal(list.ID, found.ID)
+
+	// 新版本只包含一个 token
+	found.Version = "1.1.0"
+	found.VersionMinor = 1
+	found.TokenCount = 1
+	_, err = suite.repo.Save(found, tokens[1:])
+	suite.Require().NoError(err)
+
+	var entries []model.Token
+	suite.Require().NoError(suite.repo.db.Model(found).Association("Tokens").Find(&entries))
+	suite.Require().Len(entries, 1)
+	suite.Equal("B", entries[0].Symbol)
+
+	listed, err := suite.repo.ListedTokenIDs()
+	suite.NoError(err)
+	suite.Equal([]uint{tokens[1].ID}, listed)
+
+	lists, err := suite.repo.List()
+	suite.NoError(err)
+	suite.Len(lists, 1)
+}
+
+func (suite *TokenListRepositoryTestSuite) TestSubscribedTokens() {
+	tokens := suite.createTokens(
+		model.Token{ChainID: 1, Address: "0x1", Symbol: "A"},
+		model.Token{ChainID: 1, Address: "0x2", Symbol: "B"},
+		model.Token{ChainID: 56, Address: "0x3", Symbol: "C"},
+	)
+
+	first, err := suite.repo.Save(&model.TokenList{Name: "First", Timestamp: time.Now()}, tokens)
+	suite.Require().NoError(err)
+	second, err := suite.repo.Save(&model.TokenList{Name: "Second", Timestamp: time.Now()}, tokens[:1])
+	suite.Require().NoError(err)
+
+	wallet, err := suite.walletRepo.Create(&model.Wallet{UserID: 1, Address: "0xWallet", ChainID: 1, ChainName: "Ethereum"})
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.repo.Subscribe(wallet.ID, first.ID))
+	suite.Require().NoError(suite.repo.Subscribe(wallet.ID, second.ID))
+
+	// 只返回钱包所在链上的 token，两个列表都包含的 token 只返回一次
+	subscribed, err := suite.repo.SubscribedTokens([]uint{wallet.ID})
+	suite.Require().NoError(err)
+	suite.Require().Len(subscribed, 2)
+	suite.Equal(wallet.ID, subscribed[0].WalletID)
+	suite.Equal("A", subscribed[0].Symbol)
+	suite.Equal("B", subscribed[1].Symbol)
+
+	withLists, err := suite.walletRepo.GetByID(wallet.ID)
+	suite.Require().NoError(err)
+	suite.Len(withLists.TokenLists, 2)
+
+	suite.Require().NoError(suite.repo.Unsubscribe(wallet.ID, first.ID))
+	subscribed, err = suite.repo.SubscribedTokens([]uint{wallet.ID})
+	suite.Require().NoError(err)
+	suite.Require().Len(subscribed, 1)
+	suite.Equal("A", subscribed[0].Symbol)
+}
+
+func TestTokenListRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(TokenListRepositoryTestSuite))
+}
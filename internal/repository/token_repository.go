@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"wallet-tracker/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+// FirstOrCreate 按 (链, 地址) 查找 token，不存在时写入；并发写入同一个 token 时以先写入的为准
+func (tr *TokenRepository) FirstOrCreate(token *model.Token) (*model.Token, error) {
+	if err := tr.db.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error; err != nil {
+		return nil, err
+	}
+	return tr.GetByChainAddress(token.ChainID, token.Address)
+}
+
+func (tr *TokenRepository) GetByID(id uint) (*model.Token, error) {
+	var token model.Token
+	if err := tr.db.First(&token, id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (tr *TokenRepository) GetByChainAddress(chainID int, address string) (*model.Token, error) {
+	var token model.Token
+	if err := tr.db.Where("chain_id = ? AND address = ?", chainID, address).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// List 按链和核验状态过滤，参数为零值时不过滤
+func (tr *TokenRepository) List(chainID int, status string) ([]model.Token, error) {
+	query := tr.db.Order("chain_id, id")
+	if chainID != 0 {
+		query = query.Where("chain_id = ?", chainID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var tokens []model.Token
+	if err := query.Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (tr *TokenRepository) Update(token *model.Token) (*model.Token, error) {
+	if err := tr.db.Save(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"testing"
+
+	"wallet-tracker/internal/model"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type TokenRepositoryTestSuite struct {
+	suite.Suite
+	repo *TokenRepository
+}
+
+func (suite *TokenRepositoryTestSuite) SetupTest() {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	err = db.AutoMigrate(&model.Token{})
+	suite.Require().NoError(err)
+
+	suite.repo = NewTokenRepository(db)
+}
+
+func (suite *TokenRepositoryTestSuite) TestFirstOrCreate() {
+	created, err := suite.repo.FirstOrCreate(&model.Token{
+		ChainID:  1,
+		Address:  "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48",
+		Symbol:   "USDC",
+		Decimals: 6,
+		Status:   model.TokenStatusUnverified,
+	})
+	suite.NoError(err)
+	suite.NotZero(created.ID)
+
+	// 已存在的 token 保留原有元数据
+	again, err := suite.repo.FirstOrCreate(&model.Token{
+		ChainID:  1,
+		Address:  "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48",
+		Symbol:   "FAKE",
+		Decimals: 18,
+	})
+	suite.NoError(err)
+	suite.Equal(created.ID, again.ID)
+	suite.Equal("USDC", again.Symbol)
+
+	// 其它链上的同一地址是另一个 token
+	bsc, err := suite.repo.FirstOrCreate(&model.Token{
+		ChainID: 56,
+		Address: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48",
+		Symbol:  "USDC",
+	})
+	suite.NoError(err)
+	suite.NotEqual(created.ID, bsc.ID)
+}
+
+func (suite *TokenRepositoryTestSuite) TestListAndUpdate() {
+	for _, token := range []*model.Token{
+		{ChainID: 1, Address: "0x1", Symbol: "A", Status: model.TokenStatusVerified},
+		{ChainID: 1, Address: "0x2", Symbol: "B", Status: model.TokenStatusUnverified},
+		{ChainID: 56, Address: "0x3", Symbol: "C", Status: model.TokenStatusUnverified},
+	} {
+		_, err := suite.repo.FirstOrCreate(token)
+		suite.Require().NoError(err)
+	}
+
+	all, err := suite.repo.List(0, "")
+	suite.NoError(err)
+	suite.Len(all, 3)
+
+	ethereum, err := suite.repo.List(1, "")
+	suite.NoError(err)
+	suite.Len(ethereum, 2)
+
+	unverified, err := suite.repo.List(1, model.TokenStatusUnverified)
+	suite.NoError(err)
+	suite.Require().Len(unverified, 1)
+	suite.Equal("B", unverified[0].Symbol)
+
+	unverified[0].Status = model.TokenStatusVerified
+	unverified[0].Overridden = true
+	_, err = suite.repo.Update(&unverified[0])
+	suite.NoError(err)
+
+	found, err := suite.repo.GetByChainAddress(1, "0x2")
+	suite.NoError(err)
+	suite.Equal(model.TokenStatusVerified, found.Status)
+	suite.True(found.Overridden)
+}
+
+func TestTokenRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(TokenRepositoryTestSuite))
+}
@@ -12,6 +12,7 @@ type UserRepositoryInterface interface {
 	GetByID(id uint) (*model.User, error)
 	GetByUsername(username string) (*model.User, error)
 	GetByEmail(email string) (*model.User, error)
+	Update(user *model.User) (*model.User, error)
 }
 
 type UserRepository struct {
@@ -52,3 +53,10 @@ func (ur *UserRepository) GetByEmail(email string) (*model.User, error) {
 	}
 	return &user, nil
 }
+
+func (ur *UserRepository) Update(user *model.User) (*model.User, error) {
+	if err := ur.db.Save(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
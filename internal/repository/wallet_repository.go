@@ -30,7 +30,7 @@ func (wr *WalletRepository) CreateToken(token *model.WalletToken) (*model.Wallet
 
 func (wr *WalletRepository) GetByUserID(userID uint) ([]model.Wallet, error) {
 	var wallets []model.Wallet
-	if err := wr.db.Preload("Tokens").Where("user_id = ?", userID).Find(&wallets).Error; err != nil {
+	if err := wr.db.Preload("Tokens.Token").Preload("TokenLists").Where("user_id = ?", userID).Find(&wallets).Error; err != nil {
 		return nil, err
 	}
 	return wallets, nil
@@ -38,8 +38,17 @@ func (wr *WalletRepository) GetByUserID(userID uint) ([]model.Wallet, error) {
 
 func (wr *WalletRepository) GetByID(id uint) (*model.Wallet, error) {
 	var wallet model.Wallet
-	if err := wr.db.Preload("Tokens").First(&wallet, id).Error; err != nil {
+	if err := wr.db.Preload("Tokens.Token").Preload("TokenLists").First(&wallet, id).Error; err != nil {
 		return nil, err
 	}
 	return &wallet, nil
 }
+
+// List 返回所有钱包，用于后台任务
+func (wr *WalletRepository) List() ([]model.Wallet, error) {
+	var wallets []model.Wallet
+	if err := wr.db.Preload("Tokens.Token").Order("id").Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
@@ -22,7 +22,7 @@ func (suite *WalletRepositoryTestSuite) SetupTest() {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	suite.Require().NoError(err)
 
-	err = db.AutoMigrate(&model.User{}, &model.Wallet{}, &model.WalletToken{})
+	err = db.AutoMigrate(&model.User{}, &model.Wallet{}, &model.Token{}, &model.WalletToken{}, &model.TokenList{})
 	suite.Require().NoError(err)
 
 	suite.db = db
@@ -97,21 +97,35 @@ func (suite *WalletRepositoryTestSuite) TestCreateToken() {
 	createdWallet, err := suite.repo.Create(wallet)
 	suite.NoError(err)
 
+	// 元数据保存在全局 token 表中
+	usdc, err := NewTokenRepository(suite.db).FirstOrCreate(&model.Token{
+		ChainID:  1,
+		Address:  "0xA0b86a33E6417C9f3b6C37Bb8E0A8b8BBf9f2f71",
+		Symbol:   "USDC",
+		Name:     "USD Coin",
+		Decimals: 6,
+	})
+	suite.Require().NoError(err)
+
 	// 创建代币
 	token := &model.WalletToken{
-		WalletID:      createdWallet.ID,
-		TokenAddress:  "0xA0b86a33E6417C9f3b6C37Bb8E0A8b8BBf9f2f71",
-		TokenSymbol:   "USDC",
-		TokenName:     "USD Coin",
-		TokenDecimals: 6,
-		IsActive:      true,
+		WalletID:     createdWallet.ID,
+		TokenID:      usdc.ID,
+		TokenAddress: usdc.Address,
+		IsActive:     true,
 	}
 
 	createdToken, err := suite.repo.CreateToken(token)
 	suite.NoError(err)
 	suite.NotNil(createdToken)
 	suite.NotZero(createdToken.ID)
-	suite.Equal("USDC", createdToken.TokenSymbol)
+
+	// 查询钱包时带出 token 元数据
+	found, err := suite.repo.GetByID(createdWallet.ID)
+	suite.NoError(err)
+	suite.Require().Len(found.Tokens, 1)
+	suite.Require().NotNil(found.Tokens[0].Token)
+	suite.Equal("USDC", found.Tokens[0].Token.Symbol)
 }
 
 func TestWalletRepositoryTestSuite(t *testing.T) {
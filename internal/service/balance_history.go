@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wallet-tracker/internal/model"
+	"wallet-tracker/pkg/blockchain"
+)
+
+var ErrInvalidBlockParam = errors.New("invalid block parameter")
+
+// BalanceAt 指定读取历史余额的时间点。Blocks 中的链使用指定区块，
+// 其余的链使用不晚于 Time 的最后一个区块；两者都没有时使用最新区块
+type BalanceAt struct {
+	Time   *time.Time
+	Blocks map[int]uint64
+}
+
+// ParseBlocks 解析 block 参数：单个区块号只能用于所有钱包都在同一条链上的情况，
+// 多条链时使用 "1:19000000,56:38000000" 的形式
+func ParseBlocks(value string, chainIDs []int) (map[int]uint64, error) {
+	blocks := make(map[int]uint64)
+
+	if !strings.Contains(value, ":") {
+		number, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidBlockParam, value)
+		}
+
+		chains := make(map[int]bool)
+		for _, chainID := range chainIDs {
+			chains[chainID] = true
+		}
+		if len(chains) > 1 {
+			return nil, fmt.Errorf("%w: wallets are on %d chains, use chain_id:block pairs", ErrInvalidBlockParam, len(chains))
+		}
+		for chainID := range chains {
+			blocks[chainID] = number
+		}
+		return blocks, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		chainPart, blockPart, _ := strings.Cut(strings.TrimSpace(pair), ":")
+		chainID, err := strconv.Atoi(chainPart)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidBlockParam, pair)
+		}
+		number, err := strconv.ParseUint(blockPart, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidBlockParam, pair)
+		}
+		if _, exists := blocks[chainID]; exists {
+			return nil, fmt.Errorf("%w: chain %d is given more than once", ErrInvalidBlockParam, chainID)
+		}
+		blocks[chainID] = number
+	}
+	return blocks, nil
+}
+
+// GetHistoricalBalances 读取所有钱包在指定时间点的余额。同一条链上的余额都在同一个区块读取，
+// 返回的 ChainBlock 说明每条链使用的区块；无法确定区块的链，其余额条目带有错误状态。
+// at 为空时读取每条链最新区块上一致的快照
+func (bs *BlockchainService) GetHistoricalBalances(ctx context.Context, wallets []model.Wallet, at BalanceAt) ([]model.TokenBalance, []model.ChainBlock, error) {
+	ctx, cancel := context.WithTimeout(ctx, bs.requestTimeout)
+	defer cancel()
+
+	plan := planBalances(wallets)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	outcomes := make(map[int][]balanceOutcome)
+	blocks := make([]model.ChainBlock, 0, len(plan.requests))
+	for chainID, reqs := range plan.requests {
+		wg.Add(1)
+		go func(chainID int, reqs []balanceRequest) {
+			defer wg.Done()
+
+			block, chainOutcomes := bs.getHistoricalChainBalances(ctx, chainID, reqs, at)
+			mu.Lock()
+			outcomes[chainID] = chainOutcomes
+			blocks = append(blocks, block)
+			mu.Unlock()
+		}(chainID, reqs)
+	}
+	wg.Wait()
+
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return nil, nil, ctx.Err()
+	}
+
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].ChainID < blocks[j].ChainID })
+	return bs.collectBalances(plan, outcomes), blocks, nil
+}
+
+// getHistoricalChainBalances 确定链上要读取的区块，再在该区块读取一组余额。
+// 已确认的区块不会被重组，读取结果永久缓存
+func (bs *BlockchainService) getHistoricalChainBalances(ctx context.Context, chainID int, requests []balanceRequest, at BalanceAt) (model.ChainBlock, []balanceOutcome) {
+	outcomes := make([]balanceOutcome, len(requests))
+	block := model.ChainBlock{ChainID: chainID, ChainName: bs.GetChainName(chainID)}
+
+	fail := func(err error) (model.ChainBlock, []balanceOutcome) {
+		block.Error = err.Error()
+		for i := range outcomes {
+			outcomes[i].err = err
+		}
+		return block, outcomes
+	}
+
+	client, err := bs.client(chainID)
+	if err != nil {
+		return fail(err)
+	}
+
+	head, headTime, err := client.LatestHeader(ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	block.BlockNumber, block.Timestamp = head, headTime
+	if number, exists := at.Blocks[chainID]; exists {
+		if number > head {
+			return fail(fmt.Errorf("%w: %d is after the latest block %d", blockchain.ErrBlockNotFound, number, head))
+		}
+		block.BlockNumber = number
+	} else if at.Time != nil {
+		if block.BlockNumber, err = client.BlockAtTime(ctx, *at.Time); err != nil {
+			return fail(err)
+		}
+	}
+	if block.BlockNumber != head {
+		if block.Timestamp, err = client.BlockTime(ctx, block.BlockNumber); err != nil {
+			return fail(err)
+		}
+	}
+
+	var missing []int
+	for i, req := range requests {
+		cached, err := bs.cache.GetHistoricalBalance(ctx, chainID, block.BlockNumber, req.WalletAddress, req.TokenAddress)
+		if err != nil {
+			missing = append(missing, i)
+			continue
+		}
+		applyTokenMetadata(cached, req.Token)
+		cached.Status = model.BalanceStatusCached
+		cached.FromCache = true
+		outcomes[i].balance = cached
+	}
+	if len(missing) == 0 {
+		return block, outcomes
+	}
+
+	missingReqs := make([]balanceRequest, len(missing))
+	for j, i := range missing {
+		missingReqs[j] = requests[i]
+	}
+
+	fetched := bs.readChainBalances(ctx, chainID, client.NewBatchAt(block.BlockNumber), missingReqs)
+	cacheable := head-block.BlockNumber >= blockchain.ReorgSafeDepth
+	for j, i := range missing {
+		outcomes[i] = fetched[j]
+		if errors.Is(fetched[j].err, blockchain.ErrArchiveRequired) {
+			block.Error = fetched[j].err.Error()
+		}
+		if cacheable && fetched[j].err == nil {
+			req := requests[i]
+			bs.cache.SetHistoricalBalance(ctx, chainID, block.BlockNumber, req.WalletAddress, req.TokenAddress, fetched[j].balance)
+		}
+	}
+
+	return block, outcomes
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBlocks(t *testing.T) {
+	blocks, err := ParseBlocks("19000000", []int{1, 1})
+	require.NoError(t, err)
+	assert.Equal(t, map[int]uint64{1: 19000000}, blocks)
+
+	blocks, err = ParseBlocks("1:19000000, 56:38000000", []int{1, 56})
+	require.NoError(t, err)
+	assert.Equal(t, map[int]uint64{1: 19000000, 56: 38000000}, blocks)
+
+	// 多条链时必须指定链
+	_, err = ParseBlocks("19000000", []int{1, 56})
+	assert.ErrorIs(t, err, ErrInvalidBlockParam)
+
+	for _, value := range []string{"latest", "-1", "1:", "eth:100", "1:100,1:200"} {
+		_, err := ParseBlocks(value, []int{1})
+		assert.ErrorIs(t, err, ErrInvalidBlockParam, value)
+	}
+}
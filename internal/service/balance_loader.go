@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"wallet-tracker/internal/model"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// batchFetcher 一次读取一组余额，返回值与请求一一对应
+type batchFetcher func(ctx context.Context, requests []balanceRequest) []balanceOutcome
+
+type pendingLoad struct {
+	req     balanceRequest
+	outcome balanceOutcome
+	done    chan struct{}
+}
+
+// chainLoader 合并同一条链上并发到达的余额查询：
+//   - 相同 (链, 钱包, token) 的并发查询通过 singleflight 共享一次读取
+//   - 在 window 内到达的查询合并为一个批次（最多 maxBatch 个），交给 fetch 执行
+//   - 同时执行的批次数不超过 sem 的容量，避免触发服务商限流
+type chainLoader struct {
+	chainID  int
+	fetch    batchFetcher
+	window   time.Duration
+	maxBatch int
+	timeout  time.Duration
+	sem      chan struct{}
+	flight   singleflight.Group
+
+	mu      sync.Mutex
+	pending []*pendingLoad
+	timer   *time.Timer
+}
+
+func newChainLoader(chainID int, fetch batchFetcher, window time.Duration, maxBatch, maxConcurrency int, timeout time.Duration) *chainLoader {
+	return &chainLoader{
+		chainID:  chainID,
+		fetch:    fetch,
+		window:   window,
+		maxBatch: maxBatch,
+		timeout:  timeout,
+		sem:      make(chan struct{}, maxConcurrency),
+	}
+}
+
+// load 返回一次余额查询的结果。共享的读取不会因为单个调用方取消而中断，
+// 调用方只是不再等待
+func (l *chainLoader) load(ctx context.Context, req balanceRequest) (*model.TokenBalance, error) {
+	key := fmt.Sprintf("%d:%s:%s", l.chainID, strings.ToLower(req.WalletAddress), strings.ToLower(req.TokenAddress))
+
+	ch := l.flight.DoChan(key, func() (interface{}, error) {
+		p := l.enqueue(req)
+		<-p.done
+		return p.outcome.balance, p.outcome.err
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*model.TokenBalance), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *chainLoader) enqueue(req balanceRequest) *pendingLoad {
+	p := &pendingLoad{req: req, done: make(chan struct{})}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.pending = append(l.pending, p)
+	switch {
+	case len(l.pending) >= l.maxBatch:
+		if l.timer != nil {
+			l.timer.Stop()
+		}
+		batch := l.pending
+		l.pending = nil
+		go l.dispatch(batch)
+	case len(l.pending) == 1:
+		l.timer = time.AfterFunc(l.window, l.flush)
+	}
+
+	return p
+}
+
+func (l *chainLoader) flush() {
+	l.mu.Lock()
+	batch := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	if len(batch) > 0 {
+		l.dispatch(batch)
+	}
+}
+
+func (l *chainLoader) dispatch(batch []*pendingLoad) {
+	l.sem <- struct{}{}
+	defer func() { <-l.sem }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+
+	requests := make([]balanceRequest, len(batch))
+	for i, p := range batch {
+		requests[i] = p.req
+	}
+
+	outcomes := l.fetch(ctx, requests)
+	for i, p := range batch {
+		p.outcome = outcomes[i]
+		close(p.done)
+	}
+}
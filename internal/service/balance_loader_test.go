@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFetcher 记录每个批次的大小和同时执行的批次数
+type fakeFetcher struct {
+	delay time.Duration
+
+	mu      sync.Mutex
+	batches []int
+
+	active    int32
+	maxActive int32
+}
+
+func (f *fakeFetcher) fetch(ctx context.Context, requests []balanceRequest) []balanceOutcome {
+	active := atomic.AddInt32(&f.active, 1)
+	defer atomic.AddInt32(&f.active, -1)
+	for {
+		max := atomic.LoadInt32(&f.maxActive)
+		if active <= max || atomic.CompareAndSwapInt32(&f.maxActive, max, active) {
+			break
+		}
+	}
+
+	f.mu.Lock()
+	f.batches = append(f.batches, len(requests))
+	f.mu.Unlock()
+
+	time.Sleep(f.delay)
+
+	outcomes := make([]balanceOutcome, len(requests))
+	for i, req := range requests {
+		outcomes[i].balance = &model.TokenBalance{
+			WalletAddress: req.WalletAddress,
+			TokenAddress:  req.TokenAddress,
+			Balance:       "1",
+		}
+	}
+	return outcomes
+}
+
+func (f *fakeFetcher) batchSizes() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int(nil), f.batches...)
+}
+
+func loadAll(t *testing.T, loader *chainLoader, requests []balanceRequest) []*model.TokenBalance {
+	results := make([]*model.TokenBalance, len(requests))
+
+	var wg sync.WaitGroup
+	for i, req := range requests {
+		wg.Add(1)
+		go func(i int, req balanceRequest) {
+			defer wg.Done()
+			balance, err := loader.load(context.Background(), req)
+			require.NoError(t, err)
+			results[i] = balance
+		}(i, req)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func TestChainLoader_SharesDuplicateLoads(t *testing.T) {
+	fetcher := &fakeFetcher{delay: 20 * time.Millisecond}
+	loader := newChainLoader(1, fetcher.fetch, 5*time.Millisecond, 100, 4, time.Second)
+
+	req := balanceRequest{WalletAddress: "0xWhale", TokenAddress: "0xToken"}
+	requests := make([]balanceRequest, 20)
+	for i := range requests {
+		requests[i] = req
+	}
+
+	results := loadAll(t, loader, requests)
+	assert.Equal(t, []int{1}, fetcher.batchSizes())
+	for _, balance := range results {
+		assert.Equal(t, "0xWhale", balance.WalletAddress)
+	}
+}
+
+func TestChainLoader_BatchesWithinWindow(t *testing.T) {
+	fetcher := &fakeFetcher{}
+	loader := newChainLoader(1, fetcher.fetch, 20*time.Millisecond, 3, 4, time.Second)
+
+	requests := []balanceRequest{
+		{WalletAddress: "0xA"},
+		{WalletAddress: "0xB"},
+		{WalletAddress: "0xC"},
+		{WalletAddress: "0xD"},
+		{WalletAddress: "0xE"},
+	}
+
+	results := loadAll(t, loader, requests)
+	// 达到批次上限立即发出，剩余的在时间窗口结束后发出
+	assert.ElementsMatch(t, []int{3, 2}, fetcher.batchSizes())
+	for i, balance := range results {
+		assert.Equal(t, requests[i].WalletAddress, balance.WalletAddress)
+	}
+}
+
+func TestChainLoader_LimitsConcurrency(t *testing.T) {
+	fetcher := &fakeFetcher{delay: 20 * time.Millisecond}
+	loader := newChainLoader(1, fetcher.fetch, 0, 1, 2, time.Second)
+
+	requests := make([]balanceRequest, 8)
+	for i := range requests {
+		requests[i] = balanceRequest{WalletAddress: string(rune('A' + i))}
+	}
+
+	loadAll(t, loader, requests)
+	assert.Len(t, fetcher.batchSizes(), 8)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetcher.maxActive))
+}
+
+func TestChainLoader_CallerCancellation(t *testing.T) {
+	fetcher := &fakeFetcher{delay: 100 * time.Millisecond}
+	loader := newChainLoader(1, fetcher.fetch, 0, 100, 1, time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := loader.load(ctx, balanceRequest{WalletAddress: "0xA"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"wallet-tracker/internal/model"
+)
+
+type refreshJob struct {
+	chainID int
+	req     balanceRequest
+}
+
+func (j refreshJob) key() string {
+	return fmt.Sprintf("%d:%s:%s", j.chainID, strings.ToLower(j.req.WalletAddress), strings.ToLower(j.req.TokenAddress))
+}
+
+type activeUser struct {
+	lastSeen time.Time
+	wallets  []model.Wallet
+}
+
+// RefreshStats 描述后台刷新的运行状况
+type RefreshStats struct {
+	Workers       int    `json:"workers"`
+	QueueDepth    int    `json:"queue_depth"`
+	QueueCapacity int    `json:"queue_capacity"`
+	ActiveUsers   int    `json:"active_users"`
+	Queued        uint64 `json:"queued"`
+	Deduplicated  uint64 `json:"deduplicated"`
+	Dropped       uint64 `json:"dropped"`
+	Refreshed     uint64 `json:"refreshed"`
+	Failed        uint64 `json:"failed"`
+	SoftTTL       string `json:"soft_ttl"`
+	HardTTL       string `json:"hard_ttl"`
+}
+
+// balanceRefresher 用固定数量的 worker 在后台刷新过期的缓存余额，
+// 并定期为最近活跃的用户预先刷新
+type balanceRefresher struct {
+	refresh func(ctx context.Context, job refreshJob) error
+	timeout time.Duration
+	workers int
+	queue   chan refreshJob
+
+	// 活跃用户在 activeWindow 内访问过余额
+	activeWindow time.Duration
+	now          func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]bool
+	active  map[uint]*activeUser
+
+	queued       uint64
+	deduplicated uint64
+	dropped      uint64
+	refreshed    uint64
+	failed       uint64
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+func newBalanceRefresher(refresh func(ctx context.Context, job refreshJob) error, workers, queueSize int, timeout, activeWindow time.Duration) *balanceRefresher {
+	r := &balanceRefresher{
+		refresh:      refresh,
+		timeout:      timeout,
+		workers:      workers,
+		queue:        make(chan refreshJob, queueSize),
+		activeWindow: activeWindow,
+		now:          time.Now,
+		pending:      make(map[string]bool),
+		active:       make(map[uint]*activeUser),
+		done:         make(chan struct{}),
+	}
+
+	for i := 0; i < workers; i++ {
+		r.wg.Add(1)
+		go r.worker()
+	}
+
+	return r
+}
+
+// enqueue 提交一次刷新，已在队列中的余额不会重复提交；队列满时直接丢弃，
+// 条目会在下次访问时再次提交
+func (r *balanceRefresher) enqueue(job refreshJob) bool {
+	key := job.key()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pending[key] {
+		atomic.AddUint64(&r.deduplicated, 1)
+		return false
+	}
+
+	select {
+	case r.queue <- job:
+		r.pending[key] = true
+		atomic.AddUint64(&r.queued, 1)
+		return true
+	default:
+		atomic.AddUint64(&r.dropped, 1)
+		return false
+	}
+}
+
+func (r *balanceRefresher) worker() {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case job := <-r.queue:
+			ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+			err := r.refresh(ctx, job)
+			cancel()
+
+			r.mu.Lock()
+			delete(r.pending, job.key())
+			r.mu.Unlock()
+
+			if err != nil {
+				atomic.AddUint64(&r.failed, 1)
+			} else {
+				atomic.AddUint64(&r.refreshed, 1)
+			}
+		}
+	}
+}
+
+// touch 记录用户访问了余额，以及当时的钱包列表
+func (r *balanceRefresher) touch(userID uint, wallets []model.Wallet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.active[userID] = &activeUser{lastSeen: r.now(), wallets: wallets}
+}
+
+// activeWallets 返回活跃用户的钱包，并清理超出时间窗口的用户
+func (r *balanceRefresher) activeWallets() []model.Wallet {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var wallets []model.Wallet
+	cutoff := r.now().Add(-r.activeWindow)
+	for userID, user := range r.active {
+		if user.lastSeen.Before(cutoff) {
+			delete(r.active, userID)
+			continue
+		}
+		wallets = append(wallets, user.wallets...)
+	}
+
+	return wallets
+}
+
+// schedule 每隔 interval 对活跃用户的钱包调用一次 fn
+func (r *balanceRefresher) schedule(interval time.Duration, fn func(wallets []model.Wallet)) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-ticker.C:
+				if wallets := r.activeWallets(); len(wallets) > 0 {
+					fn(wallets)
+				}
+			}
+		}
+	}()
+}
+
+func (r *balanceRefresher) stats() RefreshStats {
+	r.mu.Lock()
+	activeUsers := len(r.active)
+	r.mu.Unlock()
+
+	return RefreshStats{
+		Workers:       r.workers,
+		QueueDepth:    len(r.queue),
+		QueueCapacity: cap(r.queue),
+		ActiveUsers:   activeUsers,
+		Queued:        atomic.LoadUint64(&r.queued),
+		Deduplicated:  atomic.LoadUint64(&r.deduplicated),
+		Dropped:       atomic.LoadUint64(&r.dropped),
+		Refreshed:     atomic.LoadUint64(&r.refreshed),
+		Failed:        atomic.LoadUint64(&r.failed),
+	}
+}
+
+func (r *balanceRefresher) close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+	})
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceRefresher_DeduplicatesPendingJobs(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var refreshed []refreshJob
+
+	refresher := newBalanceRefresher(func(ctx context.Context, job refreshJob) error {
+		<-release
+		mu.Lock()
+		refreshed = append(refreshed, job)
+		mu.Unlock()
+		return nil
+	}, 1, 10, time.Second, time.Hour)
+	defer refresher.close()
+
+	job := refreshJob{chainID: 1, req: balanceRequest{WalletAddress: "0xA", TokenAddress: "0xT"}}
+	assert.True(t, refresher.enqueue(job))
+	// 同一余额在刷新完成前不会重复提交，地址大小写不影响
+	assert.False(t, refresher.enqueue(refreshJob{chainID: 1, req: balanceRequest{WalletAddress: "0xa", TokenAddress: "0xt"}}))
+	// 其它链上的同一地址是不同的余额
+	assert.True(t, refresher.enqueue(refreshJob{chainID: 56, req: job.req}))
+
+	close(release)
+	assert.Eventually(t, func() bool { return refresher.stats().Refreshed == 2 }, time.Second, 5*time.Millisecond)
+
+	stats := refresher.stats()
+	assert.Equal(t, uint64(2), stats.Queued)
+	assert.Equal(t, uint64(1), stats.Deduplicated)
+	assert.Zero(t, stats.QueueDepth)
+
+	// 刷新完成后可以再次提交
+	assert.True(t, refresher.enqueue(job))
+}
+
+func TestBalanceRefresher_DropsWhenQueueIsFull(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+
+	refresher := newBalanceRefresher(func(ctx context.Context, job refreshJob) error {
+		started <- struct{}{}
+		<-release
+		return errors.New("rpc down")
+	}, 1, 1, time.Second, time.Hour)
+	defer refresher.close()
+
+	require.True(t, refresher.enqueue(refreshJob{chainID: 1, req: balanceRequest{WalletAddress: "0xA"}}))
+	<-started
+	// worker 正忙，队列只能再容纳一个
+	assert.True(t, refresher.enqueue(refreshJob{chainID: 1, req: balanceRequest{WalletAddress: "0xB"}}))
+	assert.False(t, refresher.enqueue(refreshJob{chainID: 1, req: balanceRequest{WalletAddress: "0xC"}}))
+
+	stats := refresher.stats()
+	assert.Equal(t, 1, stats.QueueDepth)
+	assert.Equal(t, 1, stats.QueueCapacity)
+	assert.Equal(t, uint64(1), stats.Dropped)
+
+	close(release)
+	assert.Eventually(t, func() bool { return refresher.stats().Failed == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestBalanceRefresher_ActiveWallets(t *testing.T) {
+	refresher := newBalanceRefresher(func(ctx context.Context, job refreshJob) error { return nil }, 1, 10, time.Second, time.Hour)
+	defer refresher.close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	refresher.now = func() time.Time { return now }
+
+	refresher.touch(1, []model.Wallet{{Address: "0xA", ChainID: 1}})
+	now = now.Add(30 * time.Minute)
+	refresher.touch(2, []model.Wallet{{Address: "0xB", ChainID: 1}})
+
+	assert.Len(t, refresher.activeWallets(), 2)
+
+	// 用户 1 超出活跃窗口后不再预刷新
+	now = now.Add(31 * time.Minute)
+	wallets := refresher.activeWallets()
+	require.Len(t, wallets, 1)
+	assert.Equal(t, "0xB", wallets[0].Address)
+	assert.Equal(t, 1, refresher.stats().ActiveUsers)
+}
+
+func TestBalanceRefresher_Schedule(t *testing.T) {
+	refresher := newBalanceRefresher(func(ctx context.Context, job refreshJob) error { return nil }, 1, 10, time.Second, time.Hour)
+	defer refresher.close()
+
+	refresher.touch(1, []model.Wallet{{Address: "0xA", ChainID: 1}})
+
+	scheduled := make(chan []model.Wallet, 10)
+	refresher.schedule(5*time.Millisecond, func(wallets []model.Wallet) { scheduled <- wallets })
+
+	select {
+	case wallets := <-scheduled:
+		assert.Equal(t, "0xA", wallets[0].Address)
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not run")
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"wallet-tracker/internal/model"
+	"wallet-tracker/pkg/decimal"
+	"wallet-tracker/pkg/price"
+)
+
+// PortfolioValue 是一组余额的美元总值，Unpriced 是有余额但没有价格、没有计入总值的条目数
+type PortfolioValue struct {
+	USDValue string `json:"usd_value"`
+	Unpriced int    `json:"unpriced"`
+}
+
+// SetPriceProvider 设置计算余额美元价值的价格来源。链上价格源通过 BlockchainService 读取，
+// 所以价格来源只能在 BlockchainService 创建之后设置；未设置时不计算价值
+func (bs *BlockchainService) SetPriceProvider(prices price.PriceProvider) {
+	bs.prices = prices
+}
+
+// ReadFeed 读取链上价格源在 at 时的价格，at 为零值时读取最新价格
+func (bs *BlockchainService) ReadFeed(ctx context.Context, chainID int, feed string, at time.Time) (decimal.Decimal, error) {
+	client, err := bs.client(chainID)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	var blockNumber uint64
+	if !at.IsZero() {
+		if blockNumber, err = client.BlockAtTime(ctx, at); err != nil {
+			return decimal.Decimal{}, err
+		}
+	}
+
+	result, err := client.ReadPriceFeed(ctx, feed, blockNumber)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return decimal.New(result.Answer, int(result.Decimals)), nil
+}
+
+// ValueHistoricalBalances 按每条链读取余额的区块时间取价格并填充 USDValue。
+// at 为空时余额来自最新区块，使用当前价格
+func (bs *BlockchainService) ValueHistoricalBalances(ctx context.Context, balances []model.TokenBalance, blocks []model.ChainBlock, at BalanceAt) {
+	if at.Time == nil && len(at.Blocks) == 0 {
+		bs.valueBalances(ctx, balances, nil)
+		return
+	}
+
+	times := make(map[int]time.Time, len(blocks))
+	for _, block := range blocks {
+		if block.Error == "" {
+			times[block.ChainID] = block.Timestamp
+		}
+	}
+	bs.valueBalances(ctx, balances, times)
+}
+
+// valueBalances 填充余额的 USDValue，times 中没有的链使用当前价格。
+// 取价格失败只记录日志，余额照常返回
+func (bs *BlockchainService) valueBalances(ctx context.Context, balances []model.TokenBalance, times map[int]time.Time) {
+	if bs.prices == nil {
+		return
+	}
+
+	// 同一时间的资产一次查询
+	type valued struct {
+		index   int
+		asset   price.Asset
+		balance decimal.Decimal
+	}
+	groups := make(map[time.Time][]valued)
+	for i, balance := range balances {
+		if balance.RawBalance == "" {
+			continue
+		}
+		amount, err := decimal.Parse(balance.Balance)
+		if err != nil {
+			continue
+		}
+		at := times[balance.ChainID]
+		groups[at] = append(groups[at], valued{i, price.NewAsset(balance.ChainID, balance.TokenAddress), amount})
+	}
+
+	for at, group := range groups {
+		assets := make([]price.Asset, 0, len(group))
+		seen := make(map[price.Asset]bool)
+		for _, v := range group {
+			if !seen[v.asset] {
+				seen[v.asset] = true
+				assets = append(assets, v.asset)
+			}
+		}
+
+		prices, err := bs.prices.Prices(ctx, price.DefaultCurrency, at, assets)
+		if err != nil {
+			log.Printf("failed to get prices for %d assets: %v", len(assets), err)
+			continue
+		}
+		for _, v := range group {
+			if unitPrice, ok := prices[v.asset]; ok {
+				value := v.balance.Mul(unitPrice)
+				balances[v.index].USDValue = value.Round(valuePlaces).String()
+				balances[v.index].USDValueExact = &value
+			}
+		}
+	}
+}
+
+// TotalValue 汇总余额的美元价值，使用未舍入的值相加，只对总值舍入。
+// 余额为 0 或没有拿到余额的条目不计入 Unpriced
+func TotalValue(balances []model.TokenBalance) PortfolioValue {
+	var total decimal.Decimal
+	unpriced := 0
+	for _, balance := range balances {
+		if balance.USDValueExact != nil {
+			total = total.Add(*balance.USDValueExact)
+			continue
+		}
+		if balance.USDValue != "" {
+			if value, err := decimal.Parse(balance.USDValue); err == nil {
+				total = total.Add(value)
+				continue
+			}
+		}
+
+		if balance.RawBalance == "" {
+			continue
+		}
+		if amount, err := decimal.Parse(balance.Balance); err == nil && !amount.IsZero() {
+			unpriced++
+		}
+	}
+	return PortfolioValue{USDValue: total.Round(valuePlaces).String(), Unpriced: unpriced}
+}
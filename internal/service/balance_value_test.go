@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/model"
+	"wallet-tracker/pkg/decimal"
+	"wallet-tracker/pkg/price"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueBalances(t *testing.T) {
+	eth := decimal.MustParse("2000")
+	usdc := decimal.MustParse("1")
+	prices, err := price.NewStaticProvider(&price.StaticFile{
+		Prices: []price.StaticPrice{
+			{ChainID: 1, USD: &eth, History: []price.StaticPoint{{Time: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), USD: decimal.MustParse("1000")}}},
+			{ChainID: 1, Address: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", USD: &usdc},
+		},
+	})
+	require.NoError(t, err)
+
+	bs := &BlockchainService{}
+	bs.SetPriceProvider(prices)
+
+	newBalances := func() []model.TokenBalance {
+		return []model.TokenBalance{
+			{AssetType: model.AssetTypeNative, RawBalance: "1500000000000000000", Balance: "1.5", ChainID: 1},
+			{AssetType: model.AssetTypeERC20, TokenAddress: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", RawBalance: "100256", Balance: "0.100256", ChainID: 1},
+			// 没有价格
+			{AssetType: model.AssetTypeNative, RawBalance: "2000000000000000000", Balance: "2", ChainID: 56},
+			// 没有拿到余额
+			{AssetType: model.AssetTypeNative, ChainID: 1, Status: model.BalanceStatusRPCError},
+		}
+	}
+
+	balances := newBalances()
+	bs.valueBalances(context.Background(), balances, nil)
+	assert.Equal(t, "3000", balances[0].USDValue)
+	assert.Equal(t, "0.1", balances[1].USDValue)
+	assert.Empty(t, balances[2].USDValue)
+	assert.Empty(t, balances[3].USDValue)
+	assert.Equal(t, PortfolioValue{USDValue: "3000.1", Unpriced: 1}, TotalValue(balances))
+
+	// 历史余额按区块时间取价格
+	balances = newBalances()
+	blocks := []model.ChainBlock{{ChainID: 1, Timestamp: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)}}
+	at := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	bs.ValueHistoricalBalances(context.Background(), balances, blocks, BalanceAt{Time: &at})
+	assert.Equal(t, "1500", balances[0].USDValue)
+}
+
+func TestTotalValue_RoundsOnce(t *testing.T) {
+	usdc := decimal.MustParse("1")
+	prices, err := price.NewStaticProvider(&price.StaticFile{
+		Prices: []price.StaticPrice{{ChainID: 1, Address: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", USD: &usdc}},
+	})
+	require.NoError(t, err)
+
+	bs := &BlockchainService{}
+	bs.SetPriceProvider(prices)
+
+	// 每条 0.004 舍入后都是 0，合计 0.012 舍入后是 0.01
+	var balances []model.TokenBalance
+	for _, wallet := range []string{"0xA", "0xB", "0xC"} {
+		balances = append(balances, model.TokenBalance{
+			WalletAddress: wallet, AssetType: model.AssetTypeERC20, TokenAddress: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48",
+			RawBalance: "4000", Balance: "0.004", ChainID: 1,
+		})
+	}
+	bs.valueBalances(context.Background(), balances, nil)
+
+	for _, balance := range balances {
+		assert.Equal(t, "0", balance.USDValue)
+	}
+	assert.Equal(t, PortfolioValue{USDValue: "0.01", Unpriced: 0}, TotalValue(balances))
+}
@@ -1,130 +1,861 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"wallet-tracker/internal/config"
 	"wallet-tracker/internal/model"
 	"wallet-tracker/pkg/blockchain"
 	"wallet-tracker/pkg/cache"
+	"wallet-tracker/pkg/decimal"
+	"wallet-tracker/pkg/price"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	errUnsupportedChain = errors.New("unsupported chain")
+
+	ErrInvalidTokenAddress = errors.New("invalid token address")
+	ErrNotContract         = errors.New("address is not a contract on this chain")
+	ErrNonStandardToken    = errors.New("contract does not implement the ERC-20 interface")
+	ErrNFTCollection       = errors.New("contract is an NFT collection, add it with the NFT endpoint")
+	ErrNotNFTCollection    = errors.New("contract does not implement the ERC-721 or ERC-1155 interface")
 )
 
 type BlockchainService struct {
 	clients map[int]*blockchain.BlockchainClient
-	cache   *cache.RedisClient
+	loaders map[int]*chainLoader
+	chains  map[int]config.ChainConfig
+	// 保持配置中的顺序
+	chainIDs []int
+	// 启动时被拒绝的链（例如 RPC 返回了错误的 chain ID）
+	chainErrors map[int]error
+	cache       cache.Cache
+	// 一次余额请求的总耗时预算
+	requestTimeout time.Duration
+	// 缓存超过 softTTL 后返回旧值并在后台刷新，超过 hardTTL 后由缓存淘汰
+	softTTL   time.Duration
+	hardTTL   time.Duration
+	refresher *balanceRefresher
+	// 计算余额美元价值的价格来源，见 SetPriceProvider
+	prices price.PriceProvider
+}
+
+func NewBlockchainService(cfg *config.BlockchainConfig, cacheCfg *config.CacheConfig, cache cache.Cache) (*BlockchainService, error) {
+	bs := &BlockchainService{
+		clients:        make(map[int]*blockchain.BlockchainClient),
+		loaders:        make(map[int]*chainLoader),
+		chains:         make(map[int]config.ChainConfig),
+		chainErrors:    make(map[int]error),
+		cache:          cache,
+		requestTimeout: 10 * time.Second,
+		softTTL:        5 * time.Minute,
+		hardTTL:        24 * time.Hour,
+	}
+
+	if d, err := time.ParseDuration(cfg.RequestTimeout); err == nil && d > 0 {
+		bs.requestTimeout = d
+	}
+	if d, err := time.ParseDuration(cacheCfg.SoftTTL); err == nil && d > 0 {
+		bs.softTTL = d
+	}
+	if d, err := time.ParseDuration(cacheCfg.TokenBalanceTTL); err == nil && d > 0 {
+		bs.hardTTL = d
+	}
+
+	batchWindow := 2 * time.Millisecond
+	if d, err := time.ParseDuration(cfg.BatchWindow); err == nil && d >= 0 {
+		batchWindow = d
+	}
+
+	for i := range cfg.Chains {
+		chain := cfg.Chains[i]
+		bs.chains[chain.ChainID] = chain
+		bs.chainIDs = append(bs.chainIDs, chain.ChainID)
+
+		// 单条链不可用不影响其它链
+		client, err := blockchain.NewBlockchainClient(&chain, &cfg.RPCPool)
+		if err != nil {
+			log.Printf("chain %d (%s) disabled: %v", chain.ChainID, chain.Name, err)
+			bs.chainErrors[chain.ChainID] = err
+			continue
+		}
+
+		bs.clients[chain.ChainID] = client
+
+		chainID := chain.ChainID
+		fetch := func(ctx context.Context, requests []balanceRequest) []balanceOutcome {
+			return bs.fetchChainBatch(ctx, chainID, requests)
+		}
+		bs.loaders[chainID] = newChainLoader(chainID, fetch, batchWindow, chain.BatchSize, chain.MaxConcurrency, bs.requestTimeout)
+	}
+
+	bs.startRefresher(&cacheCfg.Refresh)
+
+	return bs, nil
+}
+
+func (bs *BlockchainService) startRefresher(cfg *config.RefreshConfig) {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = 4
+	}
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = 1000
+	}
+	interval := time.Minute
+	if d, err := time.ParseDuration(cfg.Interval); err == nil && d > 0 {
+		interval = d
+	}
+	activeWindow := time.Hour
+	if d, err := time.ParseDuration(cfg.ActiveWindow); err == nil && d > 0 {
+		activeWindow = d
+	}
+
+	bs.refresher = newBalanceRefresher(bs.refreshBalance, workers, queueSize, bs.requestTimeout, activeWindow)
+	bs.refresher.schedule(interval, bs.refreshActiveWallets)
+}
+
+// Close 停止后台刷新并关闭所有 RPC 连接
+func (bs *BlockchainService) Close() {
+	bs.refresher.close()
+	for _, client := range bs.clients {
+		client.Close()
+	}
+}
+
+// IsSupportedChain 判断链是否在配置中
+func (bs *BlockchainService) IsSupportedChain(chainID int) bool {
+	_, exists := bs.chains[chainID]
+	return exists
+}
+
+// SupportedChains 按配置顺序返回所有支持的链
+func (bs *BlockchainService) SupportedChains() []model.Chain {
+	chains := make([]model.Chain, 0, len(bs.chainIDs))
+	for _, chainID := range bs.chainIDs {
+		chain := bs.chains[chainID]
+		available, err := bs.chainStatus(chainID)
+
+		info := model.Chain{
+			ChainID:        chain.ChainID,
+			Name:           chain.Name,
+			NativeSymbol:   chain.NativeSymbol,
+			NativeDecimals: chain.NativeDecimals,
+			ExplorerURL:    chain.ExplorerURL,
+			Available:      available,
+		}
+		if !available && err != nil {
+			info.Error = chainErrorMessage(err)
+		}
+
+		chains = append(chains, info)
+	}
+	return chains
+}
+
+// chainErrorMessage 只返回链不可用的类别。/chains 不需要登录，
+// 而原始错误中可能带有 RPC URL，路径里常常就是 API key
+func chainErrorMessage(err error) string {
+	if errors.Is(err, blockchain.ErrChainIDMismatch) {
+		return blockchain.ErrChainIDMismatch.Error()
+	}
+	return blockchain.ErrChainUnavailable.Error()
+}
+
+// UnavailableChains 返回给定链中当前不可用的部分
+func (bs *BlockchainService) UnavailableChains(chainIDs []int) []model.Chain {
+	var unavailable []model.Chain
+
+	requested := make(map[int]bool)
+	for _, chainID := range chainIDs {
+		requested[chainID] = true
+	}
+
+	for _, chain := range bs.SupportedChains() {
+		if requested[chain.ChainID] && !chain.Available {
+			unavailable = append(unavailable, chain)
+		}
+	}
+
+	return unavailable
+}
+
+// EndpointStatus 返回某条链各 RPC 端点的健康状况
+func (bs *BlockchainService) EndpointStatus(chainID int) ([]blockchain.EndpointStatus, error) {
+	if !bs.IsSupportedChain(chainID) {
+		return nil, fmt.Errorf("unsupported chain ID: %d", chainID)
+	}
+
+	client, exists := bs.clients[chainID]
+	if !exists {
+		return nil, fmt.Errorf("%w: chain %d: %v", blockchain.ErrChainUnavailable, chainID, bs.chainErrors[chainID])
+	}
+
+	return client.EndpointStatus(), nil
+}
+
+func (bs *BlockchainService) chainStatus(chainID int) (bool, error) {
+	if err, exists := bs.chainErrors[chainID]; exists {
+		return false, err
+	}
+
+	client, exists := bs.clients[chainID]
+	if !exists {
+		return false, nil
+	}
+
+	return client.Status()
+}
+
+func (bs *BlockchainService) GetChainName(chainID int) string {
+	if chain, exists := bs.chains[chainID]; exists {
+		return chain.Name
+	}
+	return "Unknown"
+}
+
+// TokenMetadata 是从链上读取的 token 元数据，Warnings 列出使用了默认值的字段
+type TokenMetadata struct {
+	Address  string   `json:"address"`
+	Symbol   string   `json:"symbol"`
+	Name     string   `json:"name"`
+	Decimals int      `json:"decimals"`
+	Warnings []string `json:"warnings,omitempty"`
 }
 
-func NewBlockchainService(cfg *config.BlockchainConfig, cache *cache.RedisClient) (*BlockchainService, error) {
-	clients := make(map[int]*blockchain.BlockchainClient)
+// TokenMismatch 描述调用方提供的元数据与链上不一致的字段
+type TokenMismatch struct {
+	Field    string `json:"field"`
+	Supplied string `json:"supplied"`
+	OnChain  string `json:"on_chain"`
+}
+
+// LookupToken 校验地址是该链上的 ERC-20 合约，并读取 symbol / name / decimals。
+// bytes32 格式的 symbol / name 可以正常解析，缺失的元数据使用默认值并记录在 Warnings 中
+func (bs *BlockchainService) LookupToken(ctx context.Context, chainID int, tokenAddress string) (*TokenMetadata, error) {
+	if !common.IsHexAddress(tokenAddress) {
+		return nil, ErrInvalidTokenAddress
+	}
 
-	// 以太坊主网 (Chain ID: 1)
-	ethClient, err := blockchain.NewBlockchainClient(cfg.Ethereum.RPCURL)
+	client, err := bs.client(chainID)
 	if err != nil {
 		return nil, err
 	}
-	clients[1] = ethClient
 
-	// BSC 主网 (Chain ID: 56)
-	bscClient, err := blockchain.NewBlockchainClient(cfg.BSC.RPCURL)
+	address := common.HexToAddress(tokenAddress).Hex()
+
+	isContract, err := client.IsContract(ctx, address)
 	if err != nil {
 		return nil, err
 	}
-	clients[56] = bscClient
+	if !isContract {
+		return nil, ErrNotContract
+	}
 
-	// Polygon 主网 (Chain ID: 137)
-	polygonClient, err := blockchain.NewBlockchainClient(cfg.Polygon.RPCURL)
+	// ERC-721 也有 balanceOf，需要先排除；ERC-1155 一并排除
+	standard, err := client.NFTStandard(ctx, address)
 	if err != nil {
 		return nil, err
 	}
-	clients[137] = polygonClient
+	if standard != "" {
+		return nil, ErrNFTCollection
+	}
+
+	// 元数据方法是可选的，缺失时使用默认值；balanceOf 必须可以调用
+	info, err := client.GetTokenInfo(ctx, address)
+	if err != nil {
+		return nil, tokenCallError(err)
+	}
+
+	if _, err := client.GetTokenBalance(ctx, address, common.Address{}.Hex()); err != nil {
+		return nil, tokenCallError(err)
+	}
 
-	return &BlockchainService{
-		clients: clients,
-		cache:   cache,
+	return &TokenMetadata{
+		Address:  address,
+		Symbol:   info.Symbol,
+		Name:     info.Name,
+		Decimals: int(info.Decimals),
+		Warnings: info.Warnings,
 	}, nil
 }
 
-func (bs *BlockchainService) GetTokenBalance(chainID int, tokenAddress, walletAddress string, forceRefresh bool) (*model.TokenBalance, error) {
-	// 如果不强制刷新，先尝试从缓存获取
-	if !forceRefresh {
-		if cached, err := bs.cache.GetTokenBalance(walletAddress, tokenAddress); err == nil {
-			return cached, nil
-		}
+// LookupCollection 校验地址是该链上的 ERC-721 或 ERC-1155 合约，并读取合集的名称和支持的扩展
+func (bs *BlockchainService) LookupCollection(ctx context.Context, chainID int, contractAddress string) (*blockchain.NFTCollectionInfo, error) {
+	if !common.IsHexAddress(contractAddress) {
+		return nil, ErrInvalidTokenAddress
+	}
+
+	client, err := bs.client(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	isContract, err := client.IsContract(ctx, contractAddress)
+	if err != nil {
+		return nil, err
+	}
+	if !isContract {
+		return nil, ErrNotContract
+	}
+
+	info, err := client.GetNFTCollectionInfo(ctx, contractAddress)
+	if errors.Is(err, blockchain.ErrNotNFT) {
+		return nil, ErrNotNFTCollection
 	}
+	return info, err
+}
 
+// client 返回链的 RPC 客户端；启动时被拒绝的链返回 ErrChainUnavailable
+func (bs *BlockchainService) client(chainID int) (*blockchain.BlockchainClient, error) {
 	client, exists := bs.clients[chainID]
 	if !exists {
-		return nil, fmt.Errorf("unsupported chain ID: %d", chainID)
+		if chainErr, disabled := bs.chainErrors[chainID]; disabled {
+			return nil, fmt.Errorf("%w: chain %d: %v", blockchain.ErrChainUnavailable, chainID, chainErr)
+		}
+		return nil, fmt.Errorf("%w: %d", errUnsupportedChain, chainID)
 	}
+	return client, nil
+}
 
-	// 获取余额
-	balance, err := client.GetTokenBalance(tokenAddress, walletAddress)
+// LatestBlock 返回链的最新区块高度
+func (bs *BlockchainService) LatestBlock(ctx context.Context, chainID int) (uint64, error) {
+	client, err := bs.client(chainID)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
+	return client.BlockNumber(ctx)
+}
+
+// DiscoveryStartBlock 返回发现 token 时最早扫描的区块
+func (bs *BlockchainService) DiscoveryStartBlock(chainID int) uint64 {
+	return bs.chains[chainID].DiscoveryStartBlock
+}
 
-	// 获取 token 信息
-	symbol, name, decimals, err := client.GetTokenInfo(tokenAddress)
+// ScanTransfers 扫描钱包在 [fromBlock, toBlock] 内的 ERC-20 Transfer 日志，
+// 每次查询的区块数不超过链配置的 log_range
+func (bs *BlockchainService) ScanTransfers(ctx context.Context, chainID int, walletAddress string, fromBlock, toBlock uint64) (*blockchain.TransferScan, error) {
+	client, err := bs.client(chainID)
 	if err != nil {
-		return nil, err
+		return &blockchain.TransferScan{Next: fromBlock}, err
 	}
+	return client.ScanTransfers(ctx, walletAddress, fromBlock, toBlock, bs.chains[chainID].LogRange)
+}
 
-	// 计算实际余额（考虑小数位）
-	divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
-	balanceFloat := new(big.Float).SetInt(balance)
-	divisorFloat := new(big.Float).SetInt(divisor)
-	actualBalance := new(big.Float).Quo(balanceFloat, divisorFloat)
+// tokenCallError 区分链不可用和合约本身不符合 ERC-20
+func tokenCallError(err error) error {
+	if errors.Is(err, blockchain.ErrChainUnavailable) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrNonStandardToken, err)
+}
 
-	chainName := getChainName(chainID)
+// TokenMismatches 返回调用方提供且与 token 元数据不一致的字段，未提供的字段不比较
+func TokenMismatches(m *model.Token, symbol, name string, decimals *int) []TokenMismatch {
+	mismatches := []TokenMismatch{}
 
-	tokenBalance := &model.TokenBalance{
-		WalletAddress: walletAddress,
-		TokenAddress:  tokenAddress,
-		Balance:       actualBalance.String(),
-		Symbol:        symbol,
-		Name:          name,
-		Decimals:      int(decimals),
-		ChainID:       chainID,
-		ChainName:     chainName,
+	if symbol != "" && !strings.EqualFold(strings.TrimSpace(symbol), m.Symbol) {
+		mismatches = append(mismatches, TokenMismatch{Field: "symbol", Supplied: symbol, OnChain: m.Symbol})
+	}
+	if name != "" && !strings.EqualFold(strings.TrimSpace(name), m.Name) {
+		mismatches = append(mismatches, TokenMismatch{Field: "name", Supplied: name, OnChain: m.Name})
 	}
+	if decimals != nil && *decimals != m.Decimals {
+		mismatches = append(mismatches, TokenMismatch{Field: "decimals", Supplied: strconv.Itoa(*decimals), OnChain: strconv.Itoa(m.Decimals)})
+	}
+
+	return mismatches
+}
 
-	// 缓存结果
-	bs.cache.SetTokenBalance(walletAddress, tokenAddress, tokenBalance)
+// balanceRequest 描述一次余额查询，TokenAddress 为空表示原生币。
+// Token 是 token 表中的元数据，为空时从链上读取
+type balanceRequest struct {
+	WalletAddress string
+	TokenAddress  string
+	Token         *model.Token
+}
 
-	return tokenBalance, nil
+type balanceOutcome struct {
+	balance *model.TokenBalance
+	err     error
 }
 
-func (bs *BlockchainService) GetMultipleTokenBalances(wallets []model.Wallet, forceRefresh bool) ([]model.TokenBalance, error) {
-	var results []model.TokenBalance
+func (bs *BlockchainService) GetTokenBalance(ctx context.Context, chainID int, tokenAddress, walletAddress string, forceRefresh bool) (*model.TokenBalance, error) {
+	outcomes := bs.getChainBalances(ctx, chainID, []balanceRequest{{WalletAddress: walletAddress, TokenAddress: tokenAddress}}, forceRefresh)
+	return outcomes[0].balance, outcomes[0].err
+}
+
+func (bs *BlockchainService) GetNativeBalance(ctx context.Context, chainID int, walletAddress string, forceRefresh bool) (*model.TokenBalance, error) {
+	outcomes := bs.getChainBalances(ctx, chainID, []balanceRequest{{WalletAddress: walletAddress}}, forceRefresh)
+	return outcomes[0].balance, outcomes[0].err
+}
+
+// GetMultipleTokenBalances 在请求预算内查询所有钱包的余额。每个钱包和 token 都有一个条目，
+// 查询失败或预算耗尽的条目通过 Status 和 Error 说明原因
+func (bs *BlockchainService) GetMultipleTokenBalances(ctx context.Context, wallets []model.Wallet, forceRefresh bool) ([]model.TokenBalance, error) {
+	ctx, cancel := context.WithTimeout(ctx, bs.requestTimeout)
+	defer cancel()
+
+	plan := planBalances(wallets)
+
+	// 各条链并行查询
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	outcomes := make(map[int][]balanceOutcome)
+	for chainID, reqs := range plan.requests {
+		wg.Add(1)
+		go func(chainID int, reqs []balanceRequest) {
+			defer wg.Done()
+
+			chainOutcomes := bs.getChainBalances(ctx, chainID, reqs, forceRefresh)
+			mu.Lock()
+			outcomes[chainID] = chainOutcomes
+			mu.Unlock()
+		}(chainID, reqs)
+	}
+	wg.Wait()
+
+	// 客户端已断开，没有必要继续组装结果
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return nil, ctx.Err()
+	}
+
+	balances := bs.collectBalances(plan, outcomes)
+	bs.valueBalances(ctx, balances, nil)
+	return balances, nil
+}
+
+// balanceSlot 是结果中的一个条目在按链分组的查询中的位置。
+// hideZero 的条目来自订阅的 Token List，余额为 0 或查询失败时不输出
+type balanceSlot struct {
+	chainID  int
+	index    int
+	hideZero bool
+}
+
+// balancePlan 把钱包的余额查询按链分组，同一条链上的所有查询合并为一次 Multicall
+type balancePlan struct {
+	slots    []balanceSlot
+	requests map[int][]balanceRequest
+}
+
+func planBalances(wallets []model.Wallet) *balancePlan {
+	plan := &balancePlan{requests: make(map[int][]balanceRequest)}
+	add := func(chainID int, req balanceRequest, hideZero bool) {
+		plan.slots = append(plan.slots, balanceSlot{chainID, len(plan.requests[chainID]), hideZero})
+		plan.requests[chainID] = append(plan.requests[chainID], req)
+	}
 
 	for _, wallet := range wallets {
+		// 每个钱包都包含原生币余额
+		add(wallet.ChainID, balanceRequest{WalletAddress: wallet.Address}, false)
+
 		for _, token := range wallet.Tokens {
-			if !token.IsActive {
+			if !token.IsActive || !isFungible(token) {
 				continue
 			}
+			add(wallet.ChainID, balanceRequest{
+				WalletAddress: wallet.Address,
+				TokenAddress:  token.TokenAddress,
+				Token:         token.Token,
+			}, false)
+		}
 
-			balance, err := bs.GetTokenBalance(wallet.ChainID, token.TokenAddress, wallet.Address, forceRefresh)
-			if err != nil {
-				continue // 跳过错误的 token，继续处理其他的
-			}
+		for i := range wallet.ListTokens {
+			token := &wallet.ListTokens[i]
+			add(wallet.ChainID, balanceRequest{
+				WalletAddress: wallet.Address,
+				TokenAddress:  token.Address,
+				Token:         token,
+			}, true)
+		}
+	}
 
-			results = append(results, *balance)
+	return plan
+}
+
+// collectBalances 按钱包和 token 的原始顺序输出各条链的查询结果
+func (bs *BlockchainService) collectBalances(plan *balancePlan, outcomes map[int][]balanceOutcome) []model.TokenBalance {
+	var results []model.TokenBalance
+	for _, s := range plan.slots {
+		outcome := outcomes[s.chainID][s.index]
+		if s.hideZero && (outcome.err != nil || isZeroBalance(outcome.balance)) {
+			continue
 		}
+		if outcome.err != nil {
+			results = append(results, bs.failedBalance(s.chainID, plan.requests[s.chainID][s.index], outcome.err))
+			continue
+		}
+		results = append(results, *outcome.balance)
 	}
+	return results
+}
 
-	return results, nil
+// isFungible 判断钱包跟踪的 token 是否是 ERC-20，NFT 合集不出现在余额中
+func isFungible(token model.WalletToken) bool {
+	return token.Token == nil || token.Token.Standard == "" || token.Token.Standard == model.TokenStandardERC20
 }
 
-func getChainName(chainID int) string {
-	switch chainID {
-	case 1:
-		return "Ethereum"
-	case 56:
-		return "BSC"
-	case 137:
-		return "Polygon"
+// failedBalance 为没有拿到余额的查询生成占位条目
+func (bs *BlockchainService) failedBalance(chainID int, req balanceRequest, err error) model.TokenBalance {
+	balance := model.TokenBalance{
+		WalletAddress: req.WalletAddress,
+		AssetType:     model.AssetTypeERC20,
+		TokenAddress:  req.TokenAddress,
+		ChainID:       chainID,
+		ChainName:     bs.GetChainName(chainID),
+		Status:        balanceStatus(err),
+		Error:         err.Error(),
+	}
+
+	if req.TokenAddress == "" {
+		chain := bs.chains[chainID]
+		balance.AssetType = model.AssetTypeNative
+		balance.Symbol = chain.NativeSymbol
+		balance.Name = chain.NativeName
+		balance.Decimals = chain.NativeDecimals
+	}
+
+	return balance
+}
+
+// isZeroBalance 判断余额是否为 0，无法解析的余额按 0 处理
+func isZeroBalance(balance *model.TokenBalance) bool {
+	raw := balance.RawBalance
+	if raw == "" {
+		raw = balance.Balance
+	}
+	amount, err := decimal.Parse(raw)
+	return err != nil || amount.IsZero()
+}
+
+// balanceStatus 把查询错误归类为响应中的状态
+func balanceStatus(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return model.BalanceStatusTimeout
+	case errors.Is(err, errUnsupportedChain):
+		return model.BalanceStatusUnsupportedChain
+	case errors.Is(err, ErrNonStandardToken):
+		return model.BalanceStatusNonStandardToken
+	case errors.Is(err, blockchain.ErrArchiveRequired):
+		return model.BalanceStatusArchiveRequired
+	case errors.Is(err, blockchain.ErrBlockNotFound), errors.Is(err, blockchain.ErrBeforeGenesis):
+		return model.BalanceStatusInvalidBlock
 	default:
-		return "Unknown"
+		return model.BalanceStatusRPCError
+	}
+}
+
+// getChainBalances 查询同一条链上的一组余额：先查缓存，未命中的部分交给该链的 loader 合并读取
+func (bs *BlockchainService) getChainBalances(ctx context.Context, chainID int, requests []balanceRequest, forceRefresh bool) []balanceOutcome {
+	outcomes := make([]balanceOutcome, len(requests))
+
+	loader, exists := bs.loaders[chainID]
+	if !exists {
+		err := fmt.Errorf("%w: %d", errUnsupportedChain, chainID)
+		if chainErr, disabled := bs.chainErrors[chainID]; disabled {
+			err = fmt.Errorf("%w: chain %d: %v", blockchain.ErrChainUnavailable, chainID, chainErr)
+		}
+
+		for i := range outcomes {
+			outcomes[i].err = err
+		}
+		return outcomes
+	}
+
+	var wg sync.WaitGroup
+	for i, req := range requests {
+		wg.Add(1)
+		go func(i int, req balanceRequest) {
+			defer wg.Done()
+
+			if !forceRefresh {
+				if cached, err := bs.getCachedBalance(ctx, chainID, req); err == nil {
+					applyTokenMetadata(cached, req.Token)
+					cached.Status = model.BalanceStatusCached
+					cached.FromCache = true
+					if bs.isSoftExpired(cached) {
+						// 先返回旧值，由后台 worker 刷新
+						cached.Status = model.BalanceStatusStale
+						bs.refresher.enqueue(refreshJob{chainID: chainID, req: req})
+					}
+					outcomes[i].balance = cached
+					return
+				}
+			}
+
+			balance, err := loader.load(ctx, req)
+			if err != nil && balanceStatus(err) == model.BalanceStatusRPCError {
+				// RPC 失败时退回到旧的缓存值
+				if cached, cacheErr := bs.getCachedBalance(ctx, chainID, req); cacheErr == nil {
+					applyTokenMetadata(cached, req.Token)
+					cached.Status = model.BalanceStatusStale
+					cached.Error = err.Error()
+					cached.FromCache = true
+					balance, err = cached, nil
+				}
+			}
+			outcomes[i].balance, outcomes[i].err = balance, err
+		}(i, req)
+	}
+	wg.Wait()
+
+	return outcomes
+}
+
+// applyTokenMetadata 用 token 表中的元数据覆盖缓存条目，管理员修改元数据后立即生效
+func applyTokenMetadata(balance *model.TokenBalance, token *model.Token) {
+	if token == nil {
+		return
+	}
+
+	balance.Symbol = token.Symbol
+	balance.Name = token.Name
+	balance.Warnings = token.Warnings
+	balance.Spam = token.Spam
+	if balance.Decimals != token.Decimals {
+		balance.Decimals = token.Decimals
+		if raw, ok := new(big.Int).SetString(balance.RawBalance, 10); ok {
+			balance.Balance = decimal.New(raw, token.Decimals).String()
+		}
+	}
+}
+
+// fetchChainBatch 通过一个 Multicall 批次读取一组最新余额，并写入缓存
+func (bs *BlockchainService) fetchChainBatch(ctx context.Context, chainID int, requests []balanceRequest) []balanceOutcome {
+	outcomes := bs.readChainBalances(ctx, chainID, bs.clients[chainID].NewBatch(), requests)
+	for i, req := range requests {
+		if outcomes[i].err == nil {
+			bs.setCachedBalance(ctx, chainID, req, outcomes[i].balance)
+		}
+	}
+	return outcomes
+}
+
+// readChainBalances 把一组余额查询加入 batch 并执行，batch 决定读取的区块。
+// 只有 token 表中还没有元数据的 token 才会同时读取 symbol / name / decimals
+func (bs *BlockchainService) readChainBalances(ctx context.Context, chainID int, batch *blockchain.Batch, requests []balanceRequest) []balanceOutcome {
+	outcomes := make([]balanceOutcome, len(requests))
+
+	balances := make([]*blockchain.BalanceResult, len(requests))
+	infos := make(map[string]*blockchain.TokenInfoResult)
+
+	for i, req := range requests {
+		if req.TokenAddress == "" {
+			balances[i] = batch.NativeBalance(req.WalletAddress)
+			continue
+		}
+
+		balances[i] = batch.BalanceOf(req.TokenAddress, req.WalletAddress)
+		if req.Token != nil {
+			continue
+		}
+		if _, exists := infos[req.TokenAddress]; !exists {
+			infos[req.TokenAddress] = batch.TokenInfo(req.TokenAddress)
+		}
+	}
+
+	if err := batch.Execute(ctx); err != nil {
+		for i := range outcomes {
+			outcomes[i].err = err
+		}
+		return outcomes
+	}
+
+	fetchedAt := time.Now()
+	for i, req := range requests {
+		result := balances[i]
+		if result.Err != nil {
+			outcomes[i].err = result.Err
+			if req.TokenAddress != "" {
+				outcomes[i].err = fmt.Errorf("%w: %v", ErrNonStandardToken, result.Err)
+			}
+			continue
+		}
+
+		var balance *model.TokenBalance
+		if req.TokenAddress == "" {
+			chain := bs.chains[chainID]
+			balance = &model.TokenBalance{
+				WalletAddress: req.WalletAddress,
+				AssetType:     model.AssetTypeNative,
+				RawBalance:    result.Balance.String(),
+				Balance:       decimal.New(result.Balance, chain.NativeDecimals).String(),
+				Symbol:        chain.NativeSymbol,
+				Name:          chain.NativeName,
+				Decimals:      chain.NativeDecimals,
+				ChainID:       chainID,
+				ChainName:     chain.Name,
+				Status:        model.BalanceStatusOK,
+				FetchedAt:     &fetchedAt,
+			}
+		} else {
+			token := req.Token
+			if token == nil {
+				// 元数据不完整时使用默认值，余额照常返回
+				info := infos[req.TokenAddress]
+				if info.Err != nil {
+					outcomes[i].err = fmt.Errorf("%w: %v", ErrNonStandardToken, info.Err)
+					continue
+				}
+				token = &model.Token{Symbol: info.Symbol, Name: info.Name, Decimals: int(info.Decimals), Warnings: info.Warnings}
+			}
+
+			balance = &model.TokenBalance{
+				WalletAddress: req.WalletAddress,
+				AssetType:     model.AssetTypeERC20,
+				TokenAddress:  req.TokenAddress,
+				RawBalance:    result.Balance.String(),
+				Balance:       decimal.New(result.Balance, token.Decimals).String(),
+				Symbol:        token.Symbol,
+				Name:          token.Name,
+				Decimals:      token.Decimals,
+				ChainID:       chainID,
+				ChainName:     bs.GetChainName(chainID),
+				Warnings:      token.Warnings,
+				Spam:          token.Spam,
+				Status:        model.BalanceStatusOK,
+				FetchedAt:     &fetchedAt,
+			}
+		}
+
+		balance.BlockNumber = batch.BlockNumber()
+		outcomes[i].balance = balance
+	}
+
+	return outcomes
+}
+
+// TrackActiveUser 记录用户查看了余额，调度器会为活跃用户预先刷新过期的缓存
+func (bs *BlockchainService) TrackActiveUser(userID uint, wallets []model.Wallet) {
+	bs.refresher.touch(userID, wallets)
+}
+
+// RefreshStats 返回后台刷新的运行状况
+func (bs *BlockchainService) RefreshStats() RefreshStats {
+	stats := bs.refresher.stats()
+	stats.SoftTTL = bs.softTTL.String()
+	stats.HardTTL = bs.hardTTL.String()
+	return stats
+}
+
+// refreshBalance 由后台 worker 调用，读取结果时会写入缓存
+func (bs *BlockchainService) refreshBalance(ctx context.Context, job refreshJob) error {
+	loader, exists := bs.loaders[job.chainID]
+	if !exists {
+		return fmt.Errorf("%w: %d", errUnsupportedChain, job.chainID)
+	}
+
+	_, err := loader.load(ctx, job.req)
+	return err
+}
+
+// refreshActiveWallets 为缺失或超过 soft TTL 的余额提交后台刷新
+func (bs *BlockchainService) refreshActiveWallets(wallets []model.Wallet) {
+	ctx, cancel := context.WithTimeout(context.Background(), bs.requestTimeout)
+	defer cancel()
+
+	for _, wallet := range wallets {
+		requests := []balanceRequest{{WalletAddress: wallet.Address}}
+		for _, token := range wallet.Tokens {
+			if token.IsActive && isFungible(token) {
+				requests = append(requests, balanceRequest{WalletAddress: wallet.Address, TokenAddress: token.TokenAddress, Token: token.Token})
+			}
+		}
+		for i := range wallet.ListTokens {
+			token := &wallet.ListTokens[i]
+			requests = append(requests, balanceRequest{WalletAddress: wallet.Address, TokenAddress: token.Address, Token: token})
+		}
+
+		for _, req := range requests {
+			cached, err := bs.getCachedBalance(ctx, wallet.ChainID, req)
+			if err == nil && !bs.isSoftExpired(cached) {
+				continue
+			}
+			bs.refresher.enqueue(refreshJob{chainID: wallet.ChainID, req: req})
+		}
+	}
+}
+
+func (bs *BlockchainService) isSoftExpired(balance *model.TokenBalance) bool {
+	return balance.FetchedAt == nil || time.Since(*balance.FetchedAt) >= bs.softTTL
+}
+
+func (bs *BlockchainService) getCachedBalance(ctx context.Context, chainID int, req balanceRequest) (*model.TokenBalance, error) {
+	if req.TokenAddress == "" {
+		return bs.cache.GetNativeBalance(ctx, chainID, req.WalletAddress)
+	}
+	return bs.cache.GetTokenBalance(ctx, chainID, req.WalletAddress, req.TokenAddress)
+}
+
+func (bs *BlockchainService) setCachedBalance(ctx context.Context, chainID int, req balanceRequest, balance *model.TokenBalance) {
+	if req.TokenAddress == "" {
+		bs.cache.SetNativeBalance(ctx, chainID, req.WalletAddress, balance)
+		return
 	}
+	bs.cache.SetTokenBalance(ctx, chainID, req.WalletAddress, req.TokenAddress, balance)
+}
+
+// TotalBalances 按链和资产汇总所有钱包的余额，没有拿到余额的条目不计入。
+// 汇总使用最小单位的整数，结果与逐个相加完全一致
+func TotalBalances(balances []model.TokenBalance) []model.BalanceTotal {
+	type assetKey struct {
+		chainID int
+		token   string
+	}
+
+	var order []assetKey
+	totals := make(map[assetKey]*model.BalanceTotal)
+	sums := make(map[assetKey]decimal.Decimal)
+
+	for _, balance := range balances {
+		if balance.RawBalance == "" {
+			continue
+		}
+		raw, ok := new(big.Int).SetString(balance.RawBalance, 10)
+		if !ok {
+			continue
+		}
+
+		key := assetKey{balance.ChainID, strings.ToLower(balance.TokenAddress)}
+		total, exists := totals[key]
+		if !exists {
+			total = &model.BalanceTotal{
+				ChainID:      balance.ChainID,
+				ChainName:    balance.ChainName,
+				AssetType:    balance.AssetType,
+				TokenAddress: balance.TokenAddress,
+				Symbol:       balance.Symbol,
+				Decimals:     balance.Decimals,
+			}
+			totals[key] = total
+			order = append(order, key)
+		}
+
+		sums[key] = sums[key].Add(decimal.New(raw, 0))
+		total.Wallets++
+	}
+
+	result := make([]model.BalanceTotal, 0, len(order))
+	for _, key := range order {
+		total := totals[key]
+		sum := sums[key]
+		total.RawBalance = sum.String()
+		total.Balance = decimal.New(sum.Raw(), total.Decimals).String()
+		result = append(result, *total)
+	}
+
+	return result
 }
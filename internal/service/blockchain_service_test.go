@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"wallet-tracker/internal/model"
+	"wallet-tracker/pkg/blockchain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalanceStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"deadline", fmt.Errorf("read balance: %w", context.DeadlineExceeded), model.BalanceStatusTimeout},
+		{"unsupported chain", fmt.Errorf("%w: %d", errUnsupportedChain, 999), model.BalanceStatusUnsupportedChain},
+		{"non-standard token", fmt.Errorf("%w: %v", ErrNonStandardToken, blockchain.ErrCallFailed), model.BalanceStatusNonStandardToken},
+		{"chain unavailable", fmt.Errorf("%w: chain 1: dial failed", blockchain.ErrChainUnavailable), model.BalanceStatusRPCError},
+		{"archive required", fmt.Errorf("%w: block 100", blockchain.ErrArchiveRequired), model.BalanceStatusArchiveRequired},
+		{"block not found", fmt.Errorf("%w: 5000", blockchain.ErrBlockNotFound), model.BalanceStatusInvalidBlock},
+		{"before genesis", blockchain.ErrBeforeGenesis, model.BalanceStatusInvalidBlock},
+		{"other", errors.New("boom"), model.BalanceStatusRPCError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, balanceStatus(tt.err))
+		})
+	}
+}
+
+func TestFailedBalance(t *testing.T) {
+	bs := &BlockchainService{}
+
+	balance := bs.failedBalance(999, balanceRequest{WalletAddress: "0xA", TokenAddress: "0xT"}, fmt.Errorf("%w: %d", errUnsupportedChain, 999))
+	assert.Equal(t, model.BalanceStatusUnsupportedChain, balance.Status)
+	assert.Equal(t, "unsupported chain: 999", balance.Error)
+	assert.Equal(t, "Unknown", balance.ChainName)
+	assert.Equal(t, model.AssetTypeERC20, balance.AssetType)
+	assert.False(t, balance.FromCache)
+	assert.Nil(t, balance.FetchedAt)
+}
+
+func TestTotalBalances(t *testing.T) {
+	balances := []model.TokenBalance{
+		{WalletAddress: "0xA", AssetType: model.AssetTypeNative, RawBalance: "1000000000000000001", Symbol: "ETH", Decimals: 18, ChainID: 1, ChainName: "Ethereum"},
+		{WalletAddress: "0xA", AssetType: model.AssetTypeERC20, TokenAddress: "0xUSDC", RawBalance: "1500000", Symbol: "USDC", Decimals: 6, ChainID: 1, ChainName: "Ethereum"},
+		{WalletAddress: "0xB", AssetType: model.AssetTypeNative, RawBalance: "2000000000000000000", Symbol: "ETH", Decimals: 18, ChainID: 1, ChainName: "Ethereum"},
+		{WalletAddress: "0xB", AssetType: model.AssetTypeERC20, TokenAddress: "0xusdc", RawBalance: "2500001", Symbol: "USDC", Decimals: 6, ChainID: 1, ChainName: "Ethereum"},
+		// 同一地址在其它链上单独汇总
+		{WalletAddress: "0xA", AssetType: model.AssetTypeERC20, TokenAddress: "0xUSDC", RawBalance: "1", Symbol: "USDC", Decimals: 6, ChainID: 56, ChainName: "BSC"},
+		// 没有拿到余额的条目不计入
+		{WalletAddress: "0xC", AssetType: model.AssetTypeNative, Symbol: "ETH", Decimals: 18, ChainID: 1, ChainName: "Ethereum", Status: model.BalanceStatusRPCError},
+	}
+
+	totals := TotalBalances(balances)
+	assert.Len(t, totals, 3)
+
+	assert.Equal(t, "ETH", totals[0].Symbol)
+	assert.Equal(t, "3000000000000000001", totals[0].RawBalance)
+	assert.Equal(t, "3.000000000000000001", totals[0].Balance)
+	assert.Equal(t, 2, totals[0].Wallets)
+
+	assert.Equal(t, "USDC", totals[1].Symbol)
+	assert.Equal(t, "4.000001", totals[1].Balance)
+	assert.Equal(t, 2, totals[1].Wallets)
+
+	assert.Equal(t, 56, totals[2].ChainID)
+	assert.Equal(t, "0.000001", totals[2].Balance)
+}
+
+func TestTokenMismatches(t *testing.T) {
+	token := &model.Token{Symbol: "USDC", Name: "USD Coin", Decimals: 6}
+	six, eighteen := 6, 18
+
+	assert.Empty(t, TokenMismatches(token, "", "", nil))
+	assert.Empty(t, TokenMismatches(token, "usdc", "USD Coin", &six))
+
+	mismatches := TokenMismatches(token, "USDT", "", &eighteen)
+	assert.Equal(t, []TokenMismatch{
+		{Field: "symbol", Supplied: "USDT", OnChain: "USDC"},
+		{Field: "decimals", Supplied: "18", OnChain: "6"},
+	}, mismatches)
+}
+
+func TestTokenCallError(t *testing.T) {
+	unavailable := fmt.Errorf("%w: chain 1: timeout", blockchain.ErrChainUnavailable)
+	assert.Equal(t, unavailable, tokenCallError(unavailable))
+	assert.ErrorIs(t, tokenCallError(context.DeadlineExceeded), context.DeadlineExceeded)
+	assert.ErrorIs(t, tokenCallError(errors.New("execution reverted")), ErrNonStandardToken)
+}
+
+func TestApplyTokenMetadata(t *testing.T) {
+	balance := &model.TokenBalance{RawBalance: "1500000", Balance: "0.0000000000015", Symbol: "USDC", Decimals: 18}
+
+	// 管理员修正了 decimals 后，缓存中的余额按新的小数位重新换算
+	applyTokenMetadata(balance, &model.Token{Symbol: "USDC.e", Name: "Bridged USDC", Decimals: 6, Warnings: []string{blockchain.WarningNameMissing}})
+	assert.Equal(t, "1.5", balance.Balance)
+	assert.Equal(t, []string{blockchain.WarningNameMissing}, balance.Warnings)
+	assert.Equal(t, "USDC.e", balance.Symbol)
+	assert.Equal(t, "Bridged USDC", balance.Name)
+	assert.Equal(t, 6, balance.Decimals)
+
+	applyTokenMetadata(balance, nil)
+	assert.Equal(t, "USDC.e", balance.Symbol)
+}
+
+func TestIsZeroBalance(t *testing.T) {
+	assert.True(t, isZeroBalance(&model.TokenBalance{RawBalance: "0", Balance: "0"}))
+	assert.True(t, isZeroBalance(&model.TokenBalance{Balance: "0.000"}))
+	assert.False(t, isZeroBalance(&model.TokenBalance{RawBalance: "1", Balance: "0.000001"}))
+	assert.False(t, isZeroBalance(&model.TokenBalance{Balance: "2.5"}))
+}
@@ -0,0 +1,346 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/internal/model"
+	"wallet-tracker/internal/repository"
+	"wallet-tracker/pkg/blockchain"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSuggestionNotFound   = errors.New("suggestion not found")
+	ErrSuggestionNotPending = errors.New("suggestion has already been accepted or dismissed")
+)
+
+// DiscoveryResult 是对一个钱包的一次扫描结果
+type DiscoveryResult struct {
+	WalletID  uint   `json:"wallet_id"`
+	FromBlock uint64 `json:"from_block"`
+	NextBlock uint64 `json:"next_block"`
+	Head      uint64 `json:"head"`
+	// 已扫描到最新区块
+	Complete  bool                    `json:"complete"`
+	Suggested []model.TokenSuggestion `json:"suggested"`
+	Added     []model.WalletToken     `json:"added"`
+}
+
+// DiscoveryService 从钱包的 Transfer 日志中发现持有的 token，按用户设置作为建议或直接添加到钱包。
+// 每个钱包的扫描进度保存在游标中，中断后从上次的位置继续
+type DiscoveryService struct {
+	discoveryRepo     *repository.DiscoveryRepository
+	walletRepo        *repository.WalletRepository
+	userRepo          repository.UserRepositoryInterface
+	tokenService      *TokenService
+	walletService     *WalletService
+	blockchainService *BlockchainService
+
+	interval        time.Duration
+	maxBlocksPerRun uint64
+	lookbackBlocks  uint64
+
+	// 同一个钱包不并发扫描
+	mu      sync.Mutex
+	running map[uint]bool
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+func NewDiscoveryService(cfg *config.DiscoveryConfig, discoveryRepo *repository.DiscoveryRepository, walletRepo *repository.WalletRepository, userRepo repository.UserRepositoryInterface, tokenService *TokenService, walletService *WalletService, blockchainService *BlockchainService) *DiscoveryService {
+	ds := &DiscoveryService{
+		discoveryRepo:     discoveryRepo,
+		walletRepo:        walletRepo,
+		userRepo:          userRepo,
+		tokenService:      tokenService,
+		walletService:     walletService,
+		blockchainService: blockchainService,
+		interval:          10 * time.Minute,
+		maxBlocksPerRun:   cfg.MaxBlocksPerRun,
+		lookbackBlocks:    cfg.LookbackBlocks,
+		running:           make(map[uint]bool),
+		done:              make(chan struct{}),
+	}
+
+	if interval, err := time.ParseDuration(cfg.Interval); err == nil && interval > 0 {
+		ds.interval = interval
+	}
+	if ds.maxBlocksPerRun == 0 {
+		ds.maxBlocksPerRun = 100000
+	}
+
+	if cfg.Enabled {
+		ds.wg.Add(1)
+		go ds.loop()
+	}
+
+	return ds
+}
+
+// Close 停止后台扫描
+func (ds *DiscoveryService) Close() {
+	ds.closeOnce.Do(func() {
+		close(ds.done)
+		ds.wg.Wait()
+	})
+}
+
+func (ds *DiscoveryService) loop() {
+	defer ds.wg.Done()
+
+	ticker := time.NewTicker(ds.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ds.done:
+			return
+		case <-ticker.C:
+			ds.discoverAll()
+		}
+	}
+}
+
+// discoverAll 依次扫描所有钱包，每个钱包最多扫描 maxBlocksPerRun 个区块
+func (ds *DiscoveryService) discoverAll() {
+	wallets, err := ds.walletRepo.List()
+	if err != nil {
+		log.Printf("token discovery: failed to list wallets: %v", err)
+		return
+	}
+
+	for i := range wallets {
+		select {
+		case <-ds.done:
+			return
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), ds.interval)
+		if _, err := ds.DiscoverWallet(ctx, &wallets[i]); err != nil {
+			log.Printf("token discovery: wallet %d: %v", wallets[i].ID, err)
+		}
+		cancel()
+	}
+}
+
+// discoveryRange 计算本次扫描的区块范围，已扫描到 head 时返回 false
+func discoveryRange(next, head, maxBlocks uint64) (uint64, uint64, bool) {
+	if next > head {
+		return 0, 0, false
+	}
+
+	to := head
+	if head-next >= maxBlocks {
+		to = next + maxBlocks - 1
+	}
+	return next, to, true
+}
+
+// startBlock 返回新钱包第一次扫描的起点
+func (ds *DiscoveryService) startBlock(chainID int, head uint64) uint64 {
+	start := ds.blockchainService.DiscoveryStartBlock(chainID)
+	if ds.lookbackBlocks > 0 && head > ds.lookbackBlocks && head-ds.lookbackBlocks > start {
+		start = head - ds.lookbackBlocks
+	}
+	return start
+}
+
+// DiscoverWallet 从游标处继续扫描钱包的 Transfer 日志，对仍有余额且尚未跟踪的 token
+// 创建建议，或在用户开启了 auto_add_tokens 时直接添加到钱包
+func (ds *DiscoveryService) DiscoverWallet(ctx context.Context, wallet *model.Wallet) (*DiscoveryResult, error) {
+	ds.mu.Lock()
+	if ds.running[wallet.ID] {
+		ds.mu.Unlock()
+		return nil, errors.New("discovery is already running for this wallet")
+	}
+	ds.running[wallet.ID] = true
+	ds.mu.Unlock()
+
+	defer func() {
+		ds.mu.Lock()
+		delete(ds.running, wallet.ID)
+		ds.mu.Unlock()
+	}()
+
+	user, err := ds.userRepo.GetByID(wallet.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := ds.blockchainService.LatestBlock(ctx, wallet.ChainID)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := ds.discoveryRepo.GetCursor(wallet.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		cursor = &model.DiscoveryCursor{WalletID: wallet.ID, NextBlock: ds.startBlock(wallet.ChainID, head)}
+	} else if err != nil {
+		return nil, err
+	}
+
+	result := &DiscoveryResult{
+		WalletID:  wallet.ID,
+		FromBlock: cursor.NextBlock,
+		NextBlock: cursor.NextBlock,
+		Head:      head,
+		Suggested: []model.TokenSuggestion{},
+		Added:     []model.WalletToken{},
+	}
+
+	from, to, ok := discoveryRange(cursor.NextBlock, head, ds.maxBlocksPerRun)
+	if !ok {
+		result.Complete = true
+		return result, nil
+	}
+
+	scan, scanErr := ds.blockchainService.ScanTransfers(ctx, wallet.ChainID, wallet.Address, from, to)
+
+	tracked := make(map[string]bool)
+	for _, token := range wallet.Tokens {
+		tracked[strings.ToLower(token.TokenAddress)] = true
+	}
+
+	// 先处理已发现的 token 再推进游标，处理失败时下次重新扫描这一段
+	for _, tokenAddress := range scan.Tokens {
+		if tracked[strings.ToLower(tokenAddress)] {
+			continue
+		}
+		if err := ds.offerToken(ctx, wallet, user, tokenAddress, scan.Stats[tokenAddress], result); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	cursor.NextBlock = scan.Next
+	cursor.LastRunAt = &now
+	cursor.LastError = ""
+	if scanErr != nil {
+		cursor.LastError = scanErr.Error()
+	}
+	if err := ds.discoveryRepo.SaveCursor(cursor); err != nil {
+		return nil, err
+	}
+
+	result.NextBlock = cursor.NextBlock
+	result.Complete = cursor.NextBlock > head
+	return result, scanErr
+}
+
+// offerToken 处理一个发现的 token：非 ERC-20 合约、垃圾 token 和余额为 0 的 token 被忽略
+func (ds *DiscoveryService) offerToken(ctx context.Context, wallet *model.Wallet, user *model.User, tokenAddress string, stats *blockchain.TransferStats, result *DiscoveryResult) error {
+	token, err := ds.tokenService.Resolve(ctx, wallet.ChainID, tokenAddress)
+	if err != nil {
+		if errors.Is(err, ErrNotContract) || errors.Is(err, ErrNonStandardToken) || errors.Is(err, ErrNFTCollection) || errors.Is(err, ErrInvalidTokenAddress) {
+			return nil
+		}
+		return err
+	}
+
+	token, err = ds.tokenService.RecordTransferSignals(token, stats)
+	if err != nil {
+		return err
+	}
+	if token.Spam {
+		return nil
+	}
+
+	// 余额读取失败时仍然保留建议，由用户决定
+	balance, err := ds.blockchainService.GetTokenBalance(ctx, wallet.ChainID, token.Address, wallet.Address, true)
+	if err == nil && isZeroBalance(balance) {
+		return nil
+	}
+
+	if user.AutoAddTokens {
+		walletToken, err := ds.walletService.AddTokenToWallet(wallet.ID, token)
+		if err != nil {
+			return err
+		}
+		result.Added = append(result.Added, *walletToken)
+		return nil
+	}
+
+	suggestion := &model.TokenSuggestion{
+		WalletID: wallet.ID,
+		TokenID:  token.ID,
+		Status:   model.SuggestionStatusPending,
+	}
+	if err == nil {
+		suggestion.RawBalance = balance.RawBalance
+		suggestion.Balance = balance.Balance
+	}
+
+	created, err := ds.discoveryRepo.CreateSuggestion(suggestion)
+	if err != nil {
+		return err
+	}
+	if created {
+		suggestion.Token = token
+		result.Suggested = append(result.Suggested, *suggestion)
+	}
+	return nil
+}
+
+// GetCursor 返回钱包的扫描进度，尚未扫描过的钱包返回 nil
+func (ds *DiscoveryService) GetCursor(walletID uint) (*model.DiscoveryCursor, error) {
+	cursor, err := ds.discoveryRepo.GetCursor(walletID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return cursor, err
+}
+
+func (ds *DiscoveryService) ListSuggestions(walletID uint, status string) ([]model.TokenSuggestion, error) {
+	return ds.discoveryRepo.ListSuggestions(walletID, status)
+}
+
+// AcceptSuggestion 把建议的 token 添加到钱包
+func (ds *DiscoveryService) AcceptSuggestion(walletID, suggestionID uint) (*model.WalletToken, error) {
+	suggestion, err := ds.pendingSuggestion(walletID, suggestionID)
+	if err != nil {
+		return nil, err
+	}
+
+	walletToken, err := ds.walletService.AddTokenToWallet(walletID, suggestion.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ds.discoveryRepo.UpdateSuggestionStatus(suggestion.ID, model.SuggestionStatusAccepted); err != nil {
+		return nil, err
+	}
+	return walletToken, nil
+}
+
+// DismissSuggestion 忽略建议，同一个 token 之后不会再被建议
+func (ds *DiscoveryService) DismissSuggestion(walletID, suggestionID uint) error {
+	suggestion, err := ds.pendingSuggestion(walletID, suggestionID)
+	if err != nil {
+		return err
+	}
+	return ds.discoveryRepo.UpdateSuggestionStatus(suggestion.ID, model.SuggestionStatusDismissed)
+}
+
+func (ds *DiscoveryService) pendingSuggestion(walletID, suggestionID uint) (*model.TokenSuggestion, error) {
+	suggestion, err := ds.discoveryRepo.GetSuggestion(suggestionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && suggestion.WalletID != walletID) {
+		return nil, ErrSuggestionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if suggestion.Status != model.SuggestionStatusPending {
+		return nil, ErrSuggestionNotPending
+	}
+	return suggestion, nil
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscoveryRange(t *testing.T) {
+	from, to, ok := discoveryRange(100, 1000, 5000)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(100), from)
+	assert.Equal(t, uint64(1000), to)
+
+	// 每次最多扫描 maxBlocks 个区块
+	from, to, ok = discoveryRange(100, 1000, 500)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(100), from)
+	assert.Equal(t, uint64(599), to)
+
+	from, to, ok = discoveryRange(1000, 1000, 500)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1000), from)
+	assert.Equal(t, uint64(1000), to)
+
+	_, _, ok = discoveryRange(1001, 1000, 500)
+	assert.False(t, ok)
+}
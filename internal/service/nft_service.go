@@ -0,0 +1,526 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/internal/model"
+	"wallet-tracker/internal/repository"
+	"wallet-tracker/pkg/blockchain"
+	"wallet-tracker/pkg/nftmeta"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMaxTokensPerCollection = 500
+	defaultNFTRefreshInterval     = time.Hour
+	defaultNFTMaxBlocksPerSync    = 100000
+
+	// 每次同步最多下载的元数据数和并发数，其余的下次同步继续
+	maxMetadataPerSync  = 50
+	metadataConcurrency = 8
+
+	// 下载失败后等待 metadataRetryBase 重试，每次失败加倍，最长 metadataRetryMax
+	metadataRetryBase = 10 * time.Minute
+	metadataRetryMax  = 7 * 24 * time.Hour
+
+	// 后台同步的 worker 数、队列长度和单次同步的超时时间
+	nftSyncWorkers   = 2
+	nftSyncQueueSize = 1000
+	nftSyncTimeout   = 5 * time.Minute
+)
+
+// NFTHolding 是钱包在一个合集中的持仓
+type NFTHolding struct {
+	WalletID      uint            `json:"wallet_id"`
+	WalletAddress string          `json:"wallet_address"`
+	ChainID       int             `json:"chain_id"`
+	ChainName     string          `json:"chain_name"`
+	Collection    *model.Token    `json:"collection"`
+	Balance       string          `json:"balance"`
+	Items         []model.NFTItem `json:"items"`
+	SyncedAt      *time.Time      `json:"synced_at,omitempty"`
+	// 不支持枚举的合集的 Transfer 日志还没有扫描到最新区块
+	Partial bool `json:"partial"`
+	// 持仓已过期，正在后台重新读取
+	Refreshing bool   `json:"refreshing,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// NFTService 维护钱包持有的 ERC-721 / ERC-1155 NFT：持仓从链上读取后保存，
+// 超过 refresh_interval 后查询时返回保存的结果，并在后台重新读取
+type NFTService struct {
+	nftRepo           *repository.NFTRepository
+	tokenRepo         *repository.TokenRepository
+	walletService     *WalletService
+	spamService       *SpamService
+	blockchainService *BlockchainService
+	fetcher           *nftmeta.Fetcher
+	syncer            *nftSyncer
+
+	maxTokens        int
+	maxBlocksPerSync uint64
+	refreshInterval  time.Duration
+}
+
+func NewNFTService(cfg *config.NFTConfig, nftRepo *repository.NFTRepository, tokenRepo *repository.TokenRepository, walletService *WalletService, spamService *SpamService, blockchainService *BlockchainService) (*NFTService, error) {
+	fetcher, err := nftmeta.NewFetcher(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshInterval := defaultNFTRefreshInterval
+	if cfg.RefreshInterval != "" {
+		if refreshInterval, err = time.ParseDuration(cfg.RefreshInterval); err != nil {
+			return nil, fmt.Errorf("nft.refresh_interval: %w", err)
+		}
+	}
+
+	maxTokens := cfg.MaxTokensPerCollection
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokensPerCollection
+	}
+	maxBlocks := cfg.MaxBlocksPerSync
+	if maxBlocks == 0 {
+		maxBlocks = defaultNFTMaxBlocksPerSync
+	}
+
+	ns := &NFTService{
+		nftRepo:           nftRepo,
+		tokenRepo:         tokenRepo,
+		walletService:     walletService,
+		spamService:       spamService,
+		blockchainService: blockchainService,
+		fetcher:           fetcher,
+		maxTokens:         maxTokens,
+		maxBlocksPerSync:  maxBlocks,
+		refreshInterval:   refreshInterval,
+	}
+	ns.syncer = newNFTSyncer(func(ctx context.Context, job nftSyncJob) error {
+		return ns.syncHolding(ctx, &job.wallet, job.collection)
+	}, nftSyncWorkers, nftSyncQueueSize, nftSyncTimeout)
+
+	return ns, nil
+}
+
+// Close 停止后台同步
+func (ns *NFTService) Close() {
+	ns.syncer.close()
+}
+
+// ResolveCollection 返回合集在 token 表中的记录；第一次出现的合集先在链上确认实现了 ERC-721 或 ERC-1155
+func (ns *NFTService) ResolveCollection(ctx context.Context, chainID int, contractAddress string) (*model.Token, error) {
+	if !common.IsHexAddress(contractAddress) {
+		return nil, ErrInvalidTokenAddress
+	}
+	address := common.HexToAddress(contractAddress).Hex()
+
+	token, err := ns.tokenRepo.GetByChainAddress(chainID, address)
+	if err == nil && isNFTCollection(token) {
+		return token, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	info, err := ns.blockchainService.LookupCollection(ctx, chainID, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// 旧版本可能把合集当作 ERC-20 登记过
+	if token != nil {
+		token.Standard = info.Standard
+		token.Decimals = 0
+		ns.spamService.Score(token)
+		return ns.tokenRepo.Update(token)
+	}
+
+	token = &model.Token{
+		ChainID:     chainID,
+		Address:     address,
+		Symbol:      info.Symbol,
+		Name:        info.Name,
+		Standard:    info.Standard,
+		Status:      model.TokenStatusUnverified,
+		FirstSeenAt: time.Now(),
+	}
+	ns.spamService.Score(token)
+
+	return ns.tokenRepo.FirstOrCreate(token)
+}
+
+// AddCollection 让钱包跟踪一个 NFT 合集，并立即读取持仓
+func (ns *NFTService) AddCollection(ctx context.Context, wallet *model.Wallet, contractAddress string) (*NFTHolding, error) {
+	collection, err := ns.ResolveCollection(ctx, wallet.ChainID, contractAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	tracked := false
+	for _, token := range wallet.Tokens {
+		if token.TokenID == collection.ID {
+			tracked = true
+		}
+	}
+	if !tracked {
+		if _, err := ns.walletService.AddTokenToWallet(wallet.ID, collection); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ns.syncHolding(ctx, wallet, collection); err != nil {
+		// 失败原因保存在持仓的 Error 中
+		log.Printf("failed to sync collection %s for wallet %d: %v", collection.Address, wallet.ID, err)
+	}
+	return ns.holding(wallet, collection, false)
+}
+
+// Holdings 返回用户所有钱包跟踪的合集保存的持仓，过期或 refresh 时在后台重新读取。
+// 垃圾合集默认不返回，第二个返回值是隐藏的合集数
+func (ns *NFTService) Holdings(userID uint, refresh, includeSpam bool) ([]NFTHolding, int, error) {
+	wallets, err := ns.walletService.GetUserWallets(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	holdings := []NFTHolding{}
+	hidden := 0
+	for i := range wallets {
+		wallet := &wallets[i]
+		for _, token := range wallet.Tokens {
+			if !token.IsActive || token.Token == nil || !isNFTCollection(token.Token) {
+				continue
+			}
+			if token.Token.Spam && !includeSpam {
+				hidden++
+				continue
+			}
+
+			holding, err := ns.holding(wallet, token.Token, refresh)
+			if err != nil {
+				return nil, 0, err
+			}
+			holdings = append(holdings, *holding)
+		}
+	}
+
+	return holdings, hidden, nil
+}
+
+// syncHolding 从链上同步持仓并保存，同步失败时保留上次的结果并记录错误
+func (ns *NFTService) syncHolding(ctx context.Context, wallet *model.Wallet, collection *model.Token) error {
+	state, err := ns.syncState(wallet.ID, collection.ID)
+	if err != nil {
+		return err
+	}
+
+	syncErr := ns.sync(ctx, wallet, collection, state)
+	if syncErr != nil {
+		state.LastError = syncErr.Error()
+	}
+	if err := ns.nftRepo.SaveSync(state); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+func (ns *NFTService) syncState(walletID, collectionID uint) (*model.NFTSync, error) {
+	state, err := ns.nftRepo.GetSync(walletID, collectionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.NFTSync{WalletID: walletID, CollectionID: collectionID}, nil
+	}
+	return state, err
+}
+
+// holding 返回保存的持仓，过期或 refresh 时提交后台同步
+func (ns *NFTService) holding(wallet *model.Wallet, collection *model.Token, refresh bool) (*NFTHolding, error) {
+	state, err := ns.syncState(wallet.ID, collection.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshing := false
+	if refresh || state.SyncedAt == nil || state.Partial || time.Since(*state.SyncedAt) > ns.refreshInterval {
+		refreshing = ns.syncer.enqueue(nftSyncJob{wallet: *wallet, collection: collection})
+	}
+
+	items, err := ns.nftRepo.ListItems(wallet.ID, collection.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NFTHolding{
+		WalletID:      wallet.ID,
+		WalletAddress: wallet.Address,
+		ChainID:       wallet.ChainID,
+		ChainName:     ns.blockchainService.GetChainName(wallet.ChainID),
+		Collection:    collection,
+		Balance:       state.Balance,
+		Items:         items,
+		SyncedAt:      state.SyncedAt,
+		Partial:       state.Partial,
+		Refreshing:    refreshing,
+		Error:         state.LastError,
+	}, nil
+}
+
+// sync 从链上读取钱包持有的 tokenId：支持 ERC721Enumerable 的合集直接枚举，
+// 其它合集从转账日志中找出收到过的 token，再确认仍然持有
+func (ns *NFTService) sync(ctx context.Context, wallet *model.Wallet, collection *model.Token, state *model.NFTSync) error {
+	client, err := ns.blockchainService.client(wallet.ChainID)
+	if err != nil {
+		return err
+	}
+
+	info, err := client.GetNFTCollectionInfo(ctx, collection.Address)
+	if err != nil {
+		return err
+	}
+
+	var balance *big.Int
+	var owned []model.NFTItem
+	var scanErr error
+	if info.Standard == blockchain.StandardERC721 && info.Enumerable {
+		var tokenIDs []*big.Int
+		balance, tokenIDs, err = client.EnumerateNFTs(ctx, collection.Address, wallet.Address, ns.maxTokens)
+		if err != nil {
+			return err
+		}
+		for _, tokenID := range tokenIDs {
+			owned = append(owned, model.NFTItem{TokenID: tokenID.String(), Balance: "1"})
+		}
+		state.Partial = false
+	} else {
+		balance, owned, scanErr = ns.scanOwned(ctx, client, wallet, collection, info.Standard, state)
+		if balance == nil {
+			return scanErr
+		}
+	}
+
+	if err := ns.nftRepo.ReplaceItems(wallet.ID, collection.ID, owned); err != nil {
+		return err
+	}
+
+	if err := ns.fetchMetadata(ctx, client, wallet.ID, collection, info.Standard); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	state.Balance = balance.String()
+	state.SyncedAt = &now
+	state.LastError = ""
+	return scanErr
+}
+
+// scanOwned 从 state.NextBlock 继续扫描转入钱包的转账日志，每次最多 maxBlocksPerSync 个区块。
+// 已记录的 token 和新发现的 token 一起确认：ERC-721 用 ownerOf，ERC-1155 用 balanceOfBatch。
+// ERC-1155 合约没有总余额，返回的 balance 是持有数量之和。扫描中途失败时返回已确认的结果和错误
+func (ns *NFTService) scanOwned(ctx context.Context, client *blockchain.BlockchainClient, wallet *model.Wallet, collection *model.Token, standard string, state *model.NFTSync) (*big.Int, []model.NFTItem, error) {
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if state.NextBlock == 0 {
+		state.NextBlock = ns.blockchainService.DiscoveryStartBlock(wallet.ChainID)
+	}
+
+	var scanErr error
+	candidates := make(map[string]*big.Int)
+	if from, to, ok := discoveryRange(state.NextBlock, head, ns.maxBlocksPerSync); ok {
+		logRange := ns.blockchainService.chains[wallet.ChainID].LogRange
+		var scan *blockchain.NFTTransferScan
+		if standard == blockchain.StandardERC1155 {
+			scan, scanErr = client.ScanERC1155Transfers(ctx, collection.Address, wallet.Address, from, to, logRange)
+		} else {
+			scan, scanErr = client.ScanNFTTransfers(ctx, collection.Address, wallet.Address, from, to, logRange)
+		}
+		for _, tokenID := range scan.TokenIDs {
+			candidates[tokenID.String()] = tokenID
+		}
+		state.NextBlock = scan.Next
+	}
+	state.Partial = state.NextBlock <= head
+
+	items, err := ns.nftRepo.ListItems(wallet.ID, collection.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, item := range items {
+		if tokenID, ok := new(big.Int).SetString(item.TokenID, 10); ok {
+			candidates[item.TokenID] = tokenID
+		}
+	}
+
+	var tokenIDs []*big.Int
+	for _, tokenID := range candidates {
+		tokenIDs = append(tokenIDs, tokenID)
+	}
+	sort.Slice(tokenIDs, func(i, j int) bool { return tokenIDs[i].Cmp(tokenIDs[j]) < 0 })
+
+	var balance *big.Int
+	var owned []model.NFTItem
+	if standard == blockchain.StandardERC1155 {
+		amounts, err := client.ERC1155Balances(ctx, collection.Address, wallet.Address, tokenIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+		balance = new(big.Int)
+		for i, tokenID := range tokenIDs {
+			if amounts[i].Sign() > 0 {
+				balance.Add(balance, amounts[i])
+				owned = append(owned, model.NFTItem{TokenID: tokenID.String(), Balance: amounts[i].String()})
+			}
+		}
+	} else {
+		if balance, err = client.GetTokenBalance(ctx, collection.Address, wallet.Address); err != nil {
+			return nil, nil, err
+		}
+		owners, err := client.OwnersOf(ctx, collection.Address, tokenIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+		owner := common.HexToAddress(wallet.Address)
+		for i, tokenID := range tokenIDs {
+			if owners[i] == owner {
+				owned = append(owned, model.NFTItem{TokenID: tokenID.String(), Balance: "1"})
+			}
+		}
+	}
+
+	if len(owned) > ns.maxTokens {
+		owned = owned[:ns.maxTokens]
+	}
+	return balance, owned, scanErr
+}
+
+// fetchMetadata 为还没有元数据或上次下载失败的 NFT 读取 tokenURI（ERC-1155 为 uri）并下载元数据
+func (ns *NFTService) fetchMetadata(ctx context.Context, client *blockchain.BlockchainClient, walletID uint, collection *model.Token, standard string) error {
+	items, err := ns.nftRepo.ListItems(walletID, collection.ID)
+	if err != nil {
+		return err
+	}
+
+	pending := pendingMetadata(items, time.Now())
+	if len(pending) == 0 {
+		return nil
+	}
+	tokenIDs := make([]*big.Int, len(pending))
+	for i, item := range pending {
+		tokenIDs[i], _ = new(big.Int).SetString(item.TokenID, 10)
+	}
+
+	var uris []blockchain.StringResult
+	if standard == blockchain.StandardERC1155 {
+		uris, err = client.ERC1155URIs(ctx, collection.Address, tokenIDs)
+	} else {
+		uris, err = client.TokenURIs(ctx, collection.Address, tokenIDs)
+	}
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, metadataConcurrency)
+	for i, item := range pending {
+		wg.Add(1)
+		go func(item *model.NFTItem, uri blockchain.StringResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			ns.applyMetadata(ctx, item, uri)
+		}(item, uris[i])
+	}
+	wg.Wait()
+
+	for _, item := range pending {
+		if err := ns.nftRepo.UpdateItem(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pendingMetadata 选出这次同步要下载元数据的 NFT，最多 maxMetadataPerSync 个。
+// 从未下载过的排在前面；下载失败的按退避时间重试，等待最久的优先，
+// 永久失效的 URI 不会占满每次同步的名额
+func pendingMetadata(items []model.NFTItem, now time.Time) []*model.NFTItem {
+	var fresh, retries []*model.NFTItem
+	for i := range items {
+		item := &items[i]
+		if _, ok := new(big.Int).SetString(item.TokenID, 10); !ok {
+			continue
+		}
+		switch {
+		case item.MetadataFetchedAt == nil:
+			fresh = append(fresh, item)
+		case item.MetadataError != "" && !now.Before(metadataRetryAt(item)):
+			retries = append(retries, item)
+		}
+	}
+	sort.SliceStable(retries, func(i, j int) bool { return metadataRetryAt(retries[i]).Before(metadataRetryAt(retries[j])) })
+
+	pending := append(fresh, retries...)
+	if len(pending) > maxMetadataPerSync {
+		pending = pending[:maxMetadataPerSync]
+	}
+	return pending
+}
+
+// metadataRetryAt 返回下载失败的元数据下次可以重试的时间
+func metadataRetryAt(item *model.NFTItem) time.Time {
+	delay := metadataRetryMax
+	// 失败次数很多时已经是最长间隔，不需要移位，也避免溢出
+	if attempts := item.MetadataAttempts; attempts < 20 {
+		if d := metadataRetryBase << max(attempts-1, 0); d < delay {
+			delay = d
+		}
+	}
+	return item.MetadataFetchedAt.Add(delay)
+}
+
+func (ns *NFTService) applyMetadata(ctx context.Context, item *model.NFTItem, uri blockchain.StringResult) {
+	now := time.Now()
+	item.MetadataFetchedAt = &now
+	item.MetadataError = ""
+
+	if uri.Err != nil {
+		item.MetadataError = uri.Err.Error()
+		item.MetadataAttempts++
+		return
+	}
+	item.TokenURI = strings.TrimSpace(uri.Value)
+	if item.TokenURI == "" {
+		item.MetadataAttempts = 0
+		return
+	}
+
+	metadata, err := ns.fetcher.Fetch(ctx, item.TokenURI)
+	if err != nil {
+		item.MetadataError = err.Error()
+		item.MetadataAttempts++
+		return
+	}
+	item.MetadataAttempts = 0
+
+	item.Name = metadata.Name
+	item.Description = metadata.Description
+	item.ImageURL = metadata.Image
+	item.Attributes = string(metadata.Attributes)
+}
+
+func isNFTCollection(token *model.Token) bool {
+	return token.Standard == model.TokenStandardERC721 || token.Standard == model.TokenStandardERC1155
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/internal/model"
+	"wallet-tracker/internal/repository"
+	"wallet-tracker/pkg/blockchain"
+	"wallet-tracker/pkg/nftmeta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestNFTService_ApplyMetadata(t *testing.T) {
+	fetcher, err := nftmeta.NewFetcher(&config.NFTConfig{IPFSGateway: "https://gateway.example"})
+	require.NoError(t, err)
+	ns := &NFTService{fetcher: fetcher}
+	ctx := context.Background()
+
+	item := &model.NFTItem{TokenID: "1"}
+	ns.applyMetadata(ctx, item, blockchain.StringResult{Value: `data:application/json,{"name":"Ape #1","image":"ipfs://QmImage"}`})
+	assert.Equal(t, "Ape #1", item.Name)
+	assert.Equal(t, "https://gateway.example/ipfs/QmImage", item.ImageURL)
+	assert.Empty(t, item.MetadataError)
+	assert.NotNil(t, item.MetadataFetchedAt)
+
+	// tokenURI 调用失败时记录错误，下次同步重试
+	item = &model.NFTItem{TokenID: "2"}
+	ns.applyMetadata(ctx, item, blockchain.StringResult{Err: errors.New("execution reverted")})
+	assert.Equal(t, "execution reverted", item.MetadataError)
+
+	item = &model.NFTItem{TokenID: "3"}
+	ns.applyMetadata(ctx, item, blockchain.StringResult{Value: "ftp://example.com/3"})
+	assert.Contains(t, item.MetadataError, nftmeta.ErrUnsupportedURI.Error())
+}
+
+func TestPendingMetadata(t *testing.T) {
+	now := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	at := func(ago time.Duration) *time.Time {
+		t := now.Add(-ago)
+		return &t
+	}
+
+	var items []model.NFTItem
+	// 大量永久失败的 URI，已经重试过多次
+	for i := 0; i < maxMetadataPerSync; i++ {
+		items = append(items, model.NFTItem{ID: uint(i + 1), TokenID: fmt.Sprint(i), MetadataError: "404 Not Found", MetadataFetchedAt: at(time.Hour), MetadataAttempts: 5})
+	}
+	items = append(items,
+		// 已经下载成功
+		model.NFTItem{ID: 100, TokenID: "100", MetadataFetchedAt: at(time.Hour)},
+		// 第一次失败后已经等待超过 metadataRetryBase
+		model.NFTItem{ID: 101, TokenID: "101", MetadataError: "timeout", MetadataFetchedAt: at(time.Hour), MetadataAttempts: 1},
+		// 刚刚失败
+		model.NFTItem{ID: 102, TokenID: "102", MetadataError: "timeout", MetadataFetchedAt: at(time.Minute), MetadataAttempts: 1},
+		// 从未下载
+		model.NFTItem{ID: 103, TokenID: "103"},
+	)
+
+	pending := pendingMetadata(items, now)
+	require.Len(t, pending, 2)
+	assert.Equal(t, uint(103), pending[0].ID)
+	assert.Equal(t, uint(101), pending[1].ID)
+
+	// 退避时间到了之后，等待最久的失败条目优先
+	pending = pendingMetadata(items, now.Add(7*24*time.Hour))
+	require.Len(t, pending, maxMetadataPerSync)
+	assert.Equal(t, uint(103), pending[0].ID)
+	assert.Equal(t, uint(101), pending[1].ID)
+}
+
+func TestNFTService_ApplyMetadata_Attempts(t *testing.T) {
+	fetcher, err := nftmeta.NewFetcher(&config.NFTConfig{})
+	require.NoError(t, err)
+	ns := &NFTService{fetcher: fetcher}
+	ctx := context.Background()
+
+	item := &model.NFTItem{TokenID: "1"}
+	ns.applyMetadata(ctx, item, blockchain.StringResult{Value: "ftp://example.com/1"})
+	ns.applyMetadata(ctx, item, blockchain.StringResult{Value: "ftp://example.com/1"})
+	assert.Equal(t, 2, item.MetadataAttempts)
+	assert.Equal(t, item.MetadataFetchedAt.Add(2*metadataRetryBase), metadataRetryAt(item))
+
+	ns.applyMetadata(ctx, item, blockchain.StringResult{Value: `data:application/json,{"name":"Ape #1"}`})
+	assert.Zero(t, item.MetadataAttempts)
+	assert.Empty(t, item.MetadataError)
+}
+
+func TestNFTService_HoldingSyncsInBackground(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.NFTItem{}, &model.NFTSync{}))
+	nftRepo := repository.NewNFTRepository(db)
+
+	// 同步一直阻塞，模拟很慢的 RPC 或 IPFS 网关
+	release := make(chan struct{})
+	synced := make(chan nftSyncJob, 10)
+	ns := &NFTService{
+		nftRepo:           nftRepo,
+		blockchainService: &BlockchainService{chains: map[int]config.ChainConfig{1: {ChainID: 1, Name: "Ethereum"}}},
+		refreshInterval:   time.Hour,
+	}
+	ns.syncer = newNFTSyncer(func(ctx context.Context, job nftSyncJob) error {
+		synced <- job
+		<-release
+		return nil
+	}, 1, 10, time.Second)
+	defer ns.syncer.close()
+	defer close(release)
+
+	wallet := &model.Wallet{ID: 1, Address: "0xA", ChainID: 1}
+	stale := &model.Token{ID: 10, Address: "0xStale"}
+	fresh := &model.Token{ID: 11, Address: "0xFresh"}
+
+	old := time.Now().Add(-2 * time.Hour)
+	recent := time.Now().Add(-time.Minute)
+	require.NoError(t, nftRepo.SaveSync(&model.NFTSync{WalletID: 1, CollectionID: stale.ID, Balance: "1", SyncedAt: &old}))
+	require.NoError(t, nftRepo.SaveSync(&model.NFTSync{WalletID: 1, CollectionID: fresh.ID, Balance: "2", SyncedAt: &recent}))
+	require.NoError(t, nftRepo.ReplaceItems(1, stale.ID, []model.NFTItem{{TokenID: "7", Balance: "1"}}))
+
+	// 过期的持仓立即返回保存的结果，同步在后台进行
+	holding, err := ns.holding(wallet, stale, false)
+	require.NoError(t, err)
+	assert.Equal(t, "1", holding.Balance)
+	require.Len(t, holding.Items, 1)
+	assert.Equal(t, "7", holding.Items[0].TokenID)
+	assert.True(t, holding.Refreshing)
+
+	select {
+	case job := <-synced:
+		assert.Equal(t, stale.ID, job.collection.ID)
+	case <-time.After(time.Second):
+		t.Fatal("stale holding was not synced in the background")
+	}
+
+	// 同步完成前再次查询不会重复提交
+	holding, err = ns.holding(wallet, stale, true)
+	require.NoError(t, err)
+	assert.True(t, holding.Refreshing)
+
+	holding, err = ns.holding(wallet, fresh, false)
+	require.NoError(t, err)
+	assert.Equal(t, "2", holding.Balance)
+	assert.False(t, holding.Refreshing)
+
+	assert.Empty(t, synced)
+}
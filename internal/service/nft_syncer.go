@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"wallet-tracker/internal/model"
+)
+
+type nftSyncJob struct {
+	wallet     model.Wallet
+	collection *model.Token
+}
+
+func (j nftSyncJob) key() string {
+	return fmt.Sprintf("%d:%d", j.wallet.ID, j.collection.ID)
+}
+
+// nftSyncer 与 balanceRefresher 相同，用固定数量的 worker 在后台同步过期的 NFT 持仓，
+// 查询接口直接返回保存的结果，不等待链上读取和元数据下载
+type nftSyncer struct {
+	sync    func(ctx context.Context, job nftSyncJob) error
+	timeout time.Duration
+	queue   chan nftSyncJob
+
+	mu      sync.Mutex
+	pending map[string]bool
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+func newNFTSyncer(sync func(ctx context.Context, job nftSyncJob) error, workers, queueSize int, timeout time.Duration) *nftSyncer {
+	s := &nftSyncer{
+		sync:    sync,
+		timeout: timeout,
+		queue:   make(chan nftSyncJob, queueSize),
+		pending: make(map[string]bool),
+		done:    make(chan struct{}),
+	}
+
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+
+	return s
+}
+
+// enqueue 提交一次同步，返回持仓是否正在等待同步。已在队列中的持仓不会重复提交；
+// 队列满时直接丢弃，下次查询时再次提交
+func (s *nftSyncer) enqueue(job nftSyncJob) bool {
+	key := job.key()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending[key] {
+		return true
+	}
+
+	select {
+	case s.queue <- job:
+		s.pending[key] = true
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *nftSyncer) worker() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case job := <-s.queue:
+			ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+			// 失败原因保存在 NFTSync.LastError 中
+			_ = s.sync(ctx, job)
+			cancel()
+
+			s.mu.Lock()
+			delete(s.pending, job.key())
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *nftSyncer) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+	})
+}
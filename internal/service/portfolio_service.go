@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"wallet-tracker/internal/model"
+	"wallet-tracker/internal/repository"
+	"wallet-tracker/pkg/decimal"
+	"wallet-tracker/pkg/price"
+)
+
+var ErrInvalidInterval = errors.New("invalid interval")
+
+// 一次返回的最多点数；未指定间隔时从 autoIntervals 中选择点数不超过 defaultHistoryPoints 的最小间隔
+const (
+	maxHistoryPoints     = 1000
+	defaultHistoryPoints = 400
+)
+
+var autoIntervals = []time.Duration{
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+}
+
+// 价值保留的小数位
+const valuePlaces = 2
+
+// PortfolioHistory 是用户资产总值的时间序列
+type PortfolioHistory struct {
+	Currency string           `json:"currency"`
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Interval string           `json:"interval"`
+	Points   []PortfolioPoint `json:"points"`
+}
+
+// PortfolioPoint 是一个时间区间内每种资产最后一条快照的价值，按链和 token 拆分
+type PortfolioPoint struct {
+	Time   time.Time    `json:"time"`
+	Value  string       `json:"value"`
+	Chains []ChainValue `json:"chains"`
+	Tokens []TokenValue `json:"tokens"`
+	// 没有价格、没有计入总值的资产数
+	Unpriced int `json:"unpriced,omitempty"`
+}
+
+type ChainValue struct {
+	ChainID   int    `json:"chain_id"`
+	ChainName string `json:"chain_name"`
+	Value     string `json:"value"`
+}
+
+// TokenValue 是一种资产在所有钱包中的合计，没有价格时 Price 和 Value 为空
+type TokenValue struct {
+	ChainID      int    `json:"chain_id"`
+	TokenAddress string `json:"token_address,omitempty"`
+	Symbol       string `json:"symbol"`
+	Balance      string `json:"balance"`
+	Price        string `json:"price,omitempty"`
+	Value        string `json:"value,omitempty"`
+}
+
+// PortfolioService 用余额快照和价格计算用户资产总值的历史
+type PortfolioService struct {
+	snapshotRepo      *repository.SnapshotRepository
+	userRepo          repository.UserRepositoryInterface
+	blockchainService *BlockchainService
+	prices            price.PriceProvider
+}
+
+func NewPortfolioService(snapshotRepo *repository.SnapshotRepository, userRepo repository.UserRepositoryInterface, blockchainService *BlockchainService, prices price.PriceProvider) *PortfolioService {
+	return &PortfolioService{
+		snapshotRepo:      snapshotRepo,
+		userRepo:          userRepo,
+		blockchainService: blockchainService,
+		prices:            prices,
+	}
+}
+
+// ParseInterval 解析 1h、30m 这样的时长，另外支持以天为单位的 1d 和以周为单位的 1w
+func ParseInterval(value string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(value, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(value, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+
+	var interval time.Duration
+	if unit > 0 {
+		n, err := strconv.Atoi(strings.TrimRight(value, "dw"))
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidInterval, value)
+		}
+		interval = time.Duration(n) * unit
+	} else {
+		var err error
+		if interval, err = time.ParseDuration(value); err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidInterval, value)
+		}
+	}
+
+	if interval < time.Minute {
+		return 0, fmt.Errorf("%w: %q is shorter than 1m", ErrInvalidInterval, value)
+	}
+	return interval, nil
+}
+
+// historyInterval 返回时间序列的间隔：未指定时自动选择，指定的间隔不能产生超过 maxHistoryPoints 个点
+func historyInterval(from, to time.Time, interval time.Duration) (time.Duration, error) {
+	span := to.Sub(from)
+	if interval == 0 {
+		for _, candidate := range autoIntervals {
+			if span/candidate < defaultHistoryPoints {
+				return candidate, nil
+			}
+		}
+		return autoIntervals[len(autoIntervals)-1], nil
+	}
+
+	if span/interval >= maxHistoryPoints {
+		return 0, fmt.Errorf("%w: %s gives more than %d points for this range", ErrInvalidInterval, interval, maxHistoryPoints)
+	}
+	return interval, nil
+}
+
+// History 返回用户资产在 [from, to] 内的价值。快照按 interval 分组，每组中每种资产使用最后一条快照，
+// 并按组内最后一次快照的时间取价格；interval 为 0 时自动选择
+func (ps *PortfolioService) History(ctx context.Context, userID uint, from, to time.Time, interval time.Duration) (*PortfolioHistory, error) {
+	interval, err := historyInterval(from, to, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := ps.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	currency := user.BaseCurrency
+	if currency == "" {
+		currency = price.DefaultCurrency
+	}
+
+	snapshots, err := ps.snapshotRepo.ListByUser(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &PortfolioHistory{
+		Currency: currency,
+		From:     from,
+		To:       to,
+		Interval: interval.String(),
+		Points:   []PortfolioPoint{},
+	}
+	runs := bucketSnapshots(snapshots, interval)
+	ps.preloadPrices(ctx, currency, runs)
+	for _, run := range runs {
+		point, err := ps.value(ctx, currency, run)
+		if err != nil {
+			return nil, err
+		}
+		history.Points = append(history.Points, *point)
+	}
+	return history, nil
+}
+
+// preloadPrices 让支持的价格来源一次读取所有资产在整个范围内的价格，而不是每个点请求一次。
+// 失败时只记录日志，之后逐点查询
+func (ps *PortfolioService) preloadPrices(ctx context.Context, currency string, runs [][]model.BalanceSnapshot) {
+	preloader, ok := ps.prices.(price.Preloader)
+	if !ok || len(runs) == 0 {
+		return
+	}
+
+	var assets []price.Asset
+	seen := make(map[price.Asset]bool)
+	from, to := runs[0][0].TakenAt, runs[0][0].TakenAt
+	for _, run := range runs {
+		for _, snapshot := range run {
+			asset := price.NewAsset(snapshot.ChainID, snapshot.TokenAddress)
+			if !seen[asset] {
+				seen[asset] = true
+				assets = append(assets, asset)
+			}
+			if snapshot.TakenAt.Before(from) {
+				from = snapshot.TakenAt
+			}
+			if snapshot.TakenAt.After(to) {
+				to = snapshot.TakenAt
+			}
+		}
+	}
+
+	if err := preloader.Preload(ctx, currency, from, to, assets); err != nil {
+		log.Printf("failed to preload %s prices for %d assets: %v", currency, len(assets), err)
+	}
+}
+
+// bucketSnapshots 把按时间排列的快照按 interval 分组，每组中每个钱包的每种资产只保留最后一条。
+// 一次记录可能缺少部分资产（读取失败、区间内新加的 token、降采样留下的不同记录），
+// 按资产保留可以避免这些资产从区间的价值中消失
+func bucketSnapshots(snapshots []model.BalanceSnapshot, interval time.Duration) [][]model.BalanceSnapshot {
+	type assetKey struct {
+		walletID uint
+		chainID  int
+		token    string
+	}
+
+	var runs [][]model.BalanceSnapshot
+	var bucket time.Time
+	var latest map[assetKey]int
+	for _, snapshot := range snapshots {
+		start := snapshot.TakenAt.Truncate(interval)
+		if len(runs) == 0 || !start.Equal(bucket) {
+			// 新的区间
+			bucket = start
+			runs = append(runs, nil)
+			latest = make(map[assetKey]int)
+		}
+
+		run := &runs[len(runs)-1]
+		key := assetKey{snapshot.WalletID, snapshot.ChainID, strings.ToLower(snapshot.TokenAddress)}
+		if i, exists := latest[key]; exists {
+			// 更晚的快照替换同一资产之前的
+			(*run)[i] = snapshot
+			continue
+		}
+		latest[key] = len(*run)
+		*run = append(*run, snapshot)
+	}
+	return runs
+}
+
+// value 计算一组快照的价值，时间和价格取自其中最晚的快照
+func (ps *PortfolioService) value(ctx context.Context, currency string, run []model.BalanceSnapshot) (*PortfolioPoint, error) {
+	at := run[0].TakenAt
+	for _, snapshot := range run[1:] {
+		if snapshot.TakenAt.After(at) {
+			at = snapshot.TakenAt
+		}
+	}
+
+	type holding struct {
+		asset   price.Asset
+		symbol  string
+		balance decimal.Decimal
+	}
+	var holdings []*holding
+	byAsset := make(map[price.Asset]*holding)
+	for _, snapshot := range run {
+		balance, err := decimal.Parse(snapshot.Balance)
+		if err != nil {
+			continue
+		}
+
+		asset := price.NewAsset(snapshot.ChainID, snapshot.TokenAddress)
+		h, exists := byAsset[asset]
+		if !exists {
+			h = &holding{asset: asset, symbol: snapshot.Symbol}
+			byAsset[asset] = h
+			holdings = append(holdings, h)
+		}
+		h.balance = h.balance.Add(balance)
+	}
+
+	assets := make([]price.Asset, len(holdings))
+	for i, h := range holdings {
+		assets[i] = h.asset
+	}
+	prices, err := ps.prices.Prices(ctx, currency, at, assets)
+	if err != nil {
+		return nil, err
+	}
+
+	point := &PortfolioPoint{Time: at, Chains: []ChainValue{}, Tokens: []TokenValue{}}
+	var total decimal.Decimal
+	chainTotals := make(map[int]decimal.Decimal)
+	for _, h := range holdings {
+		token := TokenValue{
+			ChainID:      h.asset.ChainID,
+			TokenAddress: h.asset.Address,
+			Symbol:       h.symbol,
+			Balance:      h.balance.String(),
+		}
+
+		if unitPrice, ok := prices[h.asset]; ok {
+			value := h.balance.Mul(unitPrice)
+			token.Price = unitPrice.String()
+			token.Value = value.Round(valuePlaces).String()
+			total = total.Add(value)
+			chainTotals[h.asset.ChainID] = chainTotals[h.asset.ChainID].Add(value)
+		} else if !h.balance.IsZero() {
+			point.Unpriced++
+		}
+		point.Tokens = append(point.Tokens, token)
+	}
+
+	for chainID, value := range chainTotals {
+		point.Chains = append(point.Chains, ChainValue{
+			ChainID:   chainID,
+			ChainName: ps.blockchainService.GetChainName(chainID),
+			Value:     value.Round(valuePlaces).String(),
+		})
+	}
+	sort.Slice(point.Chains, func(i, j int) bool { return point.Chains[i].ChainID < point.Chains[j].ChainID })
+	point.Value = total.Round(valuePlaces).String()
+
+	return point, nil
+}
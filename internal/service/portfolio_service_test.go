@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/internal/model"
+	"wallet-tracker/internal/repository"
+	"wallet-tracker/pkg/decimal"
+	"wallet-tracker/pkg/price"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestParseInterval(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"30m": 30 * time.Minute,
+		"6h":  6 * time.Hour,
+		"1d":  24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+	} {
+		interval, err := ParseInterval(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, interval, value)
+	}
+
+	for _, value := range []string{"", "day", "xd", "10s", "-1h"} {
+		_, err := ParseInterval(value)
+		assert.ErrorIs(t, err, ErrInvalidInterval, value)
+	}
+}
+
+func TestHistoryInterval(t *testing.T) {
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	interval, err := historyInterval(from, from.Add(7*24*time.Hour), 0)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, interval)
+
+	// 一年的数据自动按天分组
+	interval, err = historyInterval(from, from.AddDate(1, 0, 0), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, interval)
+
+	_, err = historyInterval(from, from.AddDate(1, 0, 0), time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidInterval)
+}
+
+func TestBucketSnapshots(t *testing.T) {
+	base := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	snapshot := func(minutes int, walletID uint, token, balance string) model.BalanceSnapshot {
+		return model.BalanceSnapshot{WalletID: walletID, ChainID: 1, TokenAddress: token, Balance: balance, TakenAt: base.Add(time.Duration(minutes) * time.Minute)}
+	}
+
+	runs := bucketSnapshots([]model.BalanceSnapshot{
+		// 同一个小时内的两次记录包含不同的资产：00:00 有 ETH 和 USDC，00:30 的 USDC 读取失败
+		snapshot(0, 1, "", "1"),
+		snapshot(0, 1, "0xA0b8", "100"),
+		snapshot(30, 1, "", "2"),
+		// 区间内才加入的钱包
+		snapshot(45, 2, "", "3"),
+		snapshot(60, 1, "", "4"),
+	}, time.Hour)
+
+	require.Len(t, runs, 2)
+	require.Len(t, runs[0], 3)
+	assert.Equal(t, "2", runs[0][0].Balance)
+	assert.True(t, runs[0][0].TakenAt.Equal(base.Add(30*time.Minute)))
+	assert.Equal(t, "100", runs[0][1].Balance)
+	assert.Equal(t, uint(2), runs[0][2].WalletID)
+	require.Len(t, runs[1], 1)
+	assert.Equal(t, "4", runs[1][0].Balance)
+}
+
+func TestPortfolioService_History(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.BalanceSnapshot{}))
+	snapshotRepo := repository.NewSnapshotRepository(db)
+
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetByID", uint(1)).Return(&model.User{ID: 1, BaseCurrency: "eur"}, nil)
+
+	eth := decimal.MustParse("2000")
+	usdc := decimal.MustParse("1")
+	prices, err := price.NewStaticProvider(&price.StaticFile{
+		Rates: map[string]decimal.Decimal{"eur": decimal.MustParse("0.5")},
+		Prices: []price.StaticPrice{
+			{ChainID: 1, USD: &eth},
+			{ChainID: 1, Address: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", USD: &usdc},
+		},
+	})
+	require.NoError(t, err)
+
+	bs := &BlockchainService{chains: map[int]config.ChainConfig{1: {ChainID: 1, Name: "Ethereum"}, 56: {ChainID: 56, Name: "BSC"}}}
+	preloader := &recordingPreloader{PriceProvider: prices}
+	ps := NewPortfolioService(snapshotRepo, userRepo, bs, preloader)
+
+	base := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	snapshot := func(takenAt time.Time, walletID uint, chainID int, token, symbol, balance string) model.BalanceSnapshot {
+		return model.BalanceSnapshot{UserID: 1, WalletID: walletID, ChainID: chainID, TokenAddress: token, Symbol: symbol, RawBalance: "0", Balance: balance, TakenAt: takenAt}
+	}
+	require.NoError(t, snapshotRepo.Create([]model.BalanceSnapshot{
+		// 00:00 和 00:30 在同一个小时内，只使用 00:30
+		snapshot(base, 1, 1, "", "ETH", "9"),
+		snapshot(base.Add(30*time.Minute), 1, 1, "", "ETH", "1"),
+		snapshot(base.Add(30*time.Minute), 2, 1, "", "ETH", "0.5"),
+		snapshot(base.Add(30*time.Minute), 1, 1, "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", "USDC", "100.25"),
+		// BSC 上的 BNB 没有价格
+		snapshot(base.Add(30*time.Minute), 3, 56, "", "BNB", "2"),
+		snapshot(base.Add(time.Hour), 1, 1, "", "ETH", "2"),
+		// 同一个小时内较晚的一次记录只有 USDC，ETH 仍按 01:00 的快照计入
+		snapshot(base.Add(80*time.Minute), 1, 1, "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", "USDC", "50"),
+	}))
+
+	history, err := ps.History(context.Background(), 1, base, base.Add(2*time.Hour), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "eur", history.Currency)
+	assert.Equal(t, "1h0m0s", history.Interval)
+	require.Len(t, history.Points, 2)
+
+	first := history.Points[0]
+	assert.True(t, first.Time.Equal(base.Add(30*time.Minute)))
+	// (1 + 0.5) * 1000 + 100.25 * 0.5
+	assert.Equal(t, "1550.13", first.Value)
+	assert.Equal(t, 1, first.Unpriced)
+	require.Len(t, first.Chains, 1)
+	assert.Equal(t, ChainValue{ChainID: 1, ChainName: "Ethereum", Value: "1550.13"}, first.Chains[0])
+	require.Len(t, first.Tokens, 3)
+	assert.Equal(t, TokenValue{ChainID: 1, Symbol: "ETH", Balance: "1.5", Price: "1000", Value: "1500"}, first.Tokens[0])
+	assert.Equal(t, "", first.Tokens[2].Value)
+
+	// 2 * 1000 + 50 * 0.5
+	assert.Equal(t, "2025", history.Points[1].Value)
+	assert.True(t, history.Points[1].Time.Equal(base.Add(80*time.Minute)))
+
+	// 整个范围的价格只预加载一次
+	assert.Equal(t, 1, preloader.calls)
+	assert.True(t, preloader.from.Equal(base.Add(30*time.Minute)))
+	assert.True(t, preloader.to.Equal(base.Add(80*time.Minute)))
+	assert.Len(t, preloader.assets, 3)
+}
+
+// recordingPreloader 记录 Preload 的调用，价格由内嵌的来源提供
+type recordingPreloader struct {
+	price.PriceProvider
+	calls    int
+	from, to time.Time
+	assets   []price.Asset
+}
+
+func (rp *recordingPreloader) Preload(ctx context.Context, currency string, from, to time.Time, assets []price.Asset) error {
+	rp.calls++
+	rp.from, rp.to, rp.assets = from, to, assets
+	return nil
+}
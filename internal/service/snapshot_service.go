@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/internal/model"
+	"wallet-tracker/internal/repository"
+)
+
+// SnapshotService 定时把所有钱包的余额记录到 balance_snapshots 表，并对旧快照降采样：
+// 最近 rawRetention 内的全部保留，之后每小时保留一条，超过 hourlyRetention 后每天保留一条
+type SnapshotService struct {
+	snapshotRepo      *repository.SnapshotRepository
+	walletRepo        *repository.WalletRepository
+	walletService     *WalletService
+	blockchainService *BlockchainService
+
+	interval        time.Duration
+	rawRetention    time.Duration
+	hourlyRetention time.Duration
+
+	// 同一时间只运行一次记录
+	mu sync.Mutex
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+func NewSnapshotService(cfg *config.SnapshotConfig, snapshotRepo *repository.SnapshotRepository, walletRepo *repository.WalletRepository, walletService *WalletService, blockchainService *BlockchainService) *SnapshotService {
+	ss := &SnapshotService{
+		snapshotRepo:      snapshotRepo,
+		walletRepo:        walletRepo,
+		walletService:     walletService,
+		blockchainService: blockchainService,
+		interval:          time.Hour,
+		rawRetention:      24 * time.Hour,
+		hourlyRetention:   7 * 24 * time.Hour,
+		done:              make(chan struct{}),
+	}
+
+	if interval, err := time.ParseDuration(cfg.Interval); err == nil && interval > 0 {
+		ss.interval = interval
+	}
+	if retention, err := time.ParseDuration(cfg.RawRetention); err == nil && retention > 0 {
+		ss.rawRetention = retention
+	}
+	if retention, err := time.ParseDuration(cfg.HourlyRetention); err == nil && retention > 0 {
+		ss.hourlyRetention = retention
+	}
+	if ss.hourlyRetention < ss.rawRetention {
+		ss.hourlyRetention = ss.rawRetention
+	}
+
+	if cfg.Enabled {
+		ss.wg.Add(1)
+		go ss.loop()
+	}
+
+	return ss
+}
+
+// Close 停止定时记录
+func (ss *SnapshotService) Close() {
+	ss.closeOnce.Do(func() {
+		close(ss.done)
+		ss.wg.Wait()
+	})
+}
+
+// loop 在每个与 interval 对齐的时间点记录一次快照，然后降采样
+func (ss *SnapshotService) loop() {
+	defer ss.wg.Done()
+
+	for {
+		next := nextSnapshotTime(time.Now(), ss.interval)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ss.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), ss.interval)
+		if saved, err := ss.Capture(ctx, next); err != nil {
+			log.Printf("balance snapshots: %v", err)
+		} else {
+			log.Printf("balance snapshots: saved %d balances taken at %s", saved, next.Format(time.RFC3339))
+		}
+		cancel()
+
+		if err := ss.Downsample(time.Now()); err != nil {
+			log.Printf("balance snapshots: downsample: %v", err)
+		}
+	}
+}
+
+// nextSnapshotTime 返回 now 之后第一个与 interval 对齐的时间点
+func nextSnapshotTime(now time.Time, interval time.Duration) time.Time {
+	return now.Truncate(interval).Add(interval)
+}
+
+// Capture 记录所有钱包当前的余额，takenAt 作为这一批快照的时间。
+// 每条链先确定一个区块，所有用户的余额都在这个区块读取；读取失败和垃圾 token 的条目不记录
+func (ss *SnapshotService) Capture(ctx context.Context, takenAt time.Time) (int, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	wallets, err := ss.walletRepo.List()
+	if err != nil {
+		return 0, err
+	}
+	if err := ss.walletService.AttachListTokens(wallets); err != nil {
+		return 0, err
+	}
+
+	// 按用户分组，保持钱包的顺序
+	var userIDs []uint
+	userWallets := make(map[uint][]model.Wallet)
+	at := BalanceAt{Blocks: make(map[int]uint64)}
+	for _, wallet := range wallets {
+		if _, exists := userWallets[wallet.UserID]; !exists {
+			userIDs = append(userIDs, wallet.UserID)
+		}
+		userWallets[wallet.UserID] = append(userWallets[wallet.UserID], wallet)
+
+		if _, exists := at.Blocks[wallet.ChainID]; exists || !ss.blockchainService.IsSupportedChain(wallet.ChainID) {
+			continue
+		}
+		// 拿不到最新区块的链在读取余额时再报告错误
+		if head, err := ss.blockchainService.LatestBlock(ctx, wallet.ChainID); err == nil {
+			at.Blocks[wallet.ChainID] = head
+		}
+	}
+
+	saved := 0
+	for _, userID := range userIDs {
+		select {
+		case <-ss.done:
+			return saved, nil
+		default:
+		}
+
+		snapshots, err := ss.captureUser(ctx, userID, userWallets[userID], at, takenAt)
+		if err != nil {
+			log.Printf("balance snapshots: user %d: %v", userID, err)
+			continue
+		}
+		if err := ss.snapshotRepo.Create(snapshots); err != nil {
+			return saved, err
+		}
+		saved += len(snapshots)
+	}
+
+	return saved, nil
+}
+
+// captureUser 读取一个用户所有钱包的余额并转换为快照
+func (ss *SnapshotService) captureUser(ctx context.Context, userID uint, wallets []model.Wallet, at BalanceAt, takenAt time.Time) ([]model.BalanceSnapshot, error) {
+	balances, blocks, err := ss.blockchainService.GetHistoricalBalances(ctx, wallets, at)
+	if err != nil {
+		return nil, err
+	}
+
+	blockTimes := make(map[int]time.Time)
+	for _, block := range blocks {
+		if block.Error == "" {
+			blockTimes[block.ChainID] = block.Timestamp
+		}
+	}
+
+	type walletKey struct {
+		chainID int
+		address string
+	}
+	walletIDs := make(map[walletKey]uint)
+	for _, wallet := range wallets {
+		walletIDs[walletKey{wallet.ChainID, strings.ToLower(wallet.Address)}] = wallet.ID
+	}
+
+	var snapshots []model.BalanceSnapshot
+	for _, balance := range balances {
+		if balance.Spam || (balance.Status != model.BalanceStatusOK && balance.Status != model.BalanceStatusCached) {
+			continue
+		}
+
+		snapshots = append(snapshots, model.BalanceSnapshot{
+			UserID:       userID,
+			WalletID:     walletIDs[walletKey{balance.ChainID, strings.ToLower(balance.WalletAddress)}],
+			ChainID:      balance.ChainID,
+			AssetType:    balance.AssetType,
+			TokenAddress: balance.TokenAddress,
+			Symbol:       balance.Symbol,
+			Decimals:     balance.Decimals,
+			RawBalance:   balance.RawBalance,
+			Balance:      balance.Balance,
+			BlockNumber:  balance.BlockNumber,
+			BlockTime:    blockTimes[balance.ChainID],
+			TakenAt:      takenAt,
+			Resolution:   model.SnapshotResolutionRaw,
+		})
+	}
+	return snapshots, nil
+}
+
+// Downsample 按保留策略合并旧快照
+func (ss *SnapshotService) Downsample(now time.Time) error {
+	hourly, err := ss.snapshotRepo.Downsample(model.SnapshotResolutionRaw, model.SnapshotResolutionHourly, time.Hour, now.Add(-ss.rawRetention))
+	if err != nil {
+		return err
+	}
+	daily, err := ss.snapshotRepo.Downsample(model.SnapshotResolutionHourly, model.SnapshotResolutionDaily, 24*time.Hour, now.Add(-ss.hourlyRetention))
+	if err != nil {
+		return err
+	}
+	if hourly+daily > 0 {
+		log.Printf("balance snapshots: downsampling removed %d snapshots", hourly+daily)
+	}
+	return nil
+}
+
+// WalletHistory 返回钱包在 [from, to] 内的快照
+func (ss *SnapshotService) WalletHistory(walletID uint, from, to time.Time) ([]model.BalanceSnapshot, error) {
+	return ss.snapshotRepo.ListByWallet(walletID, from, to)
+}
+
+// UserHistory 返回用户所有钱包在 [from, to] 内的快照
+func (ss *SnapshotService) UserHistory(userID uint, from, to time.Time) ([]model.BalanceSnapshot, error) {
+	return ss.snapshotRepo.ListByUser(userID, from, to)
+}
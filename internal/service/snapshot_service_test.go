@@ -0,0 +1,19 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextSnapshotTime(t *testing.T) {
+	now := time.Date(2026, 9, 30, 13, 42, 5, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2026, 9, 30, 14, 0, 0, 0, time.UTC), nextSnapshotTime(now, time.Hour))
+	assert.Equal(t, time.Date(2026, 9, 30, 13, 45, 0, 0, time.UTC), nextSnapshotTime(now, 15*time.Minute))
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), nextSnapshotTime(now, 24*time.Hour))
+
+	// 正好在对齐的时间点时取下一个
+	assert.Equal(t, time.Date(2026, 9, 30, 15, 0, 0, 0, time.UTC), nextSnapshotTime(time.Date(2026, 9, 30, 14, 0, 0, 0, time.UTC), time.Hour))
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"slices"
+	"sync"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/internal/model"
+	"wallet-tracker/internal/repository"
+	"wallet-tracker/pkg/blockchain"
+	"wallet-tracker/pkg/spam"
+)
+
+// SpamService 给 token 表中的 token 打垃圾分。管理员核验过的 token 和导入的 Token List 中的 token
+// 是可信的，并作为判断仿冒的参考
+type SpamService struct {
+	tokenRepo         *repository.TokenRepository
+	listRepo          *repository.TokenListRepository
+	blockchainService *BlockchainService
+	scorer            *spam.Scorer
+
+	mu     sync.RWMutex
+	listed map[uint]bool
+}
+
+func NewSpamService(cfg *config.SpamConfig, tokenRepo *repository.TokenRepository, listRepo *repository.TokenListRepository, blockchainService *BlockchainService) (*SpamService, error) {
+	scorer, err := spam.NewScorer(cfg.Allow, cfg.Deny)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpamService{
+		tokenRepo:         tokenRepo,
+		listRepo:          listRepo,
+		blockchainService: blockchainService,
+		scorer:            scorer,
+		listed:            make(map[uint]bool),
+	}, nil
+}
+
+// Reload 重新读取可信 token，核验状态或 Token List 变化后调用
+func (ss *SpamService) Reload() error {
+	tokens, err := ss.tokenRepo.List(0, "")
+	if err != nil {
+		return err
+	}
+	ids, err := ss.listRepo.ListedTokenIDs()
+	if err != nil {
+		return err
+	}
+
+	listed := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		listed[id] = true
+	}
+
+	var known []spam.Token
+	for _, token := range tokens {
+		if token.Status == model.TokenStatusVerified || listed[token.ID] {
+			known = append(known, spam.Token{ChainID: token.ChainID, Address: token.Address, Symbol: token.Symbol})
+		}
+	}
+
+	natives := make(map[int]string)
+	for chainID, chain := range ss.blockchainService.chains {
+		natives[chainID] = chain.NativeSymbol
+	}
+
+	ss.scorer.SetKnownTokens(known, natives)
+
+	ss.mu.Lock()
+	ss.listed = listed
+	ss.mu.Unlock()
+	return nil
+}
+
+// Score 计算 token 的垃圾分并写入 token 的字段，不保存
+func (ss *SpamService) Score(token *model.Token) {
+	ss.mu.RLock()
+	trusted := token.Status == model.TokenStatusVerified || ss.listed[token.ID]
+	ss.mu.RUnlock()
+
+	result := ss.scorer.Score(spam.Token{
+		ChainID:  token.ChainID,
+		Address:  token.Address,
+		Symbol:   token.Symbol,
+		Name:     token.Name,
+		Trusted:  trusted,
+		List:     token.SpamList,
+		Warnings: token.Warnings,
+		Signals:  token.Signals,
+	})
+
+	token.SpamScore = result.Score
+	token.SpamReasons = result.Reasons
+	token.Spam = result.Spam
+}
+
+// RescoreAll 重新读取可信 token 并给所有 token 重新打分，返回评分有变化的 token 数
+func (ss *SpamService) RescoreAll() (int, error) {
+	if err := ss.Reload(); err != nil {
+		return 0, err
+	}
+
+	tokens, err := ss.tokenRepo.List(0, "")
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for i := range tokens {
+		token := &tokens[i]
+		score, spamFlag, reasons := token.SpamScore, token.Spam, token.SpamReasons
+
+		ss.Score(token)
+		if token.SpamScore == score && token.Spam == spamFlag && slices.Equal(token.SpamReasons, reasons) {
+			continue
+		}
+		if _, err := ss.tokenRepo.Update(token); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// RecordSignals 把在钱包转账记录中观察到的行为记录在 token 上并重新打分
+func (ss *SpamService) RecordSignals(token *model.Token, stats *blockchain.TransferStats) (*model.Token, error) {
+	if stats == nil {
+		return token, nil
+	}
+
+	var observed []string
+	if stats.ZeroValue > 0 {
+		observed = append(observed, spam.SignalZeroValueTransfer)
+	}
+	if stats.NonStandard > 0 {
+		observed = append(observed, spam.SignalNonStandardTransfer)
+	}
+	// 只收到过、从未转出过的 token
+	if stats.Incoming > 0 && stats.Outgoing == 0 {
+		observed = append(observed, spam.SignalUnsolicitedTransfer)
+	}
+
+	added := false
+	for _, signal := range observed {
+		if !slices.Contains(token.Signals, signal) {
+			token.Signals = append(token.Signals, signal)
+			added = true
+		}
+	}
+	if !added {
+		return token, nil
+	}
+
+	ss.Score(token)
+	return ss.tokenRepo.Update(token)
+}
+
+// validSpamList 判断管理员设置的名单是否合法，空字符串表示移出名单
+func validSpamList(list string) bool {
+	return list == "" || list == spam.ListAllow || list == spam.ListDeny
+}
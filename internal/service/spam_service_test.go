@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+
+	"wallet-tracker/internal/model"
+	"wallet-tracker/pkg/blockchain"
+	"wallet-tracker/pkg/spam"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpamService_ListedTokensAreReference(t *testing.T) {
+	ls, tokenRepo := newTestTokenListService(t)
+
+	fake, err := tokenRepo.FirstOrCreate(&model.Token{ChainID: 1, Address: "0x1111111111111111111111111111111111111111", Symbol: "USDT", Name: "Tether USD"})
+	require.NoError(t, err)
+
+	// 导入列表后，列表中的 USDT 可信，另一个地址上的 USDT 被视为仿冒
+	_, err = ls.Import([]byte(testTokenList), "upload")
+	require.NoError(t, err)
+
+	fake, err = tokenRepo.GetByID(fake.ID)
+	require.NoError(t, err)
+	assert.True(t, fake.Spam)
+	assert.Equal(t, []string{spam.ReasonImpersonation, spam.ReasonUnverified}, fake.SpamReasons)
+
+	usdt, err := tokenRepo.GetByChainAddress(1, "0xdAC17F958D2ee523a2206206994597C13D831ec7")
+	require.NoError(t, err)
+	assert.False(t, usdt.Spam)
+	assert.Zero(t, usdt.SpamScore)
+}
+
+func TestSpamService_RecordSignals(t *testing.T) {
+	ls, tokenRepo := newTestTokenListService(t)
+	require.NoError(t, ls.spamService.Reload())
+
+	token, err := tokenRepo.FirstOrCreate(&model.Token{ChainID: 1, Address: "0x2222222222222222222222222222222222222222", Symbol: "ABC", Name: "ABC"})
+	require.NoError(t, err)
+
+	token, err = ls.spamService.RecordSignals(token, &blockchain.TransferStats{Incoming: 3, ZeroValue: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{spam.SignalZeroValueTransfer, spam.SignalUnsolicitedTransfer}, token.Signals)
+	// 元数据正常的 token 不会因为地址投毒的零金额转账被标记
+	assert.False(t, token.Spam)
+	assert.NotContains(t, token.SpamReasons, spam.ReasonZeroValueTransfer)
+
+	token, err = tokenRepo.FirstOrCreate(&model.Token{ChainID: 1, Address: "0x4444444444444444444444444444444444444444", Symbol: "ABC", Name: "ABC Airdrop"})
+	require.NoError(t, err)
+	token, err = ls.spamService.RecordSignals(token, &blockchain.TransferStats{Incoming: 1, ZeroValue: 1})
+	require.NoError(t, err)
+	assert.True(t, token.Spam)
+
+	// 管理员加入 allow 名单后不再标记
+	token.SpamList = spam.ListAllow
+	ls.spamService.Score(token)
+	assert.False(t, token.Spam)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"wallet-tracker/internal/model"
+	"wallet-tracker/internal/repository"
+	"wallet-tracker/pkg/tokenlist"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+var ErrTokenListNotNewer = errors.New("token list version is not newer than the imported version")
+
+// TokenListImport 是一次导入的结果，不支持的链上的 token 被跳过
+type TokenListImport struct {
+	List     *model.TokenList `json:"list"`
+	Imported int              `json:"imported"`
+	Skipped  int              `json:"skipped"`
+}
+
+// TokenListService 把 Token List 中的 token 登记到 token 表，并记录列表的版本
+type TokenListService struct {
+	listRepo          *repository.TokenListRepository
+	tokenRepo         *repository.TokenRepository
+	blockchainService *BlockchainService
+	spamService       *SpamService
+}
+
+func NewTokenListService(listRepo *repository.TokenListRepository, tokenRepo *repository.TokenRepository, blockchainService *BlockchainService, spamService *SpamService) *TokenListService {
+	return &TokenListService{
+		listRepo:          listRepo,
+		tokenRepo:         tokenRepo,
+		blockchainService: blockchainService,
+		spamService:       spamService,
+	}
+}
+
+// ImportFile 导入本地的 Token List 文件
+func (ls *TokenListService) ImportFile(path string) (*TokenListImport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ls.Import(data, path)
+}
+
+// Import 校验并导入 Token List。同名列表只接受更高的版本；
+// 已登记的 token 保留原有元数据，只补充缺失的 logo
+func (ls *TokenListService) Import(data []byte, source string) (*TokenListImport, error) {
+	parsed, err := tokenlist.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := ls.listRepo.GetByName(parsed.Name)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		list = &model.TokenList{Name: parsed.Name}
+	case err != nil:
+		return nil, err
+	default:
+		imported := tokenlist.Version{Major: list.VersionMajor, Minor: list.VersionMinor, Patch: list.VersionPatch}
+		if parsed.Version.Compare(imported) <= 0 {
+			return nil, fmt.Errorf("%w: %s %s, imported %s", ErrTokenListNotNewer, parsed.Name, parsed.Version, imported)
+		}
+	}
+
+	result := &TokenListImport{}
+	var tokens []model.Token
+	for _, entry := range parsed.Tokens {
+		// token 表的 decimals 上限与管理员修改的限制一致
+		if !ls.blockchainService.IsSupportedChain(entry.ChainID) || entry.Decimals > 77 {
+			result.Skipped++
+			continue
+		}
+
+		token, err := ls.tokenRepo.FirstOrCreate(&model.Token{
+			ChainID:     entry.ChainID,
+			Address:     common.HexToAddress(entry.Address).Hex(),
+			Symbol:      entry.Symbol,
+			Name:        entry.Name,
+			Decimals:    entry.Decimals,
+			LogoURL:     entry.LogoURI,
+			Status:      model.TokenStatusUnverified,
+			FirstSeenAt: time.Now(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if token.LogoURL == "" && entry.LogoURI != "" {
+			token.LogoURL = entry.LogoURI
+			if token, err = ls.tokenRepo.Update(token); err != nil {
+				return nil, err
+			}
+		}
+
+		tokens = append(tokens, *token)
+	}
+
+	list.Source = source
+	list.Version = parsed.Version.String()
+	list.VersionMajor = parsed.Version.Major
+	list.VersionMinor = parsed.Version.Minor
+	list.VersionPatch = parsed.Version.Patch
+	list.Timestamp = parsed.Timestamp
+	list.LogoURL = parsed.LogoURI
+	list.TokenCount = len(tokens)
+
+	if result.List, err = ls.listRepo.Save(list, tokens); err != nil {
+		return nil, err
+	}
+	result.Imported = len(tokens)
+
+	// 列表中的 token 变为可信，同时成为判断仿冒的参考
+	if _, err := ls.spamService.RescoreAll(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (ls *TokenListService) GetTokenList(listID uint) (*model.TokenList, error) {
+	return ls.listRepo.GetByID(listID)
+}
+
+func (ls *TokenListService) ListTokenLists() ([]model.TokenList, error) {
+	return ls.listRepo.List()
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"testing"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/internal/model"
+	"wallet-tracker/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testTokenList = `{
+	"name": "Test List",
+	"timestamp": "2024-01-01T00:00:00Z",
+	"version": {"major": 1, "minor": 0, "patch": 0},
+	"tokens": [
+		{"chainId": 1, "address": "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", "name": "USD Coin", "symbol": "USDC", "decimals": 6, "logoURI": "https://example.com/usdc.png"},
+		{"chainId": 1, "address": "0xdAC17F958D2ee523a2206206994597C13D831ec7", "name": "Tether USD", "symbol": "USDT", "decimals": 6},
+		{"chainId": 10, "address": "0x0b2C639c533813f4Aa9D7837CAf62653d097Ff85", "name": "USD Coin", "symbol": "USDC", "decimals": 6}
+	]
+}`
+
+func newTestTokenListService(t *testing.T) (*TokenListService, *repository.TokenRepository) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Wallet{}, &model.Token{}, &model.TokenList{}))
+
+	tokenRepo := repository.NewTokenRepository(db)
+	listRepo := repository.NewTokenListRepository(db)
+	bs := &BlockchainService{chains: map[int]config.ChainConfig{1: {ChainID: 1, Name: "Ethereum", NativeSymbol: "ETH"}}}
+	spamService, err := NewSpamService(&config.SpamConfig{}, tokenRepo, listRepo, bs)
+	require.NoError(t, err)
+
+	return NewTokenListService(listRepo, tokenRepo, bs, spamService), tokenRepo
+}
+
+func TestTokenListService_Import(t *testing.T) {
+	ls, tokenRepo := newTestTokenListService(t)
+
+	// 已登记的 token 保留原有元数据，只补充 logo
+	_, err := tokenRepo.FirstOrCreate(&model.Token{ChainID: 1, Address: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", Symbol: "USDC.e", Decimals: 6, Overridden: true})
+	require.NoError(t, err)
+
+	result, err := ls.Import([]byte(testTokenList), "upload")
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 1, result.Skipped)
+	assert.Equal(t, "1.0.0", result.List.Version)
+	assert.Equal(t, 2, result.List.TokenCount)
+
+	usdc, err := tokenRepo.GetByChainAddress(1, "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	require.NoError(t, err)
+	assert.Equal(t, "USDC.e", usdc.Symbol)
+	assert.Equal(t, "https://example.com/usdc.png", usdc.LogoURL)
+
+	usdt, err := tokenRepo.GetByChainAddress(1, "0xdAC17F958D2ee523a2206206994597C13D831ec7")
+	require.NoError(t, err)
+	assert.Equal(t, model.TokenStatusUnverified, usdt.Status)
+}
+
+func TestTokenListService_ImportRequiresNewerVersion(t *testing.T) {
+	ls, _ := newTestTokenListService(t)
+
+	_, err := ls.Import([]byte(testTokenList), "upload")
+	require.NoError(t, err)
+
+	_, err = ls.Import([]byte(testTokenList), "upload")
+	assert.ErrorIs(t, err, ErrTokenListNotNewer)
+
+	newer := []byte(`{"name": "Test List", "timestamp": "2024-02-01T00:00:00Z", "version": {"major": 1, "minor": 1, "patch": 0},
+		"tokens": [{"chainId": 1, "address": "0xdAC17F958D2ee523a2206206994597C13D831ec7", "name": "Tether USD", "symbol": "USDT", "decimals": 6}]}`)
+	result, err := ls.Import(newer, "upload")
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", result.List.Version)
+	assert.Equal(t, 1, result.List.TokenCount)
+
+	lists, err := ls.ListTokenLists()
+	require.NoError(t, err)
+	assert.Len(t, lists, 1)
+}
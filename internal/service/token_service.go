@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wallet-tracker/internal/model"
+	"wallet-tracker/internal/repository"
+	"wallet-tracker/pkg/blockchain"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidTokenStatus   = errors.New("status must be verified or unverified")
+	ErrInvalidTokenDecimals = errors.New("decimals must be between 0 and 77")
+	ErrInvalidSpamList      = errors.New("spam_list must be allow, deny or empty")
+)
+
+// TokenService 维护全局的 token 元数据表：元数据只从链上读取一次，之后所有用户共用
+type TokenService struct {
+	tokenRepo         *repository.TokenRepository
+	blockchainService *BlockchainService
+	spamService       *SpamService
+}
+
+func NewTokenService(tokenRepo *repository.TokenRepository, blockchainService *BlockchainService, spamService *SpamService) *TokenService {
+	return &TokenService{
+		tokenRepo:         tokenRepo,
+		blockchainService: blockchainService,
+		spamService:       spamService,
+	}
+}
+
+// Resolve 返回 token 的元数据；第一次出现的 token 先在链上校验并读取元数据，再登记到表中
+func (ts *TokenService) Resolve(ctx context.Context, chainID int, tokenAddress string) (*model.Token, error) {
+	if !common.IsHexAddress(tokenAddress) {
+		return nil, ErrInvalidTokenAddress
+	}
+	address := common.HexToAddress(tokenAddress).Hex()
+
+	token, err := ts.tokenRepo.GetByChainAddress(chainID, address)
+	if err == nil {
+		if token.Standard != model.TokenStandardERC20 {
+			return nil, ErrNFTCollection
+		}
+		return token, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	metadata, err := ts.blockchainService.LookupToken(ctx, chainID, address)
+	if err != nil {
+		return nil, err
+	}
+
+	token = &model.Token{
+		ChainID:     chainID,
+		Address:     metadata.Address,
+		Symbol:      metadata.Symbol,
+		Name:        metadata.Name,
+		Decimals:    metadata.Decimals,
+		Warnings:    metadata.Warnings,
+		Standard:    model.TokenStandardERC20,
+		Status:      model.TokenStatusUnverified,
+		FirstSeenAt: time.Now(),
+	}
+	ts.spamService.Score(token)
+
+	return ts.tokenRepo.FirstOrCreate(token)
+}
+
+func (ts *TokenService) GetToken(tokenID uint) (*model.Token, error) {
+	return ts.tokenRepo.GetByID(tokenID)
+}
+
+func (ts *TokenService) ListTokens(chainID int, status string) ([]model.Token, error) {
+	return ts.tokenRepo.List(chainID, status)
+}
+
+// TokenOverride 是管理员可以修改的字段，nil 表示不修改
+type TokenOverride struct {
+	Symbol   *string `json:"symbol"`
+	Name     *string `json:"name"`
+	Decimals *int    `json:"decimals"`
+	LogoURL  *string `json:"logo_url"`
+	Status   *string `json:"status"`
+	// allow / deny 名单，空字符串表示移出名单
+	SpamList *string `json:"spam_list"`
+}
+
+// OverrideToken 修改 token 的元数据。修改过 symbol / name / decimals 的 token 标记为 overridden，
+// 修改后重新打垃圾分
+func (ts *TokenService) OverrideToken(tokenID uint, override TokenOverride) (*model.Token, error) {
+	if override.Status != nil && *override.Status != model.TokenStatusVerified && *override.Status != model.TokenStatusUnverified {
+		return nil, ErrInvalidTokenStatus
+	}
+	if override.Decimals != nil && (*override.Decimals < 0 || *override.Decimals > 77) {
+		return nil, ErrInvalidTokenDecimals
+	}
+	if override.SpamList != nil && !validSpamList(*override.SpamList) {
+		return nil, ErrInvalidSpamList
+	}
+
+	token, err := ts.tokenRepo.GetByID(tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 管理员填写的字段不再是默认值，去掉对应的警告
+	if override.Symbol != nil {
+		token.Symbol = *override.Symbol
+		token.Overridden = true
+		token.Warnings = removeWarning(token.Warnings, blockchain.WarningSymbolMissing)
+	}
+	if override.Name != nil {
+		token.Name = *override.Name
+		token.Overridden = true
+		token.Warnings = removeWarning(token.Warnings, blockchain.WarningNameMissing)
+	}
+	if override.Decimals != nil {
+		token.Decimals = *override.Decimals
+		token.Overridden = true
+		token.Warnings = removeWarning(token.Warnings, blockchain.WarningDecimalsMissing)
+	}
+	if override.LogoURL != nil {
+		token.LogoURL = *override.LogoURL
+	}
+	if override.Status != nil {
+		token.Status = *override.Status
+	}
+	if override.SpamList != nil {
+		token.SpamList = *override.SpamList
+	}
+
+	ts.spamService.Score(token)
+	token, err = ts.tokenRepo.Update(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// 核验状态和 symbol 影响其它 token 的仿冒判断
+	if override.Status != nil || override.Symbol != nil {
+		if _, err := ts.spamService.RescoreAll(); err != nil {
+			return nil, err
+		}
+		return ts.tokenRepo.GetByID(tokenID)
+	}
+	return token, nil
+}
+
+// RescoreTokens 给所有 token 重新打垃圾分，返回评分有变化的 token 数
+func (ts *TokenService) RescoreTokens() (int, error) {
+	return ts.spamService.RescoreAll()
+}
+
+// RecordTransferSignals 记录发现 token 时在转账记录中观察到的行为
+func (ts *TokenService) RecordTransferSignals(token *model.Token, stats *blockchain.TransferStats) (*model.Token, error) {
+	return ts.spamService.RecordSignals(token, stats)
+}
+
+// RefreshToken 重新从链上读取元数据，并撤销管理员的修改
+func (ts *TokenService) RefreshToken(ctx context.Context, tokenID uint) (*model.Token, error) {
+	token, err := ts.tokenRepo.GetByID(tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Standard != model.TokenStandardERC20 {
+		info, err := ts.blockchainService.LookupCollection(ctx, token.ChainID, token.Address)
+		if err != nil {
+			return nil, err
+		}
+		token.Standard = info.Standard
+		token.Symbol = info.Symbol
+		token.Name = info.Name
+		token.Overridden = false
+		ts.spamService.Score(token)
+		return ts.tokenRepo.Update(token)
+	}
+
+	metadata, err := ts.blockchainService.LookupToken(ctx, token.ChainID, token.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Symbol = metadata.Symbol
+	token.Name = metadata.Name
+	token.Decimals = metadata.Decimals
+	token.Warnings = metadata.Warnings
+	token.Overridden = false
+	ts.spamService.Score(token)
+
+	return ts.tokenRepo.Update(token)
+}
+
+func removeWarning(warnings []string, warning string) []string {
+	var kept []string
+	for _, w := range warnings {
+		if w != warning {
+			kept = append(kept, w)
+		}
+	}
+	return kept
+}
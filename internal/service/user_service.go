@@ -2,7 +2,10 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"wallet-tracker/internal/model"
@@ -76,3 +79,38 @@ func (us *UserService) Login(username, password string) (string, *model.User, er
 
 	return tokenString, user, nil
 }
+
+func (us *UserService) GetUser(userID uint) (*model.User, error) {
+	return us.userRepo.GetByID(userID)
+}
+
+// Preferences 是要修改的用户设置，为 nil 的字段保持不变
+type Preferences struct {
+	AutoAddTokens *bool
+	BaseCurrency  *string
+}
+
+// 计价货币使用小写的货币代码
+var currencyPattern = regexp.MustCompile(`^[a-z]{3,5}$`)
+
+var ErrInvalidCurrency = errors.New("invalid currency code")
+
+// UpdatePreferences 修改用户设置：发现的 token 是否直接添加到钱包，以及计价货币
+func (us *UserService) UpdatePreferences(userID uint, prefs Preferences) (*model.User, error) {
+	user, err := us.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if prefs.AutoAddTokens != nil {
+		user.AutoAddTokens = *prefs.AutoAddTokens
+	}
+	if prefs.BaseCurrency != nil {
+		currency := strings.ToLower(strings.TrimSpace(*prefs.BaseCurrency))
+		if !currencyPattern.MatchString(currency) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCurrency, *prefs.BaseCurrency)
+		}
+		user.BaseCurrency = currency
+	}
+	return us.userRepo.Update(user)
+}
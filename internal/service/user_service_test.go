@@ -49,6 +49,14 @@ func (m *MockUserRepository) GetByEmail(email string) (*model.User, error) {
 	return args.Get(0).(*model.User), args.Error(1)
 }
 
+func (m *MockUserRepository) Update(user *model.User) (*model.User, error) {
+	args := m.Called(user)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
 func TestUserService_Register(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	service := NewUserService(mockRepo)
@@ -146,3 +154,36 @@ func TestUserService_Login(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestUserService_UpdatePreferences(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := NewUserService(mockRepo)
+
+	user := &model.User{ID: 1, Username: "testuser"}
+	mockRepo.On("GetByID", uint(1)).Return(user, nil).Once()
+	mockRepo.On("Update", mock.MatchedBy(func(u *model.User) bool { return u.AutoAddTokens })).Return(user, nil).Once()
+
+	autoAdd := true
+	updated, err := service.UpdatePreferences(1, Preferences{AutoAddTokens: &autoAdd})
+	assert.NoError(t, err)
+	assert.True(t, updated.AutoAddTokens)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_UpdatePreferences_BaseCurrency(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := NewUserService(mockRepo)
+
+	user := &model.User{ID: 1, Username: "testuser", AutoAddTokens: true, BaseCurrency: "usd"}
+	mockRepo.On("GetByID", uint(1)).Return(user, nil).Twice()
+	mockRepo.On("Update", mock.MatchedBy(func(u *model.User) bool { return u.BaseCurrency == "eur" && u.AutoAddTokens })).Return(user, nil).Once()
+
+	currency := " EUR "
+	_, err := service.UpdatePreferences(1, Preferences{BaseCurrency: &currency})
+	assert.NoError(t, err)
+
+	invalid := "euro$"
+	_, err = service.UpdatePreferences(1, Preferences{BaseCurrency: &invalid})
+	assert.ErrorIs(t, err, ErrInvalidCurrency)
+	mockRepo.AssertExpectations(t)
+}
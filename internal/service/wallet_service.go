@@ -1,6 +1,9 @@
 package service
 
 import (
+	"context"
+	"strings"
+
 	"wallet-tracker/internal/model"
 	"wallet-tracker/internal/repository"
 	"wallet-tracker/pkg/cache"
@@ -8,12 +11,14 @@ import (
 
 type WalletService struct {
 	walletRepo *repository.WalletRepository
-	cache      *cache.RedisClient
+	listRepo   *repository.TokenListRepository
+	cache      cache.Cache
 }
 
-func NewWalletService(walletRepo *repository.WalletRepository, cache *cache.RedisClient) *WalletService {
+func NewWalletService(walletRepo *repository.WalletRepository, listRepo *repository.TokenListRepository, cache cache.Cache) *WalletService {
 	return &WalletService{
 		walletRepo: walletRepo,
+		listRepo:   listRepo,
 		cache:      cache,
 	}
 }
@@ -30,33 +35,84 @@ func (ws *WalletService) AddWallet(userID uint, address string, chainID int, cha
 	return ws.walletRepo.Create(wallet)
 }
 
-func (ws *WalletService) AddTokenToWallet(walletID uint, tokenAddress, symbol, name string, decimals int) (*model.WalletToken, error) {
-	token := &model.WalletToken{
-		WalletID:      walletID,
-		TokenAddress:  tokenAddress,
-		TokenSymbol:   symbol,
-		TokenName:     name,
-		TokenDecimals: decimals,
-		IsActive:      true,
+func (ws *WalletService) AddTokenToWallet(walletID uint, token *model.Token) (*model.WalletToken, error) {
+	walletToken := &model.WalletToken{
+		WalletID:     walletID,
+		TokenID:      token.ID,
+		TokenAddress: token.Address,
+		IsActive:     true,
+	}
+
+	created, err := ws.walletRepo.CreateToken(walletToken)
+	if err != nil {
+		return nil, err
 	}
 
-	return ws.walletRepo.CreateToken(token)
+	created.Token = token
+	return created, nil
+}
+
+func (ws *WalletService) GetWallet(walletID uint) (*model.Wallet, error) {
+	return ws.walletRepo.GetByID(walletID)
 }
 
 func (ws *WalletService) GetUserWallets(userID uint) ([]model.Wallet, error) {
 	return ws.walletRepo.GetByUserID(userID)
 }
 
-func (ws *WalletService) RefreshUserCache(userID uint) error {
-	wallets, err := ws.walletRepo.GetByUserID(userID)
+// SubscribeTokenList 让钱包跟踪列表中的所有 token
+func (ws *WalletService) SubscribeTokenList(walletID, listID uint) error {
+	if _, err := ws.listRepo.GetByID(listID); err != nil {
+		return err
+	}
+	return ws.listRepo.Subscribe(walletID, listID)
+}
+
+func (ws *WalletService) UnsubscribeTokenList(walletID, listID uint) error {
+	return ws.listRepo.Unsubscribe(walletID, listID)
+}
+
+// AttachListTokens 为钱包填充订阅的列表中的 token，已单独添加的 token 不重复填充
+func (ws *WalletService) AttachListTokens(wallets []model.Wallet) error {
+	walletIDs := make([]uint, len(wallets))
+	for i, wallet := range wallets {
+		walletIDs[i] = wallet.ID
+	}
+
+	subscribed, err := ws.listRepo.SubscribedTokens(walletIDs)
 	if err != nil {
 		return err
 	}
 
-	var addresses []string
-	for _, wallet := range wallets {
-		addresses = append(addresses, wallet.Address)
+	byWallet := make(map[uint][]model.Token)
+	for _, token := range subscribed {
+		byWallet[token.WalletID] = append(byWallet[token.WalletID], token.Token)
+	}
+
+	for i := range wallets {
+		wallet := &wallets[i]
+
+		tracked := make(map[string]bool)
+		for _, token := range wallet.Tokens {
+			tracked[strings.ToLower(token.TokenAddress)] = true
+		}
+
+		wallet.ListTokens = nil
+		for _, token := range byWallet[wallet.ID] {
+			if !tracked[strings.ToLower(token.Address)] {
+				wallet.ListTokens = append(wallet.ListTokens, token)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (ws *WalletService) RefreshUserCache(ctx context.Context, userID uint) error {
+	wallets, err := ws.walletRepo.GetByUserID(userID)
+	if err != nil {
+		return err
 	}
 
-	return ws.cache.DeleteUserBalances(addresses)
+	return ws.cache.DeleteUserBalances(ctx, wallets)
 }
@@ -0,0 +1,170 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+var (
+	ErrChainIDMismatch  = errors.New("rpc endpoint serves a different chain")
+	ErrChainUnavailable = errors.New("chain is unavailable")
+)
+
+// 启动校验和重连的参数，测试中可以调小
+var (
+	verifyTimeout     = 5 * time.Second
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = time.Minute
+)
+
+// endpoint 是连接池中的一个 RPC 端点及其健康统计
+type endpoint struct {
+	chainID int
+	url     string
+
+	mu      sync.RWMutex
+	client  *ethclient.Client
+	refused bool
+	lastErr error
+
+	// 健康统计
+	latency             time.Duration
+	errorRate           float64
+	requests            uint64
+	failures            uint64
+	consecutiveFailures int
+	openUntil           time.Time
+	halfOpenTrial       bool
+	blockNumber         uint64
+}
+
+func newEndpoint(chainID int, rawURL string) *endpoint {
+	return &endpoint{chainID: chainID, url: rawURL}
+}
+
+// connect 拨号并校验 eth_chainId，成功后替换当前连接。返回的错误已经去掉了 URL
+func (ep *endpoint) connect() error {
+	return ep.redact(ep.dial())
+}
+
+func (ep *endpoint) dial() error {
+	client, err := ethclient.Dial(ep.url)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		client.Close()
+		return err
+	}
+
+	if chainID.Int64() != int64(ep.chainID) {
+		client.Close()
+		return fmt.Errorf("%w: expected chain %d, got %s", ErrChainIDMismatch, ep.chainID, chainID)
+	}
+
+	ep.mu.Lock()
+	ep.client = client
+	ep.lastErr = nil
+	ep.mu.Unlock()
+
+	return nil
+}
+
+// reconnectLoop 在后台按指数退避重试，直到连接成功、端点被判定为错误链或连接池关闭
+func (ep *endpoint) reconnectLoop(done <-chan struct{}) {
+	delay := reconnectMinDelay
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(delay):
+		}
+
+		err := ep.connect()
+		if err == nil {
+			log.Printf("chain %d: rpc endpoint %s is reachable again", ep.chainID, ep.displayURL())
+			return
+		}
+
+		ep.mu.Lock()
+		ep.lastErr = err
+		if errors.Is(err, ErrChainIDMismatch) {
+			ep.refused = true
+		}
+		ep.mu.Unlock()
+
+		if errors.Is(err, ErrChainIDMismatch) {
+			log.Printf("chain %d: refusing rpc endpoint %s: %v", ep.chainID, ep.displayURL(), err)
+			return
+		}
+
+		delay *= 2
+		if delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+	}
+}
+
+// displayURL 隐藏路径和查询参数，避免在日志和诊断接口中泄露 API key
+func (ep *endpoint) displayURL() string {
+	parsed, err := url.Parse(ep.url)
+	if err != nil || parsed.Host == "" {
+		return "<invalid url>"
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// redactedError 是去掉 URL 的错误，errors.Is/As 仍然作用于原始错误
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redact 把错误信息中的完整 URL 换成 displayURL。拨号和 HTTP 错误会带上完整的 URL，
+// 这些错误会进入日志、诊断接口和返回给调用方的 ErrChainUnavailable
+func (ep *endpoint) redact(err error) error {
+	if err == nil {
+		return err
+	}
+
+	// net/http 会把 URL 中的密码换成 ***，两种写法都要替换
+	forms := []string{ep.url}
+	if parsed, parseErr := url.Parse(ep.url); parseErr == nil {
+		forms = append(forms, parsed.Redacted())
+	}
+
+	msg := err.Error()
+	for _, form := range forms {
+		msg = strings.ReplaceAll(msg, form, ep.displayURL())
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+func (ep *endpoint) close() {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	if ep.client != nil {
+		ep.client.Close()
+	}
+}
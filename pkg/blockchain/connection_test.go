@@ -0,0 +1,63 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func chainConfig(chainID int, url string) *config.ChainConfig {
+	return &config.ChainConfig{
+		ChainID:          chainID,
+		Name:             "Test",
+		NativeSymbol:     "ETH",
+		NativeDecimals:   18,
+		RPCURLs:          []string{url},
+		MulticallAddress: DefaultMulticallAddress,
+	}
+}
+
+func TestNewBlockchainClient_RefusesChainIDMismatch(t *testing.T) {
+	// 配置为 BSC，但端点实际是以太坊主网
+	node := newFakeNode(t, 1)
+
+	client, err := NewBlockchainClient(chainConfig(56, node.URL()), nil)
+	assert.ErrorIs(t, err, ErrChainIDMismatch)
+	assert.Nil(t, client)
+}
+
+func TestNewBlockchainClient_DegradedUntilReachable(t *testing.T) {
+	reconnectMinDelay = 10 * time.Millisecond
+	defer func() { reconnectMinDelay = time.Second }()
+
+	node := newFakeNode(t, 56)
+	node.setNativeBalance(testWallet, big.NewInt(7))
+	node.setDown(true)
+
+	client, err := NewBlockchainClient(chainConfig(56, node.URL()), nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	available, statusErr := client.Status()
+	assert.False(t, available)
+	assert.Error(t, statusErr)
+
+	_, err = client.GetNativeBalance(context.Background(), testWallet)
+	assert.ErrorIs(t, err, ErrChainUnavailable)
+
+	node.setDown(false)
+	assert.Eventually(t, func() bool {
+		available, _ := client.Status()
+		return available
+	}, 2*time.Second, 10*time.Millisecond)
+
+	balance, err := client.GetNativeBalance(context.Background(), testWallet)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(7), balance)
+}
@@ -0,0 +1,264 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ERC1155ABI 包含 ERC-1155 中用到的只读方法和转账事件
+const ERC1155ABI = `[
+    {
+        "inputs":[{"name":"account","type":"address"},{"name":"id","type":"uint256"}],
+        "name":"balanceOf",
+        "outputs":[{"name":"","type":"uint256"}],
+        "stateMutability":"view",
+        "type":"function"
+    },
+    {
+        "inputs":[{"name":"accounts","type":"address[]"},{"name":"ids","type":"uint256[]"}],
+        "name":"balanceOfBatch",
+        "outputs":[{"name":"","type":"uint256[]"}],
+        "stateMutability":"view",
+        "type":"function"
+    },
+    {
+        "inputs":[{"name":"id","type":"uint256"}],
+        "name":"uri",
+        "outputs":[{"name":"","type":"string"}],
+        "stateMutability":"view",
+        "type":"function"
+    },
+    {
+        "anonymous":false,
+        "inputs":[
+            {"indexed":true,"name":"operator","type":"address"},
+            {"indexed":true,"name":"from","type":"address"},
+            {"indexed":true,"name":"to","type":"address"},
+            {"indexed":false,"name":"id","type":"uint256"},
+            {"indexed":false,"name":"value","type":"uint256"}
+        ],
+        "name":"TransferSingle",
+        "type":"event"
+    },
+    {
+        "anonymous":false,
+        "inputs":[
+            {"indexed":true,"name":"operator","type":"address"},
+            {"indexed":true,"name":"from","type":"address"},
+            {"indexed":true,"name":"to","type":"address"},
+            {"indexed":false,"name":"ids","type":"uint256[]"},
+            {"indexed":false,"name":"values","type":"uint256[]"}
+        ],
+        "name":"TransferBatch",
+        "type":"event"
+    }
+]`
+
+// NFT 合约实现的标准，与 token 表中的 standard 一致
+const (
+	StandardERC721  = "erc721"
+	StandardERC1155 = "erc1155"
+)
+
+// ERC-165 接口 ID
+var (
+	InterfaceERC1155            = [4]byte{0xd9, 0xb6, 0x7a, 0x26}
+	InterfaceERC1155MetadataURI = [4]byte{0x0e, 0x89, 0x34, 0x1c}
+)
+
+// ERC-1155 转账事件的签名
+var (
+	TransferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	TransferBatchTopic  = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+// 每次 balanceOfBatch 调用查询的 id 数
+const balanceOfBatchSize = 100
+
+// BigIntsResult 在 Batch.Execute 之后填充
+type BigIntsResult struct {
+	Values []*big.Int
+	Err    error
+}
+
+// BalanceOfBatch 添加一次 ERC-1155 balanceOfBatch 查询，返回 owner 持有的每个 id 的数量
+func (b *Batch) BalanceOfBatch(contractAddress, ownerAddress string, ids []*big.Int) *BigIntsResult {
+	result := &BigIntsResult{}
+
+	owner := common.HexToAddress(ownerAddress)
+	accounts := make([]common.Address, len(ids))
+	for i := range accounts {
+		accounts[i] = owner
+	}
+
+	data, err := b.client.erc1155ABI.Pack("balanceOfBatch", accounts, ids)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	b.calls = append(b.calls, batchCall{
+		call: Call{Target: common.HexToAddress(contractAddress), AllowFailure: true, CallData: data},
+		decode: func(res CallResult) {
+			if !res.Success || len(res.ReturnData) == 0 {
+				result.Err = fmt.Errorf("%w: balanceOfBatch()", ErrCallFailed)
+				return
+			}
+			if err := b.client.erc1155ABI.UnpackIntoInterface(&result.Values, "balanceOfBatch", res.ReturnData); err != nil {
+				result.Err = err
+				return
+			}
+			if len(result.Values) != len(ids) {
+				result.Err = fmt.Errorf("%w: balanceOfBatch() returned %d values for %d ids", ErrCallFailed, len(result.Values), len(ids))
+			}
+		},
+	})
+
+	return result
+}
+
+// URI 添加一次 ERC-1155 uri 查询，返回的 URI 可能包含 {id} 占位符
+func (b *Batch) URI(contractAddress string, id *big.Int) *StringResult {
+	result := &StringResult{}
+
+	data, err := b.client.erc1155ABI.Pack("uri", id)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	b.calls = append(b.calls, batchCall{
+		call: Call{Target: common.HexToAddress(contractAddress), AllowFailure: true, CallData: data},
+		decode: func(res CallResult) {
+			if !res.Success || len(res.ReturnData) == 0 {
+				result.Err = fmt.Errorf("%w: uri()", ErrCallFailed)
+				return
+			}
+			result.Err = b.client.erc1155ABI.UnpackIntoInterface(&result.Value, "uri", res.ReturnData)
+		},
+	})
+
+	return result
+}
+
+// ERC1155Balances 批量查询钱包持有的每个 id 的数量，结果与 ids 一一对应
+func (bc *BlockchainClient) ERC1155Balances(ctx context.Context, contractAddress, ownerAddress string, ids []*big.Int) ([]*big.Int, error) {
+	batch := bc.NewBatch()
+	var results []*BigIntsResult
+	for start := 0; start < len(ids); start += balanceOfBatchSize {
+		end := min(start+balanceOfBatchSize, len(ids))
+		results = append(results, batch.BalanceOfBatch(contractAddress, ownerAddress, ids[start:end]))
+	}
+	if err := batch.Execute(ctx); err != nil {
+		return nil, err
+	}
+
+	balances := make([]*big.Int, 0, len(ids))
+	for _, result := range results {
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		balances = append(balances, result.Values...)
+	}
+	return balances, nil
+}
+
+// ERC1155URIs 批量读取 uri 并替换其中的 {id}，失败的 id 在对应位置返回错误
+func (bc *BlockchainClient) ERC1155URIs(ctx context.Context, contractAddress string, ids []*big.Int) ([]StringResult, error) {
+	batch := bc.NewBatch()
+	results := make([]*StringResult, len(ids))
+	for i, id := range ids {
+		results[i] = batch.URI(contractAddress, id)
+	}
+	if err := batch.Execute(ctx); err != nil {
+		return nil, err
+	}
+
+	uris := make([]StringResult, len(ids))
+	for i, result := range results {
+		uris[i] = *result
+		if result.Err == nil {
+			uris[i].Value = ExpandERC1155URI(result.Value, ids[i])
+		}
+	}
+	return uris, nil
+}
+
+// ExpandERC1155URI 按 ERC-1155 的约定把 {id} 替换为 64 位小写十六进制的 id
+func ExpandERC1155URI(uri string, id *big.Int) string {
+	return strings.ReplaceAll(uri, "{id}", fmt.Sprintf("%064x", id))
+}
+
+// ScanERC1155Transfers 扫描 [fromBlock, toBlock] 内转入钱包的 TransferSingle 和 TransferBatch 日志，
+// 返回钱包收到过的 id。返回错误时结果仍然包含已完成的部分
+func (bc *BlockchainClient) ScanERC1155Transfers(ctx context.Context, contractAddress, ownerAddress string, fromBlock, toBlock, maxRange uint64) (*NFTTransferScan, error) {
+	contract := common.HexToAddress(contractAddress)
+	owner := common.BytesToHash(common.HexToAddress(ownerAddress).Bytes())
+	scan := &NFTTransferScan{Next: fromBlock}
+	seen := make(map[string]bool)
+
+	queries := func(from, to *big.Int) []ethereum.FilterQuery {
+		return []ethereum.FilterQuery{{
+			FromBlock: from,
+			ToBlock:   to,
+			Addresses: []common.Address{contract},
+			// 两个事件的 to 都是第 3 个 indexed 参数
+			Topics: [][]common.Hash{{TransferSingleTopic, TransferBatchTopic}, nil, nil, {owner}},
+		}}
+	}
+
+	next, err := bc.scanLogs(ctx, fromBlock, toBlock, maxRange, queries, func(logs []types.Log) {
+		for _, entry := range logs {
+			for _, id := range bc.erc1155TransferIDs(entry) {
+				if !seen[id.String()] {
+					seen[id.String()] = true
+					scan.TokenIDs = append(scan.TokenIDs, id)
+				}
+			}
+		}
+	})
+	scan.Next = next
+
+	return scan, err
+}
+
+// erc1155TransferIDs 解析转账日志中的 id，格式不对的日志忽略
+func (bc *BlockchainClient) erc1155TransferIDs(entry types.Log) []*big.Int {
+	if len(entry.Topics) != 4 {
+		return nil
+	}
+
+	switch entry.Topics[0] {
+	case TransferSingleTopic:
+		var event struct {
+			Id    *big.Int
+			Value *big.Int
+		}
+		if err := bc.erc1155ABI.UnpackIntoInterface(&event, "TransferSingle", entry.Data); err != nil {
+			return nil
+		}
+		return []*big.Int{event.Id}
+	case TransferBatchTopic:
+		var event struct {
+			Ids    []*big.Int
+			Values []*big.Int
+		}
+		if err := bc.erc1155ABI.UnpackIntoInterface(&event, "TransferBatch", entry.Data); err != nil {
+			return nil
+		}
+		return event.Ids
+	}
+	return nil
+}
+
+func parseERC1155ABI() (abi.ABI, error) {
+	return abi.JSON(strings.NewReader(ERC1155ABI))
+}
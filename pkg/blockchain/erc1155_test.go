@@ -0,0 +1,122 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testItems = "0x00000000000000000000000000000000000000d1"
+
+func erc1155Log(t *testing.T, from, to string, ids, values []int64, blockNumber uint64) types.Log {
+	contractABI, err := parseERC1155ABI()
+	require.NoError(t, err)
+
+	toBigInts := func(values []int64) []*big.Int {
+		result := make([]*big.Int, len(values))
+		for i, v := range values {
+			result[i] = big.NewInt(v)
+		}
+		return result
+	}
+
+	topic := TransferSingleTopic
+	var data []byte
+	if len(ids) == 1 {
+		data, err = contractABI.Events["TransferSingle"].Inputs.NonIndexed().Pack(big.NewInt(ids[0]), big.NewInt(values[0]))
+	} else {
+		topic = TransferBatchTopic
+		data, err = contractABI.Events["TransferBatch"].Inputs.NonIndexed().Pack(toBigInts(ids), toBigInts(values))
+	}
+	require.NoError(t, err)
+
+	return types.Log{
+		Address: common.HexToAddress(testItems),
+		Topics: []common.Hash{
+			topic,
+			common.BytesToHash(common.HexToAddress(from).Bytes()),
+			common.BytesToHash(common.HexToAddress(from).Bytes()),
+			common.BytesToHash(common.HexToAddress(to).Bytes()),
+		},
+		Data:        data,
+		BlockNumber: blockNumber,
+	}
+}
+
+func newMultiTokenNode(t *testing.T) *fakeNode {
+	node := newTestNode(t)
+	wallet := common.HexToAddress(testWallet)
+
+	node.addMultiToken(testItems, &fakeMultiToken{
+		name: "Items",
+		balances: map[string]map[common.Address]int64{
+			"1": {wallet: 5},
+			"2": {wallet: 0},
+			"3": {wallet: 1, common.HexToAddress(testBroken): 7},
+		},
+		uri: "https://example.com/items/{id}.json",
+	})
+	return node
+}
+
+func TestGetNFTCollectionInfo_ERC1155(t *testing.T) {
+	client := newTestClient(t, newMultiTokenNode(t))
+
+	info, err := client.GetNFTCollectionInfo(context.Background(), testItems)
+	require.NoError(t, err)
+	assert.Equal(t, &NFTCollectionInfo{Standard: StandardERC1155, Name: "Items", Symbol: "ITEMS", Metadata: true}, info)
+
+	standard, err := client.NFTStandard(context.Background(), testItems)
+	require.NoError(t, err)
+	assert.Equal(t, StandardERC1155, standard)
+
+	standard, err = client.NFTStandard(context.Background(), testUSDC)
+	require.NoError(t, err)
+	assert.Empty(t, standard)
+}
+
+func TestERC1155Balances(t *testing.T) {
+	client := newTestClient(t, newMultiTokenNode(t))
+
+	// 超过一次 balanceOfBatch 的 id 数时分成多次调用
+	ids := make([]*big.Int, balanceOfBatchSize+1)
+	for i := range ids {
+		ids[i] = big.NewInt(int64(i + 1))
+	}
+
+	balances, err := client.ERC1155Balances(context.Background(), testItems, testWallet, ids)
+	require.NoError(t, err)
+	require.Len(t, balances, len(ids))
+	assert.Equal(t, int64(5), balances[0].Int64())
+	assert.Equal(t, int64(0), balances[1].Int64())
+	assert.Equal(t, int64(1), balances[2].Int64())
+	assert.Equal(t, int64(0), balances[balanceOfBatchSize].Int64())
+}
+
+func TestScanERC1155Transfers(t *testing.T) {
+	node := newMultiTokenNode(t)
+	node.addLog(erc1155Log(t, testBroken, testWallet, []int64{1}, []int64{5}, 10))
+	node.addLog(erc1155Log(t, testBroken, testWallet, []int64{2, 3, 1}, []int64{1, 1, 1}, 20))
+	// 转出的不算
+	node.addLog(erc1155Log(t, testWallet, testBroken, []int64{4}, []int64{1}, 30))
+
+	client := newTestClient(t, node)
+	scan, err := client.ScanERC1155Transfers(context.Background(), testItems, testWallet, 0, 100, 25)
+	require.NoError(t, err)
+	assert.Equal(t, []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}, scan.TokenIDs)
+	assert.Equal(t, uint64(101), scan.Next)
+}
+
+func TestERC1155URIs(t *testing.T) {
+	client := newTestClient(t, newMultiTokenNode(t))
+
+	uris, err := client.ERC1155URIs(context.Background(), testItems, []*big.Int{big.NewInt(314)})
+	require.NoError(t, err)
+	require.Len(t, uris, 1)
+	assert.Equal(t, "https://example.com/items/000000000000000000000000000000000000000000000000000000000000013a.json", uris[0].Value)
+}
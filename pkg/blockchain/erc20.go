@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// 元数据方法缺失或返回无法解析的数据时使用的默认值
+const (
+	DefaultTokenSymbol   = "UNKNOWN"
+	DefaultTokenName     = "Unknown Token"
+	DefaultTokenDecimals = 18
+)
+
+// 元数据使用了默认值时记录的警告
+const (
+	WarningSymbolMissing   = "symbol_missing"
+	WarningNameMissing     = "name_missing"
+	WarningDecimalsMissing = "decimals_missing"
+)
+
+// decodeTokenString 解析 symbol() / name() 的返回值。标准 token 返回 string，
+// MKR 等早期 token 返回 bytes32，两种都无法解析时返回 false
+func decodeTokenString(contractABI abi.ABI, method string, res CallResult) (string, bool) {
+	if !res.Success || len(res.ReturnData) == 0 {
+		return "", false
+	}
+
+	var value string
+	if err := contractABI.UnpackIntoInterface(&value, method, res.ReturnData); err == nil {
+		if value = cleanTokenString(value); value != "" {
+			return value, true
+		}
+	}
+
+	// bytes32：右侧补零的定长字符串
+	if len(res.ReturnData) >= 32 {
+		word := bytes.TrimRight(res.ReturnData[:32], "\x00")
+		if value = cleanTokenString(string(word)); value != "" {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// cleanTokenString 去掉首尾空白和 NUL，非法 UTF-8 视为无法解析
+func cleanTokenString(value string) string {
+	value = strings.TrimSpace(strings.Trim(value, "\x00"))
+	if !utf8.ValidString(value) || strings.ContainsRune(value, 0) {
+		return ""
+	}
+	return value
+}
+
+// decodeTokenDecimals 解析 decimals() 的返回值。部分 token 声明为 uint256，
+// 只要数值不超过 uint8 都接受
+func decodeTokenDecimals(res CallResult) (uint8, bool) {
+	if !res.Success || len(res.ReturnData) < 32 {
+		return 0, false
+	}
+
+	value := new(big.Int).SetBytes(res.ReturnData[:32])
+	if !value.IsUint64() || value.Uint64() > 255 {
+		return 0, false
+	}
+	return uint8(value.Uint64()), true
+}
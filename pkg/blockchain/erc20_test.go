@@ -0,0 +1,109 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// quirkyTokens 是常见的非标准 ERC-20 实现，MKR 的 bytes32 元数据与主网合约一致
+var quirkyTokens = []struct {
+	name         string
+	address      string
+	token        *fakeToken
+	wantSymbol   string
+	wantName     string
+	wantDecimals uint8
+	wantWarnings []string
+}{
+	{
+		name:         "bytes32 symbol and name (MKR)",
+		address:      "0x9f8F72aA9304c8B593d555F12eF6589cC3A579A2",
+		token:        &fakeToken{symbol: "MKR", name: "Maker", decimals: 18, quirks: map[string]string{"symbol": "bytes32", "name": "bytes32"}},
+		wantSymbol:   "MKR",
+		wantName:     "Maker",
+		wantDecimals: 18,
+	},
+	{
+		name:         "missing decimals",
+		address:      "0x0000000000000000000000000000000000000d01",
+		token:        &fakeToken{symbol: "NODEC", name: "No Decimals", quirks: map[string]string{"decimals": "revert"}},
+		wantSymbol:   "NODEC",
+		wantName:     "No Decimals",
+		wantDecimals: DefaultTokenDecimals,
+		wantWarnings: []string{WarningDecimalsMissing},
+	},
+	{
+		name:         "empty name",
+		address:      "0x0000000000000000000000000000000000000d02",
+		token:        &fakeToken{symbol: "ANON", decimals: 8, quirks: map[string]string{"name": "empty"}},
+		wantSymbol:   "ANON",
+		wantName:     DefaultTokenName,
+		wantDecimals: 8,
+		wantWarnings: []string{WarningNameMissing},
+	},
+	{
+		name:         "no metadata at all",
+		address:      "0x0000000000000000000000000000000000000d03",
+		token:        &fakeToken{quirks: map[string]string{"symbol": "revert", "name": "garbage", "decimals": "empty"}},
+		wantSymbol:   DefaultTokenSymbol,
+		wantName:     DefaultTokenName,
+		wantDecimals: DefaultTokenDecimals,
+		wantWarnings: []string{WarningSymbolMissing, WarningNameMissing, WarningDecimalsMissing},
+	},
+}
+
+func newQuirkyNode(t *testing.T) *fakeNode {
+	node := newTestNode(t)
+	for _, tt := range quirkyTokens {
+		tt.token.balances = map[common.Address]*big.Int{common.HexToAddress(testWallet): big.NewInt(42)}
+		node.addToken(tt.address, tt.token)
+	}
+	return node
+}
+
+func TestTokenInfo_QuirkyTokens(t *testing.T) {
+	for _, multicall := range []bool{true, false} {
+		client := newTestClient(t, newQuirkyNode(t))
+		if !multicall {
+			client.multicall = common.Address{}
+		}
+
+		for _, tt := range quirkyTokens {
+			t.Run(tt.name, func(t *testing.T) {
+				info, err := client.GetTokenInfo(context.Background(), tt.address)
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantSymbol, info.Symbol)
+				assert.Equal(t, tt.wantName, info.Name)
+				assert.Equal(t, tt.wantDecimals, info.Decimals)
+				assert.Equal(t, tt.wantWarnings, info.Warnings)
+
+				// 元数据不完整不影响读取余额
+				balance, err := client.GetTokenBalance(context.Background(), tt.address, testWallet)
+				require.NoError(t, err)
+				assert.Equal(t, big.NewInt(42), balance)
+			})
+		}
+	}
+}
+
+func TestDecodeTokenDecimals(t *testing.T) {
+	word := func(value int64) []byte {
+		return common.LeftPadBytes(big.NewInt(value).Bytes(), 32)
+	}
+
+	decimals, ok := decodeTokenDecimals(CallResult{Success: true, ReturnData: word(6)})
+	assert.True(t, ok)
+	assert.Equal(t, uint8(6), decimals)
+
+	_, ok = decodeTokenDecimals(CallResult{Success: true, ReturnData: word(256)})
+	assert.False(t, ok)
+	_, ok = decodeTokenDecimals(CallResult{Success: false, ReturnData: word(6)})
+	assert.False(t, ok)
+	_, ok = decodeTokenDecimals(CallResult{Success: true, ReturnData: []byte{6}})
+	assert.False(t, ok)
+}
@@ -0,0 +1,394 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ERC721ABI 包含 ERC-165 和 ERC-721 中用到的只读方法
+const ERC721ABI = `[
+    {
+        "inputs":[{"name":"interfaceId","type":"bytes4"}],
+        "name":"supportsInterface",
+        "outputs":[{"name":"","type":"bool"}],
+        "stateMutability":"view",
+        "type":"function"
+    },
+    {
+        "inputs":[{"name":"owner","type":"address"}],
+        "name":"balanceOf",
+        "outputs":[{"name":"","type":"uint256"}],
+        "stateMutability":"view",
+        "type":"function"
+    },
+    {
+        "inputs":[{"name":"tokenId","type":"uint256"}],
+        "name":"ownerOf",
+        "outputs":[{"name":"","type":"address"}],
+        "stateMutability":"view",
+        "type":"function"
+    },
+    {
+        "inputs":[{"name":"owner","type":"address"},{"name":"index","type":"uint256"}],
+        "name":"tokenOfOwnerByIndex",
+        "outputs":[{"name":"","type":"uint256"}],
+        "stateMutability":"view",
+        "type":"function"
+    },
+    {
+        "inputs":[{"name":"tokenId","type":"uint256"}],
+        "name":"tokenURI",
+        "outputs":[{"name":"","type":"string"}],
+        "stateMutability":"view",
+        "type":"function"
+    },
+    {
+        "inputs":[],
+        "name":"name",
+        "outputs":[{"name":"","type":"string"}],
+        "stateMutability":"view",
+        "type":"function"
+    },
+    {
+        "inputs":[],
+        "name":"symbol",
+        "outputs":[{"name":"","type":"string"}],
+        "stateMutability":"view",
+        "type":"function"
+    }
+]`
+
+// ERC-165 接口 ID
+var (
+	InterfaceERC721           = [4]byte{0x80, 0xac, 0x58, 0xcd}
+	InterfaceERC721Enumerable = [4]byte{0x78, 0x0e, 0x9d, 0x63}
+	InterfaceERC721Metadata   = [4]byte{0x5b, 0x5e, 0x13, 0x9f}
+)
+
+var ErrNotNFT = errors.New("contract implements neither ERC-721 nor ERC-1155")
+
+// BoolResult 在 Batch.Execute 之后填充
+type BoolResult struct {
+	Value bool
+	Err   error
+}
+
+// BigIntResult 在 Batch.Execute 之后填充
+type BigIntResult struct {
+	Value *big.Int
+	Err   error
+}
+
+// AddressResult 在 Batch.Execute 之后填充
+type AddressResult struct {
+	Address common.Address
+	Err     error
+}
+
+// StringResult 在 Batch.Execute 之后填充
+type StringResult struct {
+	Value string
+	Err   error
+}
+
+// SupportsInterface 添加一次 ERC-165 supportsInterface 查询；没有实现 ERC-165 的合约视为不支持
+func (b *Batch) SupportsInterface(contractAddress string, interfaceID [4]byte) *BoolResult {
+	result := &BoolResult{}
+
+	data, err := b.client.nftABI.Pack("supportsInterface", interfaceID)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	b.calls = append(b.calls, batchCall{
+		call: Call{Target: common.HexToAddress(contractAddress), AllowFailure: true, CallData: data},
+		decode: func(res CallResult) {
+			// ERC-165 要求返回恰好 32 字节的 bool
+			if !res.Success || len(res.ReturnData) != 32 {
+				return
+			}
+			var supported bool
+			if err := b.client.nftABI.UnpackIntoInterface(&supported, "supportsInterface", res.ReturnData); err == nil {
+				result.Value = supported
+			}
+		},
+	})
+
+	return result
+}
+
+// TokenOfOwnerByIndex 添加一次 ERC721Enumerable tokenOfOwnerByIndex 查询
+func (b *Batch) TokenOfOwnerByIndex(contractAddress, ownerAddress string, index int) *BigIntResult {
+	result := &BigIntResult{}
+
+	data, err := b.client.nftABI.Pack("tokenOfOwnerByIndex", common.HexToAddress(ownerAddress), big.NewInt(int64(index)))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	b.calls = append(b.calls, batchCall{
+		call: Call{Target: common.HexToAddress(contractAddress), AllowFailure: true, CallData: data},
+		decode: func(res CallResult) {
+			result.Value, result.Err = b.client.unpackBigInt(b.client.nftABI, "tokenOfOwnerByIndex", res)
+		},
+	})
+
+	return result
+}
+
+// OwnerOf 添加一次 ERC-721 ownerOf 查询；已销毁的 token 返回错误
+func (b *Batch) OwnerOf(contractAddress string, tokenID *big.Int) *AddressResult {
+	result := &AddressResult{}
+
+	data, err := b.client.nftABI.Pack("ownerOf", tokenID)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	b.calls = append(b.calls, batchCall{
+		call: Call{Target: common.HexToAddress(contractAddress), AllowFailure: true, CallData: data},
+		decode: func(res CallResult) {
+			if !res.Success || len(res.ReturnData) == 0 {
+				result.Err = fmt.Errorf("%w: ownerOf()", ErrCallFailed)
+				return
+			}
+			result.Err = b.client.nftABI.UnpackIntoInterface(&result.Address, "ownerOf", res.ReturnData)
+		},
+	})
+
+	return result
+}
+
+// TokenURI 添加一次 ERC-721 tokenURI 查询
+func (b *Batch) TokenURI(contractAddress string, tokenID *big.Int) *StringResult {
+	result := &StringResult{}
+
+	data, err := b.client.nftABI.Pack("tokenURI", tokenID)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	b.calls = append(b.calls, batchCall{
+		call: Call{Target: common.HexToAddress(contractAddress), AllowFailure: true, CallData: data},
+		decode: func(res CallResult) {
+			if !res.Success || len(res.ReturnData) == 0 {
+				result.Err = fmt.Errorf("%w: tokenURI()", ErrCallFailed)
+				return
+			}
+			result.Err = b.client.nftABI.UnpackIntoInterface(&result.Value, "tokenURI", res.ReturnData)
+		},
+	})
+
+	return result
+}
+
+// SupportsInterface 通过 ERC-165 查询合约是否实现了接口
+func (bc *BlockchainClient) SupportsInterface(ctx context.Context, contractAddress string, interfaceID [4]byte) (bool, error) {
+	batch := bc.NewBatch()
+	result := batch.SupportsInterface(contractAddress, interfaceID)
+	if err := batch.Execute(ctx); err != nil {
+		return false, err
+	}
+	return result.Value, result.Err
+}
+
+// NFTCollectionInfo 描述一个 NFT 合约；name / symbol 是可选的，缺失时为空。
+// Enumerable 和 Metadata 是 ERC-721 的扩展，ERC-1155 合约的 Metadata 表示实现了 uri
+type NFTCollectionInfo struct {
+	Standard   string
+	Name       string
+	Symbol     string
+	Enumerable bool
+	Metadata   bool
+}
+
+// NFTStandard 通过 ERC-165 判断合约实现的 NFT 标准，不是 NFT 合约时返回空字符串
+func (bc *BlockchainClient) NFTStandard(ctx context.Context, contractAddress string) (string, error) {
+	batch := bc.NewBatch()
+	erc721 := batch.SupportsInterface(contractAddress, InterfaceERC721)
+	erc1155 := batch.SupportsInterface(contractAddress, InterfaceERC1155)
+	if err := batch.Execute(ctx); err != nil {
+		return "", err
+	}
+
+	switch {
+	case erc721.Value:
+		return StandardERC721, nil
+	case erc1155.Value:
+		return StandardERC1155, nil
+	}
+	return "", nil
+}
+
+// GetNFTCollectionInfo 通过 ERC-165 确认合约实现了 ERC-721 或 ERC-1155，并读取可选扩展和名称
+func (bc *BlockchainClient) GetNFTCollectionInfo(ctx context.Context, contractAddress string) (*NFTCollectionInfo, error) {
+	batch := bc.NewBatch()
+	erc721 := batch.SupportsInterface(contractAddress, InterfaceERC721)
+	enumerable := batch.SupportsInterface(contractAddress, InterfaceERC721Enumerable)
+	metadata := batch.SupportsInterface(contractAddress, InterfaceERC721Metadata)
+	erc1155 := batch.SupportsInterface(contractAddress, InterfaceERC1155)
+	metadataURI := batch.SupportsInterface(contractAddress, InterfaceERC1155MetadataURI)
+	info := batch.TokenInfo(contractAddress)
+
+	if err := batch.Execute(ctx); err != nil {
+		return nil, err
+	}
+
+	var collection *NFTCollectionInfo
+	switch {
+	case erc721.Value:
+		collection = &NFTCollectionInfo{
+			Standard:   StandardERC721,
+			Enumerable: enumerable.Value,
+			Metadata:   metadata.Value,
+		}
+	case erc1155.Value:
+		collection = &NFTCollectionInfo{
+			Standard: StandardERC1155,
+			Metadata: metadataURI.Value,
+		}
+	default:
+		return nil, ErrNotNFT
+	}
+
+	// ERC-1155 没有规定 name / symbol，但很多合约实现了
+	if info.Err == nil {
+		if !slices.Contains(info.Warnings, WarningNameMissing) {
+			collection.Name = info.Name
+		}
+		if !slices.Contains(info.Warnings, WarningSymbolMissing) {
+			collection.Symbol = info.Symbol
+		}
+	}
+	return collection, nil
+}
+
+// EnumerateNFTs 通过 ERC721Enumerable 列出钱包持有的 token，最多 limit 个；同时返回 balanceOf
+func (bc *BlockchainClient) EnumerateNFTs(ctx context.Context, contractAddress, ownerAddress string, limit int) (*big.Int, []*big.Int, error) {
+	batch := bc.NewBatch()
+	balance := batch.BalanceOf(contractAddress, ownerAddress)
+	if err := batch.Execute(ctx); err != nil {
+		return nil, nil, err
+	}
+	if balance.Err != nil {
+		return nil, nil, balance.Err
+	}
+
+	count := limit
+	if balance.Balance.IsInt64() && balance.Balance.Int64() < int64(count) {
+		count = int(balance.Balance.Int64())
+	}
+
+	batch = bc.NewBatch()
+	results := make([]*BigIntResult, count)
+	for i := range results {
+		results[i] = batch.TokenOfOwnerByIndex(contractAddress, ownerAddress, i)
+	}
+	if err := batch.Execute(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	tokenIDs := make([]*big.Int, 0, count)
+	for _, result := range results {
+		if result.Err != nil {
+			return nil, nil, result.Err
+		}
+		tokenIDs = append(tokenIDs, result.Value)
+	}
+	return balance.Balance, tokenIDs, nil
+}
+
+// NFTTransferScan 是一次 NFT 转账日志扫描的结果
+type NFTTransferScan struct {
+	// 钱包收到过的 tokenId，不代表仍然持有
+	TokenIDs []*big.Int
+	// 下一个尚未扫描的区块
+	Next uint64
+}
+
+// ScanNFTTransfers 扫描 [fromBlock, toBlock] 内转入钱包的 ERC-721 Transfer 日志，
+// 用于没有实现 ERC721Enumerable 的合约。返回错误时结果仍然包含已完成的部分
+func (bc *BlockchainClient) ScanNFTTransfers(ctx context.Context, contractAddress, ownerAddress string, fromBlock, toBlock, maxRange uint64) (*NFTTransferScan, error) {
+	contract := common.HexToAddress(contractAddress)
+	owner := common.BytesToHash(common.HexToAddress(ownerAddress).Bytes())
+	scan := &NFTTransferScan{Next: fromBlock}
+	seen := make(map[common.Hash]bool)
+
+	queries := func(from, to *big.Int) []ethereum.FilterQuery {
+		return []ethereum.FilterQuery{{
+			FromBlock: from,
+			ToBlock:   to,
+			Addresses: []common.Address{contract},
+			Topics:    [][]common.Hash{{TransferTopic}, nil, {owner}},
+		}}
+	}
+
+	next, err := bc.scanLogs(ctx, fromBlock, toBlock, maxRange, queries, func(logs []types.Log) {
+		for _, entry := range logs {
+			// ERC-721 的 tokenId 是第 4 个 topic
+			if len(entry.Topics) != 4 || seen[entry.Topics[3]] {
+				continue
+			}
+			seen[entry.Topics[3]] = true
+			scan.TokenIDs = append(scan.TokenIDs, entry.Topics[3].Big())
+		}
+	})
+	scan.Next = next
+
+	return scan, err
+}
+
+// OwnersOf 批量查询 token 的当前持有人；查询失败（例如已销毁）的 token 为零地址
+func (bc *BlockchainClient) OwnersOf(ctx context.Context, contractAddress string, tokenIDs []*big.Int) ([]common.Address, error) {
+	batch := bc.NewBatch()
+	results := make([]*AddressResult, len(tokenIDs))
+	for i, tokenID := range tokenIDs {
+		results[i] = batch.OwnerOf(contractAddress, tokenID)
+	}
+	if err := batch.Execute(ctx); err != nil {
+		return nil, err
+	}
+
+	owners := make([]common.Address, len(tokenIDs))
+	for i, result := range results {
+		if result.Err == nil {
+			owners[i] = result.Address
+		}
+	}
+	return owners, nil
+}
+
+// TokenURIs 批量读取 tokenURI，失败的 token 在对应位置返回错误
+func (bc *BlockchainClient) TokenURIs(ctx context.Context, contractAddress string, tokenIDs []*big.Int) ([]StringResult, error) {
+	batch := bc.NewBatch()
+	results := make([]*StringResult, len(tokenIDs))
+	for i, tokenID := range tokenIDs {
+		results[i] = batch.TokenURI(contractAddress, tokenID)
+	}
+	if err := batch.Execute(ctx); err != nil {
+		return nil, err
+	}
+
+	uris := make([]StringResult, len(tokenIDs))
+	for i, result := range results {
+		uris[i] = *result
+	}
+	return uris, nil
+}
+
+func parseERC721ABI() (abi.ABI, error) {
+	return abi.JSON(strings.NewReader(ERC721ABI))
+}
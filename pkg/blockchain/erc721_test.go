@@ -0,0 +1,105 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPunks = "0x00000000000000000000000000000000000000c1"
+	testApes  = "0x00000000000000000000000000000000000000c2"
+)
+
+func nftTransferLog(contract, from, to string, tokenID int64, blockNumber uint64) types.Log {
+	entry := transferLog(contract, from, to, blockNumber)
+	entry.Topics = append(entry.Topics, common.BigToHash(big.NewInt(tokenID)))
+	entry.Data = nil
+	return entry
+}
+
+func newNFTNode(t *testing.T) *fakeNode {
+	node := newTestNode(t)
+	wallet := common.HexToAddress(testWallet)
+	other := common.HexToAddress(testBroken)
+
+	node.addNFT(testPunks, &fakeNFT{
+		name:       "Punks",
+		enumerable: true,
+		owners:     map[string]common.Address{"7": wallet, "3": wallet, "9": other},
+		uris:       map[string]string{"3": "ipfs://QmPunk/3", "7": "ipfs://QmPunk/7"},
+	})
+	node.addNFT(testApes, &fakeNFT{
+		name:   "Apes",
+		owners: map[string]common.Address{"1": wallet, "2": other},
+		uris:   map[string]string{"1": "https://example.com/apes/1"},
+	})
+	return node
+}
+
+func TestGetNFTCollectionInfo(t *testing.T) {
+	client := newTestClient(t, newNFTNode(t))
+
+	punks, err := client.GetNFTCollectionInfo(context.Background(), testPunks)
+	require.NoError(t, err)
+	assert.Equal(t, &NFTCollectionInfo{Standard: StandardERC721, Name: "Punks", Symbol: "PUNKS", Enumerable: true, Metadata: true}, punks)
+
+	apes, err := client.GetNFTCollectionInfo(context.Background(), testApes)
+	require.NoError(t, err)
+	assert.False(t, apes.Enumerable)
+
+	// ERC-20 合约没有实现 ERC-165
+	_, err = client.GetNFTCollectionInfo(context.Background(), testUSDC)
+	assert.ErrorIs(t, err, ErrNotNFT)
+}
+
+func TestEnumerateNFTs(t *testing.T) {
+	client := newTestClient(t, newNFTNode(t))
+
+	balance, tokenIDs, err := client.EnumerateNFTs(context.Background(), testPunks, testWallet, 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), balance.Int64())
+	assert.Equal(t, []*big.Int{big.NewInt(3), big.NewInt(7)}, tokenIDs)
+
+	// 超过上限的部分不枚举
+	_, tokenIDs, err = client.EnumerateNFTs(context.Background(), testPunks, testWallet, 1)
+	require.NoError(t, err)
+	assert.Len(t, tokenIDs, 1)
+}
+
+func TestScanNFTTransfers(t *testing.T) {
+	node := newNFTNode(t)
+	node.addLog(nftTransferLog(testApes, testBroken, testWallet, 1, 10))
+	node.addLog(nftTransferLog(testApes, testBroken, testWallet, 2, 20))
+	node.addLog(nftTransferLog(testApes, testWallet, testBroken, 2, 30))
+	// 其它合约和 ERC-20 转账被忽略
+	node.addLog(nftTransferLog(testPunks, testBroken, testWallet, 5, 40))
+	node.addLog(transferLog(testApes, testBroken, testWallet, 50))
+
+	client := newTestClient(t, node)
+	scan, err := client.ScanNFTTransfers(context.Background(), testApes, testWallet, 0, 100, 25)
+	require.NoError(t, err)
+	assert.Equal(t, []*big.Int{big.NewInt(1), big.NewInt(2)}, scan.TokenIDs)
+	assert.Equal(t, uint64(101), scan.Next)
+
+	// 收到过的 token 需要用 ownerOf 确认是否仍然持有
+	owners, err := client.OwnersOf(context.Background(), testApes, append(scan.TokenIDs, big.NewInt(99)))
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{common.HexToAddress(testWallet), common.HexToAddress(testBroken), {}}, owners)
+}
+
+func TestTokenURIs(t *testing.T) {
+	client := newTestClient(t, newNFTNode(t))
+
+	uris, err := client.TokenURIs(context.Background(), testPunks, []*big.Int{big.NewInt(3), big.NewInt(9)})
+	require.NoError(t, err)
+	require.Len(t, uris, 2)
+	assert.NoError(t, uris[0].Err)
+	assert.Equal(t, "ipfs://QmPunk/3", uris[0].Value)
+	assert.ErrorIs(t, uris[1].Err, ErrCallFailed)
+}
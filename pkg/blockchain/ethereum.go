@@ -2,9 +2,12 @@ package blockchain
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"strings"
 
+	"wallet-tracker/internal/config"
+
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
@@ -44,123 +47,180 @@ const ERC20ABI = `[
 ]`
 
 type BlockchainClient struct {
-	client *ethclient.Client
-	abi    abi.ABI
+	chainID int
+	pool    *Pool
+	// 读取历史状态的归档节点，未配置时使用 pool
+	archive      *Pool
+	abi          abi.ABI
+	nftABI       abi.ABI
+	erc1155ABI   abi.ABI
+	multicallABI abi.ABI
+	feedABI      abi.ABI
+	multicall    common.Address
+	blockTimes   blockTimes
 }
 
-func NewBlockchainClient(rpcURL string) (*BlockchainClient, error) {
-	client, err := ethclient.Dial(rpcURL)
+// NewBlockchainClient 为一条链的所有 RPC 端点建立连接池。所有端点都返回错误的 chain ID 时拒绝该链；
+// 端点暂时不可达时以降级状态返回，并在后台重连
+func NewBlockchainClient(cfg *config.ChainConfig, poolCfg *config.RPCPoolConfig) (*BlockchainClient, error) {
+	contractABI, err := abi.JSON(strings.NewReader(ERC20ABI))
 	if err != nil {
 		return nil, err
 	}
 
-	contractABI, err := abi.JSON(strings.NewReader(ERC20ABI))
+	nftABI, err := parseERC721ABI()
 	if err != nil {
 		return nil, err
 	}
 
-	return &BlockchainClient{
-		client: client,
-		abi:    contractABI,
-	}, nil
-}
+	erc1155ABI, err := parseERC1155ABI()
+	if err != nil {
+		return nil, err
+	}
 
-func (bc *BlockchainClient) GetTokenBalance(tokenAddress, walletAddress string) (*big.Int, error) {
-	tokenAddr := common.HexToAddress(tokenAddress)
-	walletAddr := common.HexToAddress(walletAddress)
+	multicallABI, err := parseMulticallABI()
+	if err != nil {
+		return nil, err
+	}
 
-	data, err := bc.abi.Pack("balanceOf", walletAddr)
+	feedABI, err := parseAggregatorV3ABI()
 	if err != nil {
 		return nil, err
 	}
 
-	msg := ethereum.CallMsg{
-		To:   &tokenAddr,
-		Data: data,
+	var multicall common.Address
+	if cfg.MulticallAddress != "" {
+		multicall = common.HexToAddress(cfg.MulticallAddress)
 	}
 
-	result, err := bc.client.CallContract(context.Background(), msg, nil)
+	pool, err := NewPool(cfg, poolCfg)
 	if err != nil {
 		return nil, err
 	}
 
-	balance := new(big.Int)
-	if err := bc.abi.UnpackIntoInterface(&balance, "balanceOf", result); err != nil {
-		return nil, err
+	var archive *Pool
+	if len(cfg.ArchiveRPCURLs) > 0 {
+		archiveCfg := *cfg
+		archiveCfg.RPCURLs = cfg.ArchiveRPCURLs
+		if archive, err = NewPool(&archiveCfg, poolCfg); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("archive rpc: %w", err)
+		}
 	}
 
-	return balance, nil
+	return &BlockchainClient{
+		chainID:      cfg.ChainID,
+		pool:         pool,
+		archive:      archive,
+		abi:          contractABI,
+		nftABI:       nftABI,
+		erc1155ABI:   erc1155ABI,
+		multicallABI: multicallABI,
+		feedABI:      feedABI,
+		multicall:    multicall,
+	}, nil
 }
 
-func (bc *BlockchainClient) GetTokenInfo(tokenAddress string) (string, string, uint8, error) {
-	tokenAddr := common.HexToAddress(tokenAddress)
+// callContract 通过连接池执行 eth_call，block 为 nil 时读取最新状态
+func (bc *BlockchainClient) callContract(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+	var result []byte
+	err := bc.poolAt(block).Do(ctx, func(ctx context.Context, client *ethclient.Client) error {
+		var err error
+		result, err = client.CallContract(ctx, msg, block)
+		return err
+	})
+	return result, historicalError(err, block)
+}
 
-	// 获取 symbol
-	symbolData, err := bc.abi.Pack("symbol")
-	if err != nil {
-		return "", "", 0, err
-	}
+// balanceAt 通过连接池执行 eth_getBalance，block 为 nil 时读取最新状态
+func (bc *BlockchainClient) balanceAt(ctx context.Context, address common.Address, block *big.Int) (*big.Int, error) {
+	var balance *big.Int
+	err := bc.poolAt(block).Do(ctx, func(ctx context.Context, client *ethclient.Client) error {
+		var err error
+		balance, err = client.BalanceAt(ctx, address, block)
+		return err
+	})
+	return balance, historicalError(err, block)
+}
 
-	symbolMsg := ethereum.CallMsg{
-		To:   &tokenAddr,
-		Data: symbolData,
-	}
+// codeAt 通过连接池执行 eth_getCode，block 为 nil 时读取最新状态
+func (bc *BlockchainClient) codeAt(ctx context.Context, address common.Address, block *big.Int) ([]byte, error) {
+	var code []byte
+	err := bc.poolAt(block).Do(ctx, func(ctx context.Context, client *ethclient.Client) error {
+		var err error
+		code, err = client.CodeAt(ctx, address, block)
+		return err
+	})
+	return code, historicalError(err, block)
+}
 
-	symbolResult, err := bc.client.CallContract(context.Background(), symbolMsg, nil)
+// IsContract 通过 eth_getCode 判断地址上是否部署了合约
+func (bc *BlockchainClient) IsContract(ctx context.Context, address string) (bool, error) {
+	code, err := bc.codeAt(ctx, common.HexToAddress(address), nil)
 	if err != nil {
-		return "", "", 0, err
+		return false, err
 	}
+	return len(code) > 0, nil
+}
 
-	var symbol string
-	if err := bc.abi.UnpackIntoInterface(&symbol, "symbol", symbolResult); err != nil {
-		return "", "", 0, err
-	}
+// Status 返回链是否可用，以及不可用时的原因
+func (bc *BlockchainClient) Status() (bool, error) {
+	return bc.pool.Available()
+}
+
+// EndpointStatus 返回连接池中各端点的健康状况
+func (bc *BlockchainClient) EndpointStatus() []EndpointStatus {
+	return bc.pool.Status()
+}
+
+func (bc *BlockchainClient) GetTokenBalance(ctx context.Context, tokenAddress, walletAddress string) (*big.Int, error) {
+	tokenAddr := common.HexToAddress(tokenAddress)
+	walletAddr := common.HexToAddress(walletAddress)
 
-	// 获取 name
-	nameData, err := bc.abi.Pack("name")
+	data, err := bc.abi.Pack("balanceOf", walletAddr)
 	if err != nil {
-		return "", "", 0, err
+		return nil, err
 	}
 
-	nameMsg := ethereum.CallMsg{
+	msg := ethereum.CallMsg{
 		To:   &tokenAddr,
-		Data: nameData,
+		Data: data,
 	}
 
-	nameResult, err := bc.client.CallContract(context.Background(), nameMsg, nil)
+	result, err := bc.callContract(ctx, msg, nil)
 	if err != nil {
-		return "", "", 0, err
+		return nil, err
 	}
 
-	var name string
-	if err := bc.abi.UnpackIntoInterface(&name, "name", nameResult); err != nil {
-		return "", "", 0, err
-	}
+	// 空返回值说明地址上没有 balanceOf
+	return bc.unpackBigInt(bc.abi, "balanceOf", CallResult{Success: true, ReturnData: result})
+}
 
-	// 获取 decimals
-	decimalsData, err := bc.abi.Pack("decimals")
-	if err != nil {
-		return "", "", 0, err
-	}
+// GetNativeBalance 查询地址持有的链原生币余额（wei）
+func (bc *BlockchainClient) GetNativeBalance(ctx context.Context, walletAddress string) (*big.Int, error) {
+	walletAddr := common.HexToAddress(walletAddress)
 
-	decimalsMsg := ethereum.CallMsg{
-		To:   &tokenAddr,
-		Data: decimalsData,
-	}
+	return bc.balanceAt(ctx, walletAddr, nil)
+}
 
-	decimalsResult, err := bc.client.CallContract(context.Background(), decimalsMsg, nil)
-	if err != nil {
-		return "", "", 0, err
-	}
+// GetTokenInfo 读取 token 元数据，缺失或无法解析的方法使用默认值并记录在 Warnings 中
+func (bc *BlockchainClient) GetTokenInfo(ctx context.Context, tokenAddress string) (*TokenInfoResult, error) {
+	batch := bc.NewBatch()
+	info := batch.TokenInfo(tokenAddress)
 
-	var decimals uint8
-	if err := bc.abi.UnpackIntoInterface(&decimals, "decimals", decimalsResult); err != nil {
-		return "", "", 0, err
+	if err := batch.Execute(ctx); err != nil {
+		return nil, err
+	}
+	if info.Err != nil {
+		return nil, info.Err
 	}
 
-	return symbol, name, decimals, nil
+	return info, nil
 }
 
 func (bc *BlockchainClient) Close() {
-	bc.client.Close()
+	bc.pool.Close()
+	if bc.archive != nil {
+		bc.archive.Close()
+	}
 }
@@ -0,0 +1,662 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeToken 模拟一个 ERC-20 合约
+type fakeToken struct {
+	symbol   string
+	name     string
+	decimals uint8
+	balances map[common.Address]*big.Int
+	// quirks 模拟非标准实现，按方法名配置：
+	// bytes32 以 bytes32 返回字符串，revert 调用失败，empty 返回空数据，garbage 返回无法解析的数据
+	quirks map[string]string
+}
+
+// fakeNFT 模拟一个 ERC-721 合约，owners 以十进制 tokenId 为键
+type fakeNFT struct {
+	name       string
+	enumerable bool
+	owners     map[string]common.Address
+	uris       map[string]string
+}
+
+// fakeMultiToken 模拟一个 ERC-1155 合约，balances 以十进制 id 为键
+type fakeMultiToken struct {
+	name     string
+	balances map[string]map[common.Address]int64
+	uri      string
+}
+
+// fakeFeed 模拟一个 Chainlink 价格源，history 按区块覆盖价格
+type fakeFeed struct {
+	decimals  uint8
+	answer    int64
+	updatedAt int64
+	history   map[uint64]int64
+}
+
+// fakeNode 是一个最小化的 JSON-RPC 节点，用于在测试中替代真实 RPC
+type fakeNode struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu          sync.Mutex
+	down        bool
+	delay       time.Duration
+	hits        int
+	chainID     int64
+	blockNumber uint64
+	tokens      map[common.Address]*fakeToken
+	nfts        map[common.Address]*fakeNFT
+	multiTokens map[common.Address]*fakeMultiToken
+	feeds       map[common.Address]*fakeFeed
+	native      map[common.Address]*big.Int
+	calls       map[string]int
+	// logs 供 eth_getLogs 查询；logRangeLimit 大于 0 时拒绝超过该区块数的查询
+	logs          []types.Log
+	logRangeLimit uint64
+	// 区块 n 的时间戳是 genesisTime + n*blockInterval；早于 prunedBelow 的状态已裁剪，模拟非归档节点
+	genesisTime   uint64
+	blockInterval uint64
+	prunedBelow   uint64
+	// nativeHistory 按区块覆盖原生币余额，atBlock 是当前调用读取的区块
+	nativeHistory map[uint64]map[common.Address]*big.Int
+	atBlock       uint64
+
+	erc20ABI     abi.ABI
+	nftABI       abi.ABI
+	erc1155ABI   abi.ABI
+	multicallABI abi.ABI
+	feedABI      abi.ABI
+}
+
+type rpcRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params []interface{}   `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+func newFakeNode(t *testing.T, chainID int64) *fakeNode {
+	erc20ABI, err := abi.JSON(strings.NewReader(ERC20ABI))
+	require.NoError(t, err)
+	nftABI, err := parseERC721ABI()
+	require.NoError(t, err)
+	erc1155ABI, err := parseERC1155ABI()
+	require.NoError(t, err)
+	multicallABI, err := parseMulticallABI()
+	require.NoError(t, err)
+	feedABI, err := parseAggregatorV3ABI()
+	require.NoError(t, err)
+
+	n := &fakeNode{
+		t:             t,
+		chainID:       chainID,
+		blockNumber:   100,
+		tokens:        make(map[common.Address]*fakeToken),
+		nfts:          make(map[common.Address]*fakeNFT),
+		multiTokens:   make(map[common.Address]*fakeMultiToken),
+		feeds:         make(map[common.Address]*fakeFeed),
+		native:        make(map[common.Address]*big.Int),
+		calls:         make(map[string]int),
+		genesisTime:   1700000000,
+		blockInterval: 12,
+		nativeHistory: make(map[uint64]map[common.Address]*big.Int),
+		erc20ABI:      erc20ABI,
+		nftABI:        nftABI,
+		erc1155ABI:    erc1155ABI,
+		multicallABI:  multicallABI,
+		feedABI:       feedABI,
+	}
+	n.server = httptest.NewServer(http.HandlerFunc(n.serveHTTP))
+	t.Cleanup(n.server.Close)
+
+	return n
+}
+
+func (n *fakeNode) URL() string {
+	return n.server.URL
+}
+
+func (n *fakeNode) addToken(address string, token *fakeToken) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.tokens[common.HexToAddress(address)] = token
+}
+
+func (n *fakeNode) addNFT(address string, nft *fakeNFT) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.nfts[common.HexToAddress(address)] = nft
+}
+
+func (n *fakeNode) addMultiToken(address string, token *fakeMultiToken) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.multiTokens[common.HexToAddress(address)] = token
+}
+
+func (n *fakeNode) addFeed(address string, feed *fakeFeed) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.feeds[common.HexToAddress(address)] = feed
+}
+
+func (n *fakeNode) setNativeBalance(address string, balance *big.Int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.native[common.HexToAddress(address)] = balance
+}
+
+// setNativeBalanceAt 设置地址在某个区块的原生币余额
+func (n *fakeNode) setNativeBalanceAt(address string, blockNumber uint64, balance *big.Int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.nativeHistory[blockNumber] == nil {
+		n.nativeHistory[blockNumber] = make(map[common.Address]*big.Int)
+	}
+	n.nativeHistory[blockNumber][common.HexToAddress(address)] = balance
+}
+
+func (n *fakeNode) setPrunedBelow(blockNumber uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.prunedBelow = blockNumber
+}
+
+// setDown 让节点对所有请求返回 503
+func (n *fakeNode) setDown(down bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.down = down
+}
+
+// setDelay 为每个请求增加固定延迟
+func (n *fakeNode) setDelay(delay time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.delay = delay
+}
+
+// addLog 添加一条日志，logIndex 按添加顺序编号，与测试中相同的交易哈希一起唯一标识日志
+func (n *fakeNode) addLog(entry types.Log) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	entry.Index = uint(len(n.logs))
+	n.logs = append(n.logs, entry)
+}
+
+func (n *fakeNode) setLogRangeLimit(limit uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.logRangeLimit = limit
+}
+
+func (n *fakeNode) setBlockNumber(blockNumber uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.blockNumber = blockNumber
+}
+
+// hitCount 返回收到的 HTTP 请求数，包括宕机期间被拒绝的请求
+func (n *fakeNode) hitCount() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.hits
+}
+
+func (n *fakeNode) callCount(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+func (n *fakeNode) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	n.hits++
+	down := n.down
+	delay := n.delay
+	n.mu.Unlock()
+
+	time.Sleep(delay)
+	if down {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n.mu.Lock()
+	n.calls[req.Method]++
+	result, rpcErr := n.handle(req)
+	n.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr})
+}
+
+func (n *fakeNode) handle(req rpcRequest) (interface{}, *rpcError) {
+	switch req.Method {
+	case "eth_chainId":
+		return hexutil.EncodeBig(big.NewInt(n.chainID)), nil
+	case "eth_blockNumber":
+		return hexutil.EncodeUint64(n.blockNumber), nil
+	case "eth_getBlockByNumber":
+		number := n.blockParam(req.Params[0])
+		if number > n.blockNumber {
+			return json.RawMessage("null"), nil
+		}
+		return &types.Header{
+			Number:     new(big.Int).SetUint64(number),
+			Time:       n.genesisTime + number*n.blockInterval,
+			Difficulty: big.NewInt(0),
+		}, nil
+	case "eth_getCode":
+		if rpcErr := n.checkState(req.Params[1]); rpcErr != nil {
+			return nil, rpcErr
+		}
+		address := common.HexToAddress(req.Params[0].(string))
+		_, isNFT := n.nfts[address]
+		_, isMultiToken := n.multiTokens[address]
+		if _, exists := n.tokens[address]; exists || isNFT || isMultiToken || address == common.HexToAddress(DefaultMulticallAddress) {
+			return "0x6080", nil
+		}
+		return "0x", nil
+	case "eth_getBalance":
+		if rpcErr := n.checkState(req.Params[1]); rpcErr != nil {
+			return nil, rpcErr
+		}
+		address := common.HexToAddress(req.Params[0].(string))
+		return hexutil.EncodeBig(n.nativeBalance(address)), nil
+	case "eth_call":
+		if rpcErr := n.checkState(req.Params[1]); rpcErr != nil {
+			return nil, rpcErr
+		}
+		arg := req.Params[0].(map[string]interface{})
+		to := common.HexToAddress(arg["to"].(string))
+		input, _ := arg["input"].(string)
+		output, ok := n.execute(to, hexutil.MustDecode(input))
+		if !ok {
+			return nil, &rpcError{Code: 3, Message: "execution reverted"}
+		}
+		return hexutil.Encode(output), nil
+	case "eth_getLogs":
+		return n.filterLogs(req.Params[0].(map[string]interface{}))
+	default:
+		return nil, &rpcError{Code: -32601, Message: fmt.Sprintf("method %s not found", req.Method)}
+	}
+}
+
+// blockParam 解析区块参数，latest 等标签按最新区块处理
+func (n *fakeNode) blockParam(param interface{}) uint64 {
+	if tag, ok := param.(string); ok && strings.HasPrefix(tag, "0x") {
+		return hexutil.MustDecodeUint64(tag)
+	}
+	return n.blockNumber
+}
+
+// checkState 记录本次调用读取的区块；区块状态已裁剪时返回与 geth 相同的错误
+func (n *fakeNode) checkState(param interface{}) *rpcError {
+	n.atBlock = n.blockParam(param)
+	if n.atBlock < n.prunedBelow {
+		return &rpcError{Code: -32000, Message: "missing trie node 0000000000000000000000000000000000000000000000000000000000000000 (path ) state is not available"}
+	}
+	return nil
+}
+
+// filterLogs 按区块范围和 topic 过滤日志，与节点的 eth_getLogs 语义一致
+func (n *fakeNode) filterLogs(filter map[string]interface{}) (interface{}, *rpcError) {
+	from := hexutil.MustDecodeUint64(filter["fromBlock"].(string))
+	to := hexutil.MustDecodeUint64(filter["toBlock"].(string))
+	if n.logRangeLimit > 0 && to-from+1 > n.logRangeLimit {
+		return nil, &rpcError{Code: -32005, Message: "query returned more than 10000 results"}
+	}
+
+	var addresses []common.Address
+	switch v := filter["address"].(type) {
+	case string:
+		addresses = append(addresses, common.HexToAddress(v))
+	case []interface{}:
+		for _, item := range v {
+			addresses = append(addresses, common.HexToAddress(item.(string)))
+		}
+	}
+
+	topics, _ := filter["topics"].([]interface{})
+	matches := func(entry types.Log) bool {
+		if len(addresses) > 0 {
+			found := false
+			for _, address := range addresses {
+				if address == entry.Address {
+					found = true
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		for i, topic := range topics {
+			var allowed []string
+			switch v := topic.(type) {
+			case nil:
+				continue
+			case string:
+				allowed = []string{v}
+			case []interface{}:
+				for _, item := range v {
+					allowed = append(allowed, item.(string))
+				}
+			}
+			if i >= len(entry.Topics) {
+				return false
+			}
+
+			found := false
+			for _, a := range allowed {
+				if common.HexToHash(a) == entry.Topics[i] {
+					found = true
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+
+	result := []types.Log{}
+	for _, entry := range n.logs {
+		if entry.BlockNumber >= from && entry.BlockNumber <= to && matches(entry) {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+func (n *fakeNode) nativeBalance(address common.Address) *big.Int {
+	if balance, exists := n.nativeHistory[n.atBlock][address]; exists {
+		return balance
+	}
+	if balance, exists := n.native[address]; exists {
+		return balance
+	}
+	return big.NewInt(0)
+}
+
+// execute 模拟一次合约调用，返回值和是否成功
+func (n *fakeNode) execute(to common.Address, input []byte) ([]byte, bool) {
+	if len(input) < 4 {
+		return nil, false
+	}
+
+	if to == common.HexToAddress(DefaultMulticallAddress) {
+		return n.executeMulticall(input)
+	}
+
+	if nft, exists := n.nfts[to]; exists {
+		return n.executeNFT(nft, input)
+	}
+
+	if token, exists := n.multiTokens[to]; exists {
+		return n.executeMultiToken(token, input)
+	}
+
+	if feed, exists := n.feeds[to]; exists {
+		return n.executeFeed(feed, input)
+	}
+
+	token, exists := n.tokens[to]
+	if !exists {
+		// 没有合约代码的地址返回空数据
+		return nil, true
+	}
+
+	method, err := n.erc20ABI.MethodById(input[:4])
+	if err != nil {
+		return nil, false
+	}
+
+	switch token.quirks[method.Name] {
+	case "revert":
+		return nil, false
+	case "empty":
+		return nil, true
+	case "garbage":
+		return []byte{0x01, 0x02, 0x03}, true
+	case "bytes32":
+		var word [32]byte
+		value := token.symbol
+		if method.Name == "name" {
+			value = token.name
+		}
+		copy(word[:], value)
+		return word[:], true
+	}
+
+	var output []byte
+	switch method.Name {
+	case "balanceOf":
+		args, err := method.Inputs.Unpack(input[4:])
+		require.NoError(n.t, err)
+		balance, exists := token.balances[args[0].(common.Address)]
+		if !exists {
+			balance = big.NewInt(0)
+		}
+		output, err = method.Outputs.Pack(balance)
+		require.NoError(n.t, err)
+	case "symbol":
+		output, err = method.Outputs.Pack(token.symbol)
+	case "name":
+		output, err = method.Outputs.Pack(token.name)
+	case "decimals":
+		output, err = method.Outputs.Pack(token.decimals)
+	}
+	require.NoError(n.t, err)
+
+	return output, true
+}
+
+// executeNFT 模拟 ERC-721 合约的只读方法
+func (n *fakeNode) executeNFT(nft *fakeNFT, input []byte) ([]byte, bool) {
+	method, err := n.nftABI.MethodById(input[:4])
+	if err != nil {
+		return nil, false
+	}
+	args, err := method.Inputs.Unpack(input[4:])
+	require.NoError(n.t, err)
+
+	// 按 tokenId 排序后的持有列表，用于 tokenOfOwnerByIndex
+	owned := func(owner common.Address) []*big.Int {
+		var ids []*big.Int
+		for id, o := range nft.owners {
+			if o == owner {
+				value, _ := new(big.Int).SetString(id, 10)
+				ids = append(ids, value)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i].Cmp(ids[j]) < 0 })
+		return ids
+	}
+
+	var output []byte
+	switch method.Name {
+	case "supportsInterface":
+		id := args[0].([4]byte)
+		supported := id == InterfaceERC721 || id == InterfaceERC721Metadata || (id == InterfaceERC721Enumerable && nft.enumerable)
+		output, err = method.Outputs.Pack(supported)
+	case "balanceOf":
+		output, err = method.Outputs.Pack(big.NewInt(int64(len(owned(args[0].(common.Address))))))
+	case "ownerOf":
+		owner, exists := nft.owners[args[0].(*big.Int).String()]
+		if !exists {
+			return nil, false
+		}
+		output, err = method.Outputs.Pack(owner)
+	case "tokenOfOwnerByIndex":
+		ids := owned(args[0].(common.Address))
+		index := int(args[1].(*big.Int).Int64())
+		if !nft.enumerable || index >= len(ids) {
+			return nil, false
+		}
+		output, err = method.Outputs.Pack(ids[index])
+	case "tokenURI":
+		uri, exists := nft.uris[args[0].(*big.Int).String()]
+		if !exists {
+			return nil, false
+		}
+		output, err = method.Outputs.Pack(uri)
+	case "name":
+		output, err = method.Outputs.Pack(nft.name)
+	case "symbol":
+		output, err = method.Outputs.Pack(strings.ToUpper(nft.name))
+	}
+	require.NoError(n.t, err)
+
+	return output, true
+}
+
+// executeMultiToken 模拟 ERC-1155 合约的只读方法
+func (n *fakeNode) executeMultiToken(token *fakeMultiToken, input []byte) ([]byte, bool) {
+	balance := func(id *big.Int, account common.Address) *big.Int {
+		return big.NewInt(token.balances[id.String()][account])
+	}
+
+	// supportsInterface / name / symbol 与 ERC-721 的定义相同
+	if method, err := n.nftABI.MethodById(input[:4]); err == nil {
+		args, err := method.Inputs.Unpack(input[4:])
+		require.NoError(n.t, err)
+
+		var output []byte
+		switch method.Name {
+		case "supportsInterface":
+			id := args[0].([4]byte)
+			output, err = method.Outputs.Pack(id == InterfaceERC1155 || id == InterfaceERC1155MetadataURI)
+		case "name":
+			output, err = method.Outputs.Pack(token.name)
+		case "symbol":
+			output, err = method.Outputs.Pack(strings.ToUpper(token.name))
+		default:
+			return nil, false
+		}
+		require.NoError(n.t, err)
+		return output, true
+	}
+
+	method, err := n.erc1155ABI.MethodById(input[:4])
+	if err != nil {
+		return nil, false
+	}
+	args, err := method.Inputs.Unpack(input[4:])
+	require.NoError(n.t, err)
+
+	var output []byte
+	switch method.Name {
+	case "balanceOf":
+		output, err = method.Outputs.Pack(balance(args[1].(*big.Int), args[0].(common.Address)))
+	case "balanceOfBatch":
+		accounts := args[0].([]common.Address)
+		ids := args[1].([]*big.Int)
+		if len(accounts) != len(ids) {
+			return nil, false
+		}
+		balances := make([]*big.Int, len(ids))
+		for i := range ids {
+			balances[i] = balance(ids[i], accounts[i])
+		}
+		output, err = method.Outputs.Pack(balances)
+	case "uri":
+		output, err = method.Outputs.Pack(token.uri)
+	}
+	require.NoError(n.t, err)
+
+	return output, true
+}
+
+// executeFeed 模拟 Chainlink 价格源的只读方法
+func (n *fakeNode) executeFeed(feed *fakeFeed, input []byte) ([]byte, bool) {
+	method, err := n.feedABI.MethodById(input[:4])
+	if err != nil {
+		return nil, false
+	}
+
+	answer := feed.answer
+	if historical, exists := feed.history[n.atBlock]; exists {
+		answer = historical
+	}
+
+	var output []byte
+	switch method.Name {
+	case "decimals":
+		output, err = method.Outputs.Pack(feed.decimals)
+	case "latestRoundData":
+		output, err = method.Outputs.Pack(big.NewInt(1), big.NewInt(answer), big.NewInt(feed.updatedAt), big.NewInt(feed.updatedAt), big.NewInt(1))
+	}
+	require.NoError(n.t, err)
+
+	return output, true
+}
+
+func (n *fakeNode) executeMulticall(input []byte) ([]byte, bool) {
+	method, err := n.multicallABI.MethodById(input[:4])
+	if err != nil {
+		return nil, false
+	}
+
+	args, err := method.Inputs.Unpack(input[4:])
+	require.NoError(n.t, err)
+
+	switch method.Name {
+	case "getEthBalance":
+		output, err := method.Outputs.Pack(n.nativeBalance(args[0].(common.Address)))
+		require.NoError(n.t, err)
+		return output, true
+	case "aggregate3":
+		calls := *abi.ConvertType(args[0], new([]Call)).(*[]Call)
+		results := make([]CallResult, len(calls))
+		for i, call := range calls {
+			output, ok := n.execute(call.Target, call.CallData)
+			if !ok && !call.AllowFailure {
+				return nil, false
+			}
+			results[i] = CallResult{Success: ok, ReturnData: output}
+		}
+		output, err := method.Outputs.Pack(results)
+		require.NoError(n.t, err)
+		return output, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,185 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	ErrArchiveRequired = errors.New("historical state is not available on this rpc endpoint, an archive node is required")
+	ErrBlockNotFound   = errors.New("block not found")
+	ErrBeforeGenesis   = errors.New("timestamp is before the first block of the chain")
+)
+
+// 非归档节点查询已裁剪的历史状态时常见的报错
+var missingStateErrors = []string{
+	"missing trie node",
+	"state not available",
+	"state is not available",
+	"historical state",
+	"state histories",
+	"pruned",
+	"missing state",
+	"state at block",
+}
+
+// 区块时间缓存的条目上限，超过后清空重建
+const maxBlockTimes = 100000
+
+// 距离最新区块超过该深度的区块不会再被重组，时间戳可以缓存
+const ReorgSafeDepth = 64
+
+// isMissingStateError 判断调用是否因为节点没有历史状态而失败
+func isMissingStateError(err error) bool {
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+
+	msg := strings.ToLower(rpcErr.Error())
+	for _, pattern := range missingStateErrors {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// historicalError 把读取历史区块时缺少状态的错误转换为 ErrArchiveRequired
+func historicalError(err error, block *big.Int) error {
+	if err == nil || block == nil || !isMissingStateError(err) {
+		return err
+	}
+	return fmt.Errorf("%w: block %s: %v", ErrArchiveRequired, block, err)
+}
+
+// poolAt 返回读取该区块的连接池：历史区块优先使用归档节点
+func (bc *BlockchainClient) poolAt(block *big.Int) *Pool {
+	if block != nil && bc.archive != nil {
+		return bc.archive
+	}
+	return bc.pool
+}
+
+// blockTimes 缓存已确认区块的时间戳，供按时间查找区块时复用
+type blockTimes struct {
+	mu    sync.Mutex
+	times map[uint64]uint64
+}
+
+func (bt *blockTimes) get(number uint64) (uint64, bool) {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	t, ok := bt.times[number]
+	return t, ok
+}
+
+func (bt *blockTimes) set(number, t uint64) {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	if bt.times == nil || len(bt.times) >= maxBlockTimes {
+		bt.times = make(map[uint64]uint64)
+	}
+	bt.times[number] = t
+}
+
+// headerByNumber 读取区块头，number 为 nil 时读取最新区块
+func (bc *BlockchainClient) headerByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var header *types.Header
+	err := bc.poolAt(number).Do(ctx, func(ctx context.Context, client *ethclient.Client) error {
+		var err error
+		header, err = client.HeaderByNumber(ctx, number)
+		return err
+	})
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrBlockNotFound, number)
+	}
+	return header, err
+}
+
+// BlockTime 返回区块的时间戳
+func (bc *BlockchainClient) BlockTime(ctx context.Context, number uint64) (time.Time, error) {
+	if t, ok := bc.blockTimes.get(number); ok {
+		return time.Unix(int64(t), 0).UTC(), nil
+	}
+
+	header, err := bc.headerByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(header.Time), 0).UTC(), nil
+}
+
+// LatestHeader 返回最新区块的区块号和时间戳
+func (bc *BlockchainClient) LatestHeader(ctx context.Context) (uint64, time.Time, error) {
+	header, err := bc.headerByNumber(ctx, nil)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return header.Number.Uint64(), time.Unix(int64(header.Time), 0).UTC(), nil
+}
+
+// BlockAtTime 二分查找时间戳不晚于 t 的最后一个区块。t 晚于最新区块时返回最新区块
+func (bc *BlockchainClient) BlockAtTime(ctx context.Context, t time.Time) (uint64, error) {
+	head, err := bc.headerByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	target := uint64(t.Unix())
+	if t.Unix() < 0 {
+		target = 0
+	}
+
+	headNumber := head.Number.Uint64()
+	if target >= head.Time {
+		return headNumber, nil
+	}
+
+	blockTime := func(number uint64) (uint64, error) {
+		if cached, ok := bc.blockTimes.get(number); ok {
+			return cached, nil
+		}
+		header, err := bc.headerByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return 0, err
+		}
+		if number+ReorgSafeDepth <= headNumber {
+			bc.blockTimes.set(number, header.Time)
+		}
+		return header.Time, nil
+	}
+
+	genesis, err := blockTime(0)
+	if err != nil {
+		return 0, err
+	}
+	if target < genesis {
+		return 0, ErrBeforeGenesis
+	}
+
+	// 不变式：time(lo) <= target < time(hi)
+	lo, hi := uint64(0), headNumber
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		midTime, err := blockTime(mid)
+		if err != nil {
+			return 0, err
+		}
+		if midTime <= target {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
@@ -0,0 +1,125 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockAtTime(t *testing.T) {
+	node := newTestNode(t)
+	client := newTestClient(t, node)
+	ctx := context.Background()
+	blockTime := func(number uint64) time.Time {
+		return time.Unix(int64(node.genesisTime+number*node.blockInterval), 0)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want uint64
+	}{
+		{"exact block time", blockTime(42), 42},
+		{"between blocks", blockTime(42).Add(5 * time.Second), 42},
+		{"genesis", blockTime(0), 0},
+		{"after head", blockTime(100).Add(time.Hour), 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			number, err := client.BlockAtTime(ctx, tt.at)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, number)
+		})
+	}
+
+	_, err := client.BlockAtTime(ctx, blockTime(0).Add(-time.Second))
+	assert.ErrorIs(t, err, ErrBeforeGenesis)
+
+	// 已确认区块的时间戳被缓存，重复查询只需要读取最新区块头
+	node.setBlockNumber(1000)
+	_, err = client.BlockAtTime(ctx, blockTime(10))
+	require.NoError(t, err)
+	before := node.callCount("eth_getBlockByNumber")
+	_, err = client.BlockAtTime(ctx, blockTime(10))
+	require.NoError(t, err)
+	assert.Equal(t, 1, node.callCount("eth_getBlockByNumber")-before)
+
+	at, err := client.BlockTime(ctx, 42)
+	require.NoError(t, err)
+	assert.Equal(t, blockTime(42).UTC(), at)
+
+	_, err = client.BlockTime(ctx, 5000)
+	assert.ErrorIs(t, err, ErrBlockNotFound)
+}
+
+func TestBatchAt(t *testing.T) {
+	node := newTestNode(t)
+	node.setNativeBalanceAt(testWallet, 50, big.NewInt(1e18))
+	client := newTestClient(t, node)
+
+	batch := client.NewBatchAt(50)
+	then := batch.NativeBalance(testWallet)
+	usdc := batch.BalanceOf(testUSDC, testWallet)
+	// 该区块上还没有部署的合约余额为 0
+	undeployed := batch.BalanceOf("0x00000000000000000000000000000000000000ee", testWallet)
+	require.NoError(t, batch.Execute(context.Background()))
+	assert.Equal(t, big.NewInt(1e18), then.Balance)
+	assert.Equal(t, big.NewInt(1500000), usdc.Balance)
+	assert.NoError(t, undeployed.Err)
+	assert.Equal(t, 0, undeployed.Balance.Sign())
+	assert.Equal(t, uint64(50), batch.BlockNumber())
+
+	batch = client.NewBatch()
+	now := batch.NativeBalance(testWallet)
+	require.NoError(t, batch.Execute(context.Background()))
+	assert.Equal(t, big.NewInt(2e18), now.Balance)
+}
+
+func TestBatchAt_NotArchiveNode(t *testing.T) {
+	node := newTestNode(t)
+	node.setPrunedBelow(90)
+	client := newTestClient(t, node)
+
+	batch := client.NewBatchAt(50)
+	batch.NativeBalance(testWallet)
+	err := batch.Execute(context.Background())
+	assert.ErrorIs(t, err, ErrArchiveRequired)
+
+	// 缺少历史状态不影响端点健康
+	available, _ := client.Status()
+	assert.True(t, available)
+
+	batch = client.NewBatchAt(95)
+	batch.NativeBalance(testWallet)
+	assert.NoError(t, batch.Execute(context.Background()))
+}
+
+func TestBatchAt_ArchiveEndpoint(t *testing.T) {
+	node := newTestNode(t)
+	node.setPrunedBelow(90)
+	archive := newTestNode(t)
+	archive.setNativeBalanceAt(testWallet, 50, big.NewInt(1e18))
+
+	client, err := NewBlockchainClient(&config.ChainConfig{
+		ChainID:          1,
+		RPCURLs:          []string{node.URL()},
+		ArchiveRPCURLs:   []string{archive.URL()},
+		MulticallAddress: DefaultMulticallAddress,
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+
+	// 历史区块只访问归档节点
+	before := node.callCount("eth_call")
+	batch := client.NewBatchAt(50)
+	balance := batch.NativeBalance(testWallet)
+	require.NoError(t, batch.Execute(context.Background()))
+	assert.Equal(t, big.NewInt(1e18), balance.Balance)
+	assert.Equal(t, before, node.callCount("eth_call"))
+}
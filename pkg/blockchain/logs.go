@@ -0,0 +1,202 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TransferTopic 是 Transfer(address,address,uint256) 事件的签名
+var TransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// 未配置 log_range 时每段扫描的区块数
+const DefaultLogRange = 2000
+
+// 节点拒绝 eth_getLogs 时常见的报错：区块范围过大或结果数过多
+var logRangeErrors = []string{
+	"query returned more than",
+	"response size exceeded",
+	"response size should not",
+	"exceed maximum block range",
+	"block range",
+	"range is too",
+	"range too large",
+	"too many results",
+	"limited to",
+}
+
+// isLogRangeError 判断 eth_getLogs 是否因为查询范围太大被拒绝，缩小范围后可以重试
+func isLogRangeError(err error) bool {
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+
+	msg := strings.ToLower(rpcErr.Error())
+	for _, pattern := range logRangeErrors {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockNumber 返回最新区块高度
+func (bc *BlockchainClient) BlockNumber(ctx context.Context) (uint64, error) {
+	var blockNumber uint64
+	err := bc.pool.Do(ctx, func(ctx context.Context, client *ethclient.Client) error {
+		var err error
+		blockNumber, err = client.BlockNumber(ctx)
+		return err
+	})
+	return blockNumber, err
+}
+
+// FilterLogs 通过连接池执行 eth_getLogs
+func (bc *BlockchainClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := bc.pool.Do(ctx, func(ctx context.Context, client *ethclient.Client) error {
+		var err error
+		logs, err = client.FilterLogs(ctx, query)
+		return err
+	})
+	return logs, err
+}
+
+// TransferStats 统计钱包与一个 token 之间的转账，用于识别垃圾 token
+type TransferStats struct {
+	Incoming int
+	Outgoing int
+	// 金额为 0 的转账，常见于地址投毒
+	ZeroValue int
+	// data 不是单个 uint256 的 Transfer 事件
+	NonStandard int
+}
+
+// TransferScan 是一次 Transfer 日志扫描的结果
+type TransferScan struct {
+	// 与钱包有过转账的 ERC-20 合约，按首次出现的顺序排列
+	Tokens []string
+	// 按 token 地址统计的转账
+	Stats map[string]*TransferStats
+	// 下一个尚未扫描的区块；扫描中途失败时从这里继续
+	Next uint64
+}
+
+// logID 在链上唯一标识一条日志
+type logID struct {
+	txHash common.Hash
+	index  uint
+}
+
+// scanLogs 分段执行 eth_getLogs，覆盖 [fromBlock, toBlock]。每段从 maxRange 个区块开始，
+// 节点报告范围或结果数超限时把范围减半后重试，成功后逐步恢复。
+// queries 生成每段需要执行的查询，同一段所有查询成功后去重再交给 handle，
+// 例如钱包转给自己的 Transfer 同时出现在发送方和接收方的查询中；返回下一个尚未扫描的区块
+func (bc *BlockchainClient) scanLogs(ctx context.Context, fromBlock, toBlock, maxRange uint64, queries func(from, to *big.Int) []ethereum.FilterQuery, handle func(logs []types.Log)) (uint64, error) {
+	if maxRange == 0 {
+		maxRange = DefaultLogRange
+	}
+
+	next := fromBlock
+	chunk := maxRange
+	for next <= toBlock {
+		end := next + chunk - 1
+		if end > toBlock || end < next {
+			end = toBlock
+		}
+
+		var logs []types.Log
+		var err error
+		seen := make(map[logID]bool)
+		for _, query := range queries(new(big.Int).SetUint64(next), new(big.Int).SetUint64(end)) {
+			var found []types.Log
+			found, err = bc.FilterLogs(ctx, query)
+			if err != nil {
+				break
+			}
+			for _, entry := range found {
+				id := logID{entry.TxHash, entry.Index}
+				if !seen[id] {
+					seen[id] = true
+					logs = append(logs, entry)
+				}
+			}
+		}
+
+		if err != nil {
+			if isLogRangeError(err) && chunk > 1 {
+				chunk /= 2
+				continue
+			}
+			return next, err
+		}
+
+		handle(logs)
+
+		next = end + 1
+		if chunk < maxRange {
+			chunk *= 2
+			if chunk > maxRange {
+				chunk = maxRange
+			}
+		}
+	}
+
+	return next, nil
+}
+
+// ScanTransfers 扫描 [fromBlock, toBlock] 内钱包作为发送方或接收方的 ERC-20 Transfer 日志。
+// 返回错误时 TransferScan 仍然包含已完成部分的结果
+func (bc *BlockchainClient) ScanTransfers(ctx context.Context, walletAddress string, fromBlock, toBlock, maxRange uint64) (*TransferScan, error) {
+	wallet := common.BytesToHash(common.HexToAddress(walletAddress).Bytes())
+	scan := &TransferScan{Next: fromBlock, Stats: make(map[string]*TransferStats)}
+
+	// 钱包作为发送方（topic1）和接收方（topic2）分别查询
+	queries := func(from, to *big.Int) []ethereum.FilterQuery {
+		return []ethereum.FilterQuery{
+			{FromBlock: from, ToBlock: to, Topics: [][]common.Hash{{TransferTopic}, {wallet}}},
+			{FromBlock: from, ToBlock: to, Topics: [][]common.Hash{{TransferTopic}, nil, {wallet}}},
+		}
+	}
+
+	next, err := bc.scanLogs(ctx, fromBlock, toBlock, maxRange, queries, func(logs []types.Log) {
+		for _, entry := range logs {
+			// ERC-721 的 Transfer 签名相同，但 tokenId 也是 indexed，共 4 个 topic
+			if len(entry.Topics) != 3 {
+				continue
+			}
+
+			token := entry.Address.Hex()
+			stats, exists := scan.Stats[token]
+			if !exists {
+				stats = &TransferStats{}
+				scan.Stats[token] = stats
+				scan.Tokens = append(scan.Tokens, token)
+			}
+
+			if entry.Topics[1] == wallet {
+				stats.Outgoing++
+			} else {
+				stats.Incoming++
+			}
+			switch {
+			case len(entry.Data) != 32:
+				stats.NonStandard++
+			case new(big.Int).SetBytes(entry.Data).Sign() == 0:
+				stats.ZeroValue++
+			}
+		}
+	})
+	scan.Next = next
+
+	return scan, err
+}
@@ -0,0 +1,109 @@
+package blockchain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func transferLog(token, from, to string, blockNumber uint64) types.Log {
+	return types.Log{
+		Address: common.HexToAddress(token),
+		Topics: []common.Hash{
+			TransferTopic,
+			common.BytesToHash(common.HexToAddress(from).Bytes()),
+			common.BytesToHash(common.HexToAddress(to).Bytes()),
+		},
+		Data:        common.LeftPadBytes([]byte{1}, 32),
+		BlockNumber: blockNumber,
+		TxHash:      common.BigToHash(common.Big1),
+	}
+}
+
+func TestScanTransfers(t *testing.T) {
+	node := newTestNode(t)
+	other := "0x00000000000000000000000000000000000000Aa"
+	nft := "0x00000000000000000000000000000000000000Bb"
+
+	node.addLog(transferLog(testUSDC, other, testWallet, 150))
+	node.addLog(transferLog(testDAI, testWallet, other, 9000))
+	node.addLog(transferLog(testUSDC, testWallet, other, 9500))
+	// 与钱包无关的转账
+	node.addLog(transferLog(testBroken, other, other, 200))
+	// ERC-721 Transfer 有 4 个 topic
+	erc721 := transferLog(nft, other, testWallet, 300)
+	erc721.Topics = append(erc721.Topics, common.BigToHash(common.Big1))
+	node.addLog(erc721)
+
+	client := newTestClient(t, node)
+	scan, err := client.ScanTransfers(context.Background(), testWallet, 100, 10000, 5000)
+	require.NoError(t, err)
+	assert.Equal(t, []string{common.HexToAddress(testUSDC).Hex(), common.HexToAddress(testDAI).Hex()}, scan.Tokens)
+	assert.Equal(t, uint64(10001), scan.Next)
+
+	usdc := scan.Stats[common.HexToAddress(testUSDC).Hex()]
+	assert.Equal(t, &TransferStats{Incoming: 1, Outgoing: 1}, usdc)
+}
+
+func TestScanTransfers_Stats(t *testing.T) {
+	node := newTestNode(t)
+	poison := transferLog(testUSDC, testBroken, testWallet, 10)
+	poison.Data = make([]byte, 32)
+	node.addLog(poison)
+	odd := transferLog(testDAI, testBroken, testWallet, 20)
+	odd.Data = nil
+	node.addLog(odd)
+
+	client := newTestClient(t, node)
+	scan, err := client.ScanTransfers(context.Background(), testWallet, 0, 100, 100)
+	require.NoError(t, err)
+	assert.Equal(t, &TransferStats{Incoming: 1, ZeroValue: 1}, scan.Stats[common.HexToAddress(testUSDC).Hex()])
+	assert.Equal(t, &TransferStats{Incoming: 1, NonStandard: 1}, scan.Stats[common.HexToAddress(testDAI).Hex()])
+}
+
+func TestScanTransfers_SelfTransferCountedOnce(t *testing.T) {
+	node := newTestNode(t)
+	// 转给自己的 Transfer 同时匹配发送方和接收方两个查询
+	self := transferLog(testUSDC, testWallet, testWallet, 10)
+	self.Data = make([]byte, 32)
+	node.addLog(self)
+	node.addLog(transferLog(testUSDC, testBroken, testWallet, 20))
+
+	client := newTestClient(t, node)
+	scan, err := client.ScanTransfers(context.Background(), testWallet, 0, 100, 100)
+	require.NoError(t, err)
+	assert.Equal(t, &TransferStats{Incoming: 1, Outgoing: 1, ZeroValue: 1}, scan.Stats[common.HexToAddress(testUSDC).Hex()])
+}
+
+func TestScanTransfers_ShrinksRangeOnProviderLimit(t *testing.T) {
+	node := newTestNode(t)
+	node.setLogRangeLimit(1000)
+	node.addLog(transferLog(testUSDC, testBroken, testWallet, 4321))
+
+	client := newTestClient(t, node)
+	scan, err := client.ScanTransfers(context.Background(), testWallet, 0, 9999, 8000)
+	require.NoError(t, err)
+	assert.Equal(t, []string{common.HexToAddress(testUSDC).Hex()}, scan.Tokens)
+	assert.Equal(t, uint64(10000), scan.Next)
+
+	// 范围超限不计为端点故障
+	for _, endpoint := range client.EndpointStatus() {
+		assert.Equal(t, EndpointHealthy, endpoint.State)
+	}
+}
+
+func TestScanTransfers_ResumesAfterFailure(t *testing.T) {
+	node := newTestNode(t)
+	node.addLog(transferLog(testUSDC, testBroken, testWallet, 10))
+
+	client := newTestClient(t, node)
+	node.setDown(true)
+
+	scan, err := client.ScanTransfers(context.Background(), testWallet, 0, 100, 50)
+	assert.ErrorIs(t, err, ErrChainUnavailable)
+	assert.Equal(t, uint64(0), scan.Next)
+}
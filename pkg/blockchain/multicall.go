@@ -0,0 +1,352 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Multicall3 在绝大多数 EVM 链上部署在同一地址
+const DefaultMulticallAddress = "0xcA11bde05977b3631167028862bE2a173976CA11"
+
+// 单次 aggregate3 最多打包的调用数，避免超出节点的 gas / 响应体限制
+const maxMulticallBatchSize = 300
+
+// Multicall3 ABI (仅包含用到的方法)
+const Multicall3ABI = `[
+    {
+        "inputs":[{"components":[
+            {"name":"target","type":"address"},
+            {"name":"allowFailure","type":"bool"},
+            {"name":"callData","type":"bytes"}
+        ],"name":"calls","type":"tuple[]"}],
+        "name":"aggregate3",
+        "outputs":[{"components":[
+            {"name":"success","type":"bool"},
+            {"name":"returnData","type":"bytes"}
+        ],"name":"returnData","type":"tuple[]"}],
+        "stateMutability":"payable",
+        "type":"function"
+    },
+    {
+        "inputs":[{"name":"addr","type":"address"}],
+        "name":"getEthBalance",
+        "outputs":[{"name":"balance","type":"uint256"}],
+        "stateMutability":"view",
+        "type":"function"
+    }
+]`
+
+var ErrCallFailed = errors.New("contract call failed")
+
+// Call 对应 Multicall3.Call3，字段名需与 ABI 中的 tuple 组件一致
+type Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// CallResult 对应 Multicall3.Result
+type CallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// Aggregate3 通过 Multicall3 一次执行多个只读调用，单个调用失败不影响其它调用
+func (bc *BlockchainClient) Aggregate3(ctx context.Context, calls []Call) ([]CallResult, error) {
+	return bc.aggregate3(ctx, calls, nil)
+}
+
+func (bc *BlockchainClient) aggregate3(ctx context.Context, calls []Call, block *big.Int) ([]CallResult, error) {
+	results := make([]CallResult, 0, len(calls))
+
+	for start := 0; start < len(calls); start += maxMulticallBatchSize {
+		end := start + maxMulticallBatchSize
+		if end > len(calls) {
+			end = len(calls)
+		}
+
+		data, err := bc.multicallABI.Pack("aggregate3", calls[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		msg := ethereum.CallMsg{
+			To:   &bc.multicall,
+			Data: data,
+		}
+
+		output, err := bc.callContract(ctx, msg, block)
+		if err != nil {
+			return nil, err
+		}
+
+		var chunk []CallResult
+		if err := bc.multicallABI.UnpackIntoInterface(&chunk, "aggregate3", output); err != nil {
+			return nil, err
+		}
+		if len(chunk) != end-start {
+			return nil, fmt.Errorf("multicall returned %d results for %d calls", len(chunk), end-start)
+		}
+
+		results = append(results, chunk...)
+	}
+
+	return results, nil
+}
+
+// BalanceResult 在 Batch.Execute 之后填充
+type BalanceResult struct {
+	Balance *big.Int
+	Err     error
+}
+
+// TokenInfoResult 在 Batch.Execute 之后填充。缺失或无法解析的字段使用默认值，
+// 并在 Warnings 中记录
+type TokenInfoResult struct {
+	Symbol   string
+	Name     string
+	Decimals uint8
+	Warnings []string
+	Err      error
+}
+
+type batchCall struct {
+	call Call
+	// 原生币余额在未配置 Multicall 时退化为 eth_getBalance
+	native *common.Address
+	decode func(result CallResult)
+}
+
+// Batch 收集同一条链上的余额与元数据查询，Execute 时合并为尽量少的 RPC 请求
+type Batch struct {
+	client *BlockchainClient
+	calls  []batchCall
+	// 为空时读取最新状态
+	block *big.Int
+}
+
+func (bc *BlockchainClient) NewBatch() *Batch {
+	return &Batch{client: bc}
+}
+
+// NewBatchAt 创建在指定区块读取状态的批次，所有查询看到的是同一个区块的状态。
+// 历史区块需要归档节点，节点缺少该区块的状态时返回 ErrArchiveRequired
+func (bc *BlockchainClient) NewBatchAt(blockNumber uint64) *Batch {
+	return &Batch{client: bc, block: new(big.Int).SetUint64(blockNumber)}
+}
+
+// BlockNumber 返回批次读取的区块号，读取最新状态时为 0
+func (b *Batch) BlockNumber() uint64 {
+	if b.block == nil {
+		return 0
+	}
+	return b.block.Uint64()
+}
+
+// NativeBalance 添加一次原生币余额查询
+func (b *Batch) NativeBalance(walletAddress string) *BalanceResult {
+	walletAddr := common.HexToAddress(walletAddress)
+	result := &BalanceResult{}
+
+	data, err := b.client.multicallABI.Pack("getEthBalance", walletAddr)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	b.calls = append(b.calls, batchCall{
+		call:   Call{Target: b.client.multicall, AllowFailure: true, CallData: data},
+		native: &walletAddr,
+		decode: func(res CallResult) {
+			result.Balance, result.Err = b.client.unpackBigInt(b.client.multicallABI, "getEthBalance", res)
+		},
+	})
+
+	return result
+}
+
+// BalanceOf 添加一次 ERC-20 balanceOf 查询
+func (b *Batch) BalanceOf(tokenAddress, walletAddress string) *BalanceResult {
+	result := &BalanceResult{}
+
+	data, err := b.client.abi.Pack("balanceOf", common.HexToAddress(walletAddress))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	b.calls = append(b.calls, batchCall{
+		call: Call{Target: common.HexToAddress(tokenAddress), AllowFailure: true, CallData: data},
+		decode: func(res CallResult) {
+			// 历史区块上合约可能还没有部署，此时调用成功但没有返回数据，余额视为 0
+			if b.block != nil && res.Success && len(res.ReturnData) == 0 {
+				result.Balance = new(big.Int)
+				return
+			}
+			result.Balance, result.Err = b.client.unpackBigInt(b.client.abi, "balanceOf", res)
+		},
+	})
+
+	return result
+}
+
+// TokenInfo 添加 symbol / name / decimals 三个元数据查询
+func (b *Batch) TokenInfo(tokenAddress string) *TokenInfoResult {
+	tokenAddr := common.HexToAddress(tokenAddress)
+	result := &TokenInfoResult{
+		Symbol:   DefaultTokenSymbol,
+		Name:     DefaultTokenName,
+		Decimals: DefaultTokenDecimals,
+	}
+
+	for _, method := range []string{"symbol", "name", "decimals"} {
+		method := method
+
+		data, err := b.client.abi.Pack(method)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+
+		b.calls = append(b.calls, batchCall{
+			call: Call{Target: tokenAddr, AllowFailure: true, CallData: data},
+			decode: func(res CallResult) {
+				switch method {
+				case "symbol":
+					if symbol, ok := decodeTokenString(b.client.abi, method, res); ok {
+						result.Symbol = symbol
+					} else {
+						result.Warnings = append(result.Warnings, WarningSymbolMissing)
+					}
+				case "name":
+					if name, ok := decodeTokenString(b.client.abi, method, res); ok {
+						result.Name = name
+					} else {
+						result.Warnings = append(result.Warnings, WarningNameMissing)
+					}
+				case "decimals":
+					if decimals, ok := decodeTokenDecimals(res); ok {
+						result.Decimals = decimals
+					} else {
+						result.Warnings = append(result.Warnings, WarningDecimalsMissing)
+					}
+				}
+			},
+		})
+	}
+
+	return result
+}
+
+// Len 返回已添加的底层调用数
+func (b *Batch) Len() int {
+	return len(b.calls)
+}
+
+// Execute 执行所有查询并填充结果；只有整体请求失败时才返回错误
+func (b *Batch) Execute(ctx context.Context) error {
+	if len(b.calls) == 0 {
+		return nil
+	}
+
+	direct := b.client.multicall == (common.Address{})
+	if !direct && b.block != nil {
+		// Multicall3 部署之前的区块也只能逐个调用
+		code, err := b.client.codeAt(ctx, b.client.multicall, b.block)
+		if err != nil {
+			return err
+		}
+		direct = len(code) == 0
+	}
+
+	// 未配置 Multicall 的链逐个调用
+	if direct {
+		if available, err := b.client.poolAt(b.block).Available(); !available {
+			return fmt.Errorf("%w: chain %d: %v", ErrChainUnavailable, b.client.chainID, err)
+		}
+
+		for _, bc := range b.calls {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			res, err := b.client.callDirect(ctx, bc, b.block)
+			if err != nil {
+				return err
+			}
+			bc.decode(res)
+		}
+		return nil
+	}
+
+	calls := make([]Call, len(b.calls))
+	for i, bc := range b.calls {
+		calls[i] = bc.call
+	}
+
+	results, err := b.client.aggregate3(ctx, calls, b.block)
+	if err != nil {
+		return err
+	}
+
+	for i, bc := range b.calls {
+		bc.decode(results[i])
+	}
+
+	return nil
+}
+
+// callDirect 不经 Multicall 执行单个调用；合约 revert 记为调用失败，
+// 只有链不可用、节点缺少历史状态或超时才返回错误
+func (bc *BlockchainClient) callDirect(ctx context.Context, call batchCall, block *big.Int) (CallResult, error) {
+	if call.native != nil {
+		balance, err := bc.balanceAt(ctx, *call.native, block)
+		if err != nil {
+			return CallResult{}, err
+		}
+		data, err := bc.multicallABI.Methods["getEthBalance"].Outputs.Pack(balance)
+		if err != nil {
+			return CallResult{}, err
+		}
+		return CallResult{Success: true, ReturnData: data}, nil
+	}
+
+	msg := ethereum.CallMsg{
+		To:   &call.call.Target,
+		Data: call.call.CallData,
+	}
+
+	output, err := bc.callContract(ctx, msg, block)
+	if err != nil {
+		if errors.Is(err, ErrChainUnavailable) || errors.Is(err, ErrArchiveRequired) || ctx.Err() != nil {
+			return CallResult{}, err
+		}
+		return CallResult{}, nil
+	}
+
+	return CallResult{Success: true, ReturnData: output}, nil
+}
+
+func (bc *BlockchainClient) unpackBigInt(contractABI abi.ABI, method string, res CallResult) (*big.Int, error) {
+	if !res.Success || len(res.ReturnData) == 0 {
+		return nil, fmt.Errorf("%w: %s()", ErrCallFailed, method)
+	}
+
+	value := new(big.Int)
+	if err := contractABI.UnpackIntoInterface(&value, method, res.ReturnData); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func parseMulticallABI() (abi.ABI, error) {
+	return abi.JSON(strings.NewReader(Multicall3ABI))
+}
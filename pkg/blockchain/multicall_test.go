@@ -0,0 +1,116 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"wallet-tracker/internal/config"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testWallet = "0x742d35Cc6634C0532925a3b8D2d291b8F0932C71"
+	testUSDC   = "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"
+	testDAI    = "0x6B175474E89094C44Da98b954EedeAC495271d0F"
+	testBroken = "0x000000000000000000000000000000000000dEaD"
+)
+
+func newTestClient(t *testing.T, node *fakeNode) *BlockchainClient {
+	client, err := NewBlockchainClient(&config.ChainConfig{
+		ChainID:          1,
+		Name:             "Ethereum",
+		NativeSymbol:     "ETH",
+		NativeDecimals:   18,
+		RPCURLs:          []string{node.URL()},
+		MulticallAddress: DefaultMulticallAddress,
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func newTestNode(t *testing.T) *fakeNode {
+	node := newFakeNode(t, 1)
+	node.setNativeBalance(testWallet, big.NewInt(2e18))
+	node.addToken(testUSDC, &fakeToken{
+		symbol:   "USDC",
+		name:     "USD Coin",
+		decimals: 6,
+		balances: map[common.Address]*big.Int{common.HexToAddress(testWallet): big.NewInt(1500000)},
+	})
+	node.addToken(testDAI, &fakeToken{
+		symbol:   "DAI",
+		name:     "Dai Stablecoin",
+		decimals: 18,
+		balances: map[common.Address]*big.Int{},
+	})
+	return node
+}
+
+func TestBatch_SingleMulticallRoundTrip(t *testing.T) {
+	node := newTestNode(t)
+	client := newTestClient(t, node)
+
+	batch := client.NewBatch()
+	native := batch.NativeBalance(testWallet)
+	usdc := batch.BalanceOf(testUSDC, testWallet)
+	dai := batch.BalanceOf(testDAI, testWallet)
+	broken := batch.BalanceOf(testBroken, testWallet)
+	usdcInfo := batch.TokenInfo(testUSDC)
+	brokenInfo := batch.TokenInfo(testBroken)
+
+	require.NoError(t, batch.Execute(context.Background()))
+	assert.Equal(t, 1, node.callCount("eth_call"))
+
+	assert.NoError(t, native.Err)
+	assert.Equal(t, big.NewInt(2e18), native.Balance)
+	assert.NoError(t, usdc.Err)
+	assert.Equal(t, big.NewInt(1500000), usdc.Balance)
+	assert.NoError(t, dai.Err)
+	assert.Equal(t, int64(0), dai.Balance.Int64())
+
+	assert.NoError(t, usdcInfo.Err)
+	assert.Equal(t, "USDC", usdcInfo.Symbol)
+	assert.Equal(t, "USD Coin", usdcInfo.Name)
+	assert.Equal(t, uint8(6), usdcInfo.Decimals)
+
+	// 一个无效 token 不影响整个批次
+	assert.ErrorIs(t, broken.Err, ErrCallFailed)
+	assert.NoError(t, brokenInfo.Err)
+	assert.Equal(t, []string{WarningSymbolMissing, WarningNameMissing, WarningDecimalsMissing}, brokenInfo.Warnings)
+}
+
+func TestBatch_FallbackWithoutMulticall(t *testing.T) {
+	node := newTestNode(t)
+	client := newTestClient(t, node)
+	client.multicall = common.Address{}
+
+	batch := client.NewBatch()
+	native := batch.NativeBalance(testWallet)
+	usdc := batch.BalanceOf(testUSDC, testWallet)
+	info := batch.TokenInfo(testUSDC)
+
+	require.NoError(t, batch.Execute(context.Background()))
+	assert.Equal(t, 1, node.callCount("eth_getBalance"))
+	assert.Equal(t, 4, node.callCount("eth_call"))
+
+	assert.Equal(t, big.NewInt(2e18), native.Balance)
+	assert.Equal(t, big.NewInt(1500000), usdc.Balance)
+	assert.Equal(t, "USDC", info.Symbol)
+}
+
+func TestIsContract(t *testing.T) {
+	client := newTestClient(t, newTestNode(t))
+
+	isContract, err := client.IsContract(context.Background(), testUSDC)
+	require.NoError(t, err)
+	assert.True(t, isContract)
+
+	isContract, err = client.IsContract(context.Background(), testWallet)
+	require.NoError(t, err)
+	assert.False(t, isContract)
+}
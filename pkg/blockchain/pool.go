@@ -0,0 +1,464 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"wallet-tracker/internal/config"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// 端点状态
+const (
+	EndpointHealthy = "healthy"
+	EndpointLagging = "lagging"
+	EndpointOpen    = "circuit_open"
+	EndpointDown    = "down"
+	EndpointRefused = "refused"
+)
+
+// EndpointStatus 是端点健康状况的快照，用于诊断接口
+type EndpointStatus struct {
+	URL                 string     `json:"url"`
+	State               string     `json:"state"`
+	LatencyMs           float64    `json:"latency_ms"`
+	ErrorRate           float64    `json:"error_rate"`
+	Requests            uint64     `json:"requests"`
+	Failures            uint64     `json:"failures"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	BlockNumber         uint64     `json:"block_number"`
+	BlockLag            uint64     `json:"block_lag"`
+	CircuitOpenUntil    *time.Time `json:"circuit_open_until,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+// 延迟和错误率使用指数加权移动平均
+const ewmaAlpha = 0.3
+
+type poolOptions struct {
+	callTimeout         time.Duration
+	failureThreshold    int
+	cooldown            time.Duration
+	maxBlockLag         uint64
+	healthCheckInterval time.Duration
+	maxRetries          int
+	retryBackoff        time.Duration
+}
+
+func newPoolOptions(chain *config.ChainConfig, cfg *config.RPCPoolConfig) poolOptions {
+	opts := poolOptions{
+		callTimeout:         5 * time.Second,
+		failureThreshold:    3,
+		cooldown:            30 * time.Second,
+		maxBlockLag:         5,
+		healthCheckInterval: 15 * time.Second,
+		maxRetries:          2,
+		retryBackoff:        100 * time.Millisecond,
+	}
+	if d, err := time.ParseDuration(chain.CallTimeout); err == nil && d > 0 {
+		opts.callTimeout = d
+	}
+	if cfg == nil {
+		return opts
+	}
+
+	if cfg.FailureThreshold > 0 {
+		opts.failureThreshold = cfg.FailureThreshold
+	}
+	if d, err := time.ParseDuration(cfg.Cooldown); err == nil {
+		opts.cooldown = d
+	}
+	if cfg.MaxBlockLag > 0 {
+		opts.maxBlockLag = cfg.MaxBlockLag
+	}
+	if d, err := time.ParseDuration(cfg.HealthCheckInterval); err == nil && d > 0 {
+		opts.healthCheckInterval = d
+	}
+	if cfg.MaxRetries > 0 {
+		opts.maxRetries = cfg.MaxRetries
+	}
+	if d, err := time.ParseDuration(cfg.RetryBackoff); err == nil {
+		opts.retryBackoff = d
+	}
+
+	return opts
+}
+
+// Pool 在同一条链的多个 RPC 端点之间路由请求：按延迟、错误率和区块高度落后程度打分，
+// 连续失败的端点由熔断器摘除，可重试的错误换一个端点重试
+type Pool struct {
+	chainID   int
+	endpoints []*endpoint
+	opts      poolOptions
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewPool(chain *config.ChainConfig, cfg *config.RPCPoolConfig) (*Pool, error) {
+	chainID := chain.ChainID
+	p := &Pool{
+		chainID: chainID,
+		opts:    newPoolOptions(chain, cfg),
+		done:    make(chan struct{}),
+	}
+
+	var refusedErr error
+	refused := 0
+
+	for _, rawURL := range chain.RPCURLs {
+		ep := newEndpoint(chainID, rawURL)
+		p.endpoints = append(p.endpoints, ep)
+
+		if err := ep.connect(); err != nil {
+			ep.lastErr = err
+
+			if errors.Is(err, ErrChainIDMismatch) {
+				log.Printf("chain %d: refusing rpc endpoint %s: %v", chainID, ep.displayURL(), err)
+				ep.refused = true
+				refusedErr = err
+				refused++
+				continue
+			}
+
+			log.Printf("chain %d: rpc endpoint %s unavailable, reconnecting in background: %v", chainID, ep.displayURL(), err)
+			go ep.reconnectLoop(p.done)
+		}
+	}
+
+	if refused == len(p.endpoints) {
+		p.Close()
+		return nil, refusedErr
+	}
+
+	go p.healthLoop()
+
+	return p, nil
+}
+
+// Do 选择最健康的端点执行 fn；端点故障（网络错误、限流、5xx、单次调用超时）时换端点按退避重试，
+// 仅适用于幂等的只读调用。每次尝试的 ctx 带有该链的单次调用超时
+func (p *Pool) Do(ctx context.Context, fn func(ctx context.Context, client *ethclient.Client) error) error {
+	var lastErr error
+	tried := make(map[*endpoint]bool)
+
+	for attempt := 0; attempt <= p.opts.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(p.opts.retryBackoff << (attempt - 1)):
+			}
+		}
+
+		ep, client := p.pick(tried)
+		if ep == nil {
+			break
+		}
+		tried[ep] = true
+
+		start := time.Now()
+		callCtx, cancel := context.WithTimeout(ctx, p.opts.callTimeout)
+		err := fn(callCtx, client)
+		cancel()
+
+		// 调用方取消或整体预算耗尽，不计入端点健康
+		if ctx.Err() != nil {
+			ep.releaseTrial()
+			return ctx.Err()
+		}
+
+		err = ep.redact(err)
+		if err != nil && isEndpointError(err) {
+			ep.recordFailure(err, p.opts)
+			lastErr = err
+			continue
+		}
+
+		// 合约 revert 等错误与端点健康无关
+		ep.recordSuccess(time.Since(start))
+		return err
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no healthy rpc endpoint")
+	}
+	return fmt.Errorf("%w: chain %d: %v", ErrChainUnavailable, p.chainID, lastErr)
+}
+
+// pick 优先选择本次请求尚未尝试过的端点，其次按分数选择
+func (p *Pool) pick(tried map[*endpoint]bool) (*endpoint, *ethclient.Client) {
+	now := time.Now()
+	maxBlock := p.maxBlockNumber()
+
+	var best *endpoint
+	var bestClient *ethclient.Client
+	bestScore := 0.0
+
+	for _, ep := range p.endpoints {
+		ep.mu.RLock()
+		usable := ep.client != nil && !ep.refused
+		if usable && !ep.openUntil.IsZero() && (now.Before(ep.openUntil) || ep.halfOpenTrial) {
+			usable = false
+		}
+
+		score := float64(ep.latency.Microseconds()) * (1 + 4*ep.errorRate)
+		if ep.blockNumber > 0 && maxBlock-ep.blockNumber > p.opts.maxBlockLag {
+			score += 1e9
+		}
+		client := ep.client
+		ep.mu.RUnlock()
+
+		if !usable {
+			continue
+		}
+		if tried[ep] {
+			score += 1e12
+		}
+
+		if best == nil || score < bestScore {
+			best, bestClient, bestScore = ep, client, score
+		}
+	}
+
+	if best != nil {
+		// 熔断冷却结束后只放行一个试探请求
+		best.mu.Lock()
+		if !best.openUntil.IsZero() {
+			best.halfOpenTrial = true
+		}
+		best.mu.Unlock()
+	}
+
+	return best, bestClient
+}
+
+func (ep *endpoint) recordSuccess(latency time.Duration) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	ep.requests++
+	if ep.latency == 0 {
+		ep.latency = latency
+	} else {
+		ep.latency = time.Duration(ewmaAlpha*float64(latency) + (1-ewmaAlpha)*float64(ep.latency))
+	}
+	ep.errorRate *= 1 - ewmaAlpha
+	ep.consecutiveFailures = 0
+	ep.openUntil = time.Time{}
+	ep.halfOpenTrial = false
+}
+
+func (ep *endpoint) releaseTrial() {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.halfOpenTrial = false
+}
+
+func (ep *endpoint) recordFailure(err error, opts poolOptions) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	ep.requests++
+	ep.failures++
+	ep.errorRate = ewmaAlpha + (1-ewmaAlpha)*ep.errorRate
+	ep.consecutiveFailures++
+	err = ep.redact(err)
+	ep.lastErr = err
+
+	if ep.halfOpenTrial || ep.consecutiveFailures >= opts.failureThreshold {
+		if ep.openUntil.IsZero() || ep.halfOpenTrial {
+			log.Printf("chain %d: ejecting rpc endpoint %s for %s: %v", ep.chainID, ep.displayURL(), opts.cooldown, err)
+		}
+		ep.openUntil = time.Now().Add(opts.cooldown)
+		ep.halfOpenTrial = false
+	}
+}
+
+// isEndpointError 判断错误是否由端点本身造成（值得换端点重试）
+func isEndpointError(err error) bool {
+	// 调用方取消，以及区块不存在这类正常的查询结果
+	if errors.Is(err, context.Canceled) || errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return true
+	}
+
+	// eth_getLogs 范围超限与端点健康无关，由调用方缩小范围重试
+	if isLogRangeError(err) {
+		return false
+	}
+
+	// 非归档节点没有历史状态，端点本身是健康的
+	if isMissingStateError(err) {
+		return false
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.ErrorCode() {
+		case -32005, -32603: // 限流 / 节点内部错误
+			return true
+		}
+		msg := strings.ToLower(rpcErr.Error())
+		return strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests")
+	}
+
+	// 网络错误、超时等
+	return true
+}
+
+// healthLoop 定期探测各端点的区块高度，用于计算落后程度并让熔断的端点恢复
+func (p *Pool) healthLoop() {
+	p.checkHealth()
+
+	ticker := time.NewTicker(p.opts.healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.checkHealth()
+		}
+	}
+}
+
+func (p *Pool) checkHealth() {
+	var wg sync.WaitGroup
+
+	for _, ep := range p.endpoints {
+		ep.mu.RLock()
+		client := ep.client
+		refused := ep.refused
+		ep.mu.RUnlock()
+
+		if client == nil || refused {
+			continue
+		}
+
+		wg.Add(1)
+		go func(ep *endpoint, client *ethclient.Client) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+			defer cancel()
+
+			start := time.Now()
+			blockNumber, err := client.BlockNumber(ctx)
+			if err != nil {
+				ep.recordFailure(err, p.opts)
+				return
+			}
+
+			ep.recordSuccess(time.Since(start))
+			ep.mu.Lock()
+			ep.blockNumber = blockNumber
+			ep.mu.Unlock()
+		}(ep, client)
+	}
+
+	wg.Wait()
+}
+
+func (p *Pool) maxBlockNumber() uint64 {
+	var maxBlock uint64
+	for _, ep := range p.endpoints {
+		ep.mu.RLock()
+		if ep.blockNumber > maxBlock {
+			maxBlock = ep.blockNumber
+		}
+		ep.mu.RUnlock()
+	}
+	return maxBlock
+}
+
+// Available 判断是否至少有一个端点可以接收请求，不可用时返回最近的错误
+func (p *Pool) Available() (bool, error) {
+	now := time.Now()
+	var lastErr error
+
+	for _, ep := range p.endpoints {
+		ep.mu.RLock()
+		usable := ep.client != nil && !ep.refused && (ep.openUntil.IsZero() || !now.Before(ep.openUntil))
+		if ep.lastErr != nil {
+			lastErr = ep.lastErr
+		}
+		ep.mu.RUnlock()
+
+		if usable {
+			return true, nil
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no healthy rpc endpoint")
+	}
+	return false, lastErr
+}
+
+// Status 返回各端点的健康状况
+func (p *Pool) Status() []EndpointStatus {
+	now := time.Now()
+	maxBlock := p.maxBlockNumber()
+	statuses := make([]EndpointStatus, 0, len(p.endpoints))
+
+	for _, ep := range p.endpoints {
+		ep.mu.RLock()
+		status := EndpointStatus{
+			URL:                 ep.displayURL(),
+			LatencyMs:           float64(ep.latency.Microseconds()) / 1000,
+			ErrorRate:           ep.errorRate,
+			Requests:            ep.requests,
+			Failures:            ep.failures,
+			ConsecutiveFailures: ep.consecutiveFailures,
+			BlockNumber:         ep.blockNumber,
+		}
+		if ep.blockNumber > 0 {
+			status.BlockLag = maxBlock - ep.blockNumber
+		}
+		if ep.lastErr != nil {
+			status.LastError = ep.lastErr.Error()
+		}
+
+		switch {
+		case ep.refused:
+			status.State = EndpointRefused
+		case ep.client == nil:
+			status.State = EndpointDown
+		case !ep.openUntil.IsZero() && now.Before(ep.openUntil):
+			openUntil := ep.openUntil
+			status.State = EndpointOpen
+			status.CircuitOpenUntil = &openUntil
+		case status.BlockLag > p.opts.maxBlockLag:
+			status.State = EndpointLagging
+		default:
+			status.State = EndpointHealthy
+		}
+		ep.mu.RUnlock()
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+func (p *Pool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		for _, ep := range p.endpoints {
+			ep.close()
+		}
+	})
+}
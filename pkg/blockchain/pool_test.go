@@ -0,0 +1,221 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/config"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPoolConfig() *config.RPCPoolConfig {
+	return &config.RPCPoolConfig{
+		FailureThreshold:    2,
+		Cooldown:            "1m",
+		MaxBlockLag:         5,
+		HealthCheckInterval: "20ms",
+		RetryBackoff:        "1ms",
+	}
+}
+
+func poolChain(nodes ...*fakeNode) *config.ChainConfig {
+	chain := &config.ChainConfig{ChainID: 1, Name: "Ethereum", NativeSymbol: "ETH"}
+	for _, node := range nodes {
+		chain.RPCURLs = append(chain.RPCURLs, node.URL())
+	}
+	return chain
+}
+
+func newPoolNode(t *testing.T, chainID int64) *fakeNode {
+	node := newFakeNode(t, chainID)
+	node.setNativeBalance(testWallet, big.NewInt(42))
+	return node
+}
+
+func TestPool_FailoverAndCircuitBreaker(t *testing.T) {
+	primary := newPoolNode(t, 1)
+	backup := newPoolNode(t, 1)
+	// 备用端点更慢，正常情况下优先选择主端点
+	backup.setDelay(20 * time.Millisecond)
+
+	cfg := testPoolConfig()
+	cfg.HealthCheckInterval = "1h"
+	pool, err := NewPool(poolChain(primary, backup), cfg)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	primary.setDown(true)
+
+	client := &BlockchainClient{chainID: 1, pool: pool}
+	for i := 0; i < 10; i++ {
+		balance, err := client.balanceAt(context.Background(), common.HexToAddress(testWallet), nil)
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(42), balance)
+	}
+
+	status := pool.Status()
+	assert.Equal(t, EndpointOpen, status[0].State)
+	assert.NotNil(t, status[0].CircuitOpenUntil)
+	assert.NotEmpty(t, status[0].LastError)
+	assert.Equal(t, EndpointHealthy, status[1].State)
+
+	// 熔断后不再向故障端点发送请求
+	hits := primary.hitCount()
+	_, err = client.balanceAt(context.Background(), common.HexToAddress(testWallet), nil)
+	require.NoError(t, err)
+	assert.Equal(t, hits, primary.hitCount())
+}
+
+func TestPool_RefusesMismatchedEndpoint(t *testing.T) {
+	wrongChain := newPoolNode(t, 56)
+	correct := newPoolNode(t, 1)
+
+	pool, err := NewPool(poolChain(wrongChain, correct), testPoolConfig())
+	require.NoError(t, err)
+	defer pool.Close()
+
+	status := pool.Status()
+	assert.Equal(t, EndpointRefused, status[0].State)
+	assert.Contains(t, status[0].LastError, "expected chain 1")
+
+	client := &BlockchainClient{chainID: 1, pool: pool}
+	_, err = client.balanceAt(context.Background(), common.HexToAddress(testWallet), nil)
+	require.NoError(t, err)
+	assert.Zero(t, wrongChain.callCount("eth_getBalance"))
+	assert.Equal(t, 1, correct.callCount("eth_getBalance"))
+}
+
+func TestPool_AvoidsLaggingEndpoint(t *testing.T) {
+	lagging := newPoolNode(t, 1)
+	lagging.setBlockNumber(100)
+	synced := newPoolNode(t, 1)
+	synced.setBlockNumber(200)
+
+	pool, err := NewPool(poolChain(lagging, synced), testPoolConfig())
+	require.NoError(t, err)
+	defer pool.Close()
+
+	assert.Eventually(t, func() bool {
+		status := pool.Status()
+		return status[0].State == EndpointLagging && status[0].BlockLag == 100
+	}, time.Second, 10*time.Millisecond)
+
+	client := &BlockchainClient{chainID: 1, pool: pool}
+	for i := 0; i < 5; i++ {
+		_, err := client.balanceAt(context.Background(), common.HexToAddress(testWallet), nil)
+		require.NoError(t, err)
+	}
+	assert.Zero(t, lagging.callCount("eth_getBalance"))
+	assert.Equal(t, 5, synced.callCount("eth_getBalance"))
+}
+
+func TestPool_AllEndpointsDown(t *testing.T) {
+	first := newPoolNode(t, 1)
+	second := newPoolNode(t, 1)
+
+	pool, err := NewPool(poolChain(first, second), testPoolConfig())
+	require.NoError(t, err)
+	defer pool.Close()
+
+	first.setDown(true)
+	second.setDown(true)
+
+	client := &BlockchainClient{chainID: 1, pool: pool}
+	_, err = client.balanceAt(context.Background(), common.HexToAddress(testWallet), nil)
+	assert.ErrorIs(t, err, ErrChainUnavailable)
+
+	assert.Eventually(t, func() bool {
+		available, _ := pool.Available()
+		return !available
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPool_CallTimeoutFailsOver(t *testing.T) {
+	slow := newPoolNode(t, 1)
+	fast := newPoolNode(t, 1)
+	fast.setDelay(10 * time.Millisecond)
+
+	chain := poolChain(slow, fast)
+	chain.CallTimeout = "50ms"
+	cfg := testPoolConfig()
+	cfg.HealthCheckInterval = "1h"
+	pool, err := NewPool(chain, cfg)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// 健康检查完成后再让主端点变慢
+	assert.Eventually(t, func() bool {
+		status := pool.Status()
+		return status[0].Requests > 0 && status[1].Requests > 0
+	}, time.Second, 5*time.Millisecond)
+	slow.setDelay(200 * time.Millisecond)
+
+	client := &BlockchainClient{chainID: 1, pool: pool}
+	balance, err := client.balanceAt(context.Background(), common.HexToAddress(testWallet), nil)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(42), balance)
+	assert.Equal(t, uint64(1), pool.Status()[0].Failures)
+}
+
+func TestPool_CallerDeadlineIsNotAnEndpointFailure(t *testing.T) {
+	node := newPoolNode(t, 1)
+
+	cfg := testPoolConfig()
+	cfg.HealthCheckInterval = "1h"
+	pool, err := NewPool(poolChain(node), cfg)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	assert.Eventually(t, func() bool { return pool.Status()[0].Requests > 0 }, time.Second, 5*time.Millisecond)
+	node.setDelay(200 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	client := &BlockchainClient{chainID: 1, pool: pool}
+	_, err = client.balanceAt(ctx, common.HexToAddress(testWallet), nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Zero(t, pool.Status()[0].Failures)
+}
+
+func TestPool_RedactsURLInErrors(t *testing.T) {
+	node := newPoolNode(t, 1)
+	chain := &config.ChainConfig{ChainID: 1, Name: "Ethereum", NativeSymbol: "ETH", RPCURLs: []string{node.URL() + "/v3/secret-api-key"}}
+
+	pool, err := NewPool(chain, testPoolConfig())
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// 节点停止后拨号错误中带有完整的 URL
+	node.server.Close()
+
+	client := &BlockchainClient{chainID: 1, pool: pool}
+	_, err = client.balanceAt(context.Background(), common.HexToAddress(testWallet), nil)
+	require.ErrorIs(t, err, ErrChainUnavailable)
+	assert.NotContains(t, err.Error(), "secret-api-key")
+
+	statuses := pool.Status()
+	require.Len(t, statuses, 1)
+	assert.NotEmpty(t, statuses[0].LastError)
+	assert.NotContains(t, statuses[0].LastError, "secret-api-key")
+
+	_, err = pool.Available()
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret-api-key")
+
+	// 启动时连接失败的端点同样不记录 URL
+	down := &config.ChainConfig{ChainID: 1, Name: "Ethereum", NativeSymbol: "ETH", RPCURLs: []string{"http://127.0.0.1:1/v3/secret-api-key"}}
+	pool, err = NewPool(down, testPoolConfig())
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Available()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http://127.0.0.1:1")
+	assert.NotContains(t, err.Error(), "secret-api-key")
+}
@@ -0,0 +1,125 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AggregatorV3ABI 包含 Chainlink 价格源中用到的只读方法
+const AggregatorV3ABI = `[
+    {
+        "inputs":[],
+        "name":"decimals",
+        "outputs":[{"name":"","type":"uint8"}],
+        "stateMutability":"view",
+        "type":"function"
+    },
+    {
+        "inputs":[],
+        "name":"latestRoundData",
+        "outputs":[
+            {"name":"roundId","type":"uint80"},
+            {"name":"answer","type":"int256"},
+            {"name":"startedAt","type":"uint256"},
+            {"name":"updatedAt","type":"uint256"},
+            {"name":"answeredInRound","type":"uint80"}
+        ],
+        "stateMutability":"view",
+        "type":"function"
+    }
+]`
+
+// PriceFeedResult 在 Batch.Execute 之后填充，价格为 Answer × 10^-Decimals
+type PriceFeedResult struct {
+	Answer    *big.Int
+	Decimals  uint8
+	UpdatedAt time.Time
+	Err       error
+}
+
+// PriceFeed 添加一次 Chainlink 价格源查询，读取 decimals 和最新一轮的价格
+func (b *Batch) PriceFeed(feedAddress string) *PriceFeedResult {
+	feed := common.HexToAddress(feedAddress)
+	result := &PriceFeedResult{}
+
+	for _, method := range []string{"decimals", "latestRoundData"} {
+		method := method
+
+		data, err := b.client.feedABI.Pack(method)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+
+		b.calls = append(b.calls, batchCall{
+			call: Call{Target: feed, AllowFailure: true, CallData: data},
+			decode: func(res CallResult) {
+				if result.Err != nil {
+					return
+				}
+				if !res.Success || len(res.ReturnData) == 0 {
+					result.Err = fmt.Errorf("%w: %s()", ErrCallFailed, method)
+					return
+				}
+
+				switch method {
+				case "decimals":
+					decimals, ok := decodeTokenDecimals(res)
+					if !ok {
+						result.Err = fmt.Errorf("%w: decimals()", ErrCallFailed)
+						return
+					}
+					result.Decimals = decimals
+				case "latestRoundData":
+					var round struct {
+						RoundId         *big.Int
+						Answer          *big.Int
+						StartedAt       *big.Int
+						UpdatedAt       *big.Int
+						AnsweredInRound *big.Int
+					}
+					if err := b.client.feedABI.UnpackIntoInterface(&round, method, res.ReturnData); err != nil {
+						result.Err = err
+						return
+					}
+					// 价格源尚未更新过或返回了非正数的价格
+					if round.Answer.Sign() <= 0 || round.UpdatedAt.Sign() == 0 {
+						result.Err = fmt.Errorf("%w: latestRoundData() returned no price", ErrCallFailed)
+						return
+					}
+					result.Answer = round.Answer
+					result.UpdatedAt = time.Unix(round.UpdatedAt.Int64(), 0).UTC()
+				}
+			},
+		})
+	}
+
+	return result
+}
+
+// ReadPriceFeed 读取价格源的最新价格，blockNumber 大于 0 时读取该区块上的价格
+func (bc *BlockchainClient) ReadPriceFeed(ctx context.Context, feedAddress string, blockNumber uint64) (*PriceFeedResult, error) {
+	batch := bc.NewBatch()
+	if blockNumber > 0 {
+		batch = bc.NewBatchAt(blockNumber)
+	}
+
+	result := batch.PriceFeed(feedAddress)
+	if err := batch.Execute(ctx); err != nil {
+		return nil, err
+	}
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return result, nil
+}
+
+func parseAggregatorV3ABI() (abi.ABI, error) {
+	return abi.JSON(strings.NewReader(AggregatorV3ABI))
+}
@@ -0,0 +1,37 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFeed = "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419"
+
+func TestReadPriceFeed(t *testing.T) {
+	node := newTestNode(t)
+	node.addFeed(testFeed, &fakeFeed{
+		decimals:  8,
+		answer:    250012345678,
+		updatedAt: 1700001200,
+		history:   map[uint64]int64{50: 200000000000},
+	})
+	client := newTestClient(t, node)
+
+	result, err := client.ReadPriceFeed(context.Background(), testFeed, 0)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(250012345678), result.Answer)
+	assert.Equal(t, uint8(8), result.Decimals)
+	assert.Equal(t, int64(1700001200), result.UpdatedAt.Unix())
+
+	result, err = client.ReadPriceFeed(context.Background(), testFeed, 50)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(200000000000), result.Answer)
+
+	// 不是价格源的合约
+	_, err = client.ReadPriceFeed(context.Background(), testUSDC, 0)
+	assert.ErrorIs(t, err, ErrCallFailed)
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/internal/model"
+)
+
+// 缓存后端
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+	BackendTiered = "tiered" // 进程内 LRU 在前，Redis 在后
+)
+
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache 是余额缓存的存储后端，未命中时返回 ErrCacheMiss
+type Cache interface {
+	SetTokenBalance(ctx context.Context, chainID int, walletAddress, tokenAddress string, balance *model.TokenBalance) error
+	GetTokenBalance(ctx context.Context, chainID int, walletAddress, tokenAddress string) (*model.TokenBalance, error)
+	SetNativeBalance(ctx context.Context, chainID int, walletAddress string, balance *model.TokenBalance) error
+	GetNativeBalance(ctx context.Context, chainID int, walletAddress string) (*model.TokenBalance, error)
+	DeleteTokenBalance(ctx context.Context, chainID int, walletAddress, tokenAddress string) error
+	DeleteUserBalances(ctx context.Context, wallets []model.Wallet) error
+	// 历史区块的余额不会再变化，写入后不过期；tokenAddress 为空表示原生币
+	SetHistoricalBalance(ctx context.Context, chainID int, blockNumber uint64, walletAddress, tokenAddress string, balance *model.TokenBalance) error
+	GetHistoricalBalance(ctx context.Context, chainID int, blockNumber uint64, walletAddress, tokenAddress string) (*model.TokenBalance, error)
+	// 资产价格，at 为 0 表示当前价格，按 cache.price_ttl 过期；带时间戳的历史价格按 cache.history_price_ttl 过期。
+	// 空字符串表示没有价格，用于避免重复查询
+	SetPrice(ctx context.Context, currency string, chainID int, tokenAddress string, at int64, price string) error
+	GetPrice(ctx context.Context, currency string, chainID int, tokenAddress string, at int64) (string, error)
+}
+
+const (
+	defaultPriceTTL        = 5 * time.Minute
+	defaultHistoryPriceTTL = 30 * 24 * time.Hour
+)
+
+// priceTTL 读取 cache.price_ttl，未配置时为 5 分钟
+func priceTTL(cacheCfg *config.CacheConfig) time.Duration {
+	ttl, err := time.ParseDuration(cacheCfg.PriceTTL)
+	if err != nil || ttl <= 0 {
+		return defaultPriceTTL
+	}
+	return ttl
+}
+
+// historyPriceTTL 读取 cache.history_price_ttl，未配置时为 30 天
+func historyPriceTTL(cacheCfg *config.CacheConfig) time.Duration {
+	ttl, err := time.ParseDuration(cacheCfg.HistoryPriceTTL)
+	if err != nil || ttl <= 0 {
+		return defaultHistoryPriceTTL
+	}
+	return ttl
+}
+
+// Purger 由持久化的后端实现，用于清理旧版本命名空间留下的 key
+type Purger interface {
+	PurgeStaleKeys(ctx context.Context) (int, error)
+}
+
+// New 按 cache.backend 创建缓存，只有 redis 和 tiered 需要连接 Redis
+func New(redisCfg *config.RedisConfig, cacheCfg *config.CacheConfig) (Cache, error) {
+	switch cacheCfg.Backend {
+	case "", BackendRedis:
+		return NewRedisClient(redisCfg, cacheCfg)
+	case BackendMemory:
+		return NewMemoryCache(cacheCfg), nil
+	case BackendTiered:
+		remote, err := NewRedisClient(redisCfg, cacheCfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewTieredCache(cacheCfg, remote), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cacheCfg.Backend)
+	}
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+
+	"wallet-tracker/internal/config"
+)
+
+// keyspace 生成带命名空间和版本号的缓存 key：
+//
+//	<namespace>:v<version>:balance:<chain_id>:<wallet>:<token|native>
+//	<namespace>:v<version>:wallet:<chain_id>:<wallet>   该钱包所有余额 key 的索引集合
+//	<namespace>:v<version>:history:<chain_id>:<block>:<wallet>:<token|native>   历史区块的余额，不过期
+//	<namespace>:v<version>:price:<currency>:<chain_id>:<token|native>[:<unix>]   资产价格，带时间戳的是历史价格
+//
+// 地址统一转为小写；提升版本号即可让旧 key 全部失效
+type keyspace struct {
+	namespace string
+	prefix    string
+}
+
+func newKeyspace(namespace string, version int) keyspace {
+	return keyspace{
+		namespace: namespace,
+		prefix:    fmt.Sprintf("%s:v%d:", namespace, version),
+	}
+}
+
+// keyspaceFromConfig 读取 cache.namespace 和 cache.version，未配置时使用 wt:v1
+func keyspaceFromConfig(cacheCfg *config.CacheConfig) keyspace {
+	namespace := cacheCfg.Namespace
+	if namespace == "" {
+		namespace = "wt"
+	}
+	version := cacheCfg.Version
+	if version <= 0 {
+		version = 1
+	}
+	return newKeyspace(namespace, version)
+}
+
+func (k keyspace) balance(chainID int, walletAddress, tokenAddress string) string {
+	token := "native"
+	if tokenAddress != "" {
+		token = strings.ToLower(tokenAddress)
+	}
+	return fmt.Sprintf("%sbalance:%d:%s:%s", k.prefix, chainID, strings.ToLower(walletAddress), token)
+}
+
+func (k keyspace) history(chainID int, blockNumber uint64, walletAddress, tokenAddress string) string {
+	token := "native"
+	if tokenAddress != "" {
+		token = strings.ToLower(tokenAddress)
+	}
+	return fmt.Sprintf("%shistory:%d:%d:%s:%s", k.prefix, chainID, blockNumber, strings.ToLower(walletAddress), token)
+}
+
+// price 的 at 为 0 表示当前价格
+func (k keyspace) price(currency string, chainID int, tokenAddress string, at int64) string {
+	token := "native"
+	if tokenAddress != "" {
+		token = strings.ToLower(tokenAddress)
+	}
+	key := fmt.Sprintf("%sprice:%s:%d:%s", k.prefix, strings.ToLower(currency), chainID, token)
+	if at != 0 {
+		key = fmt.Sprintf("%s:%d", key, at)
+	}
+	return key
+}
+
+// walletBalances 返回该钱包所有余额 key 的公共前缀
+func (k keyspace) walletBalances(chainID int, walletAddress string) string {
+	return fmt.Sprintf("%sbalance:%d:%s:", k.prefix, chainID, strings.ToLower(walletAddress))
+}
+
+func (k keyspace) walletIndex(chainID int, walletAddress string) string {
+	return fmt.Sprintf("%swallet:%d:%s", k.prefix, chainID, strings.ToLower(walletAddress))
+}
+
+// isStale 判断 key 是否属于本命名空间的旧版本
+func (k keyspace) isStale(key string) bool {
+	return strings.HasPrefix(key, k.namespace+":") && !strings.HasPrefix(key, k.prefix)
+}
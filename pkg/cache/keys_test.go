@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyspace_Balance(t *testing.T) {
+	keys := newKeyspace("wt", 2)
+
+	assert.Equal(t,
+		"wt:v2:balance:1:0xabc:0xdef",
+		keys.balance(1, "0xABC", "0xDeF"))
+	assert.Equal(t, "wt:v2:balance:56:0xabc:native", keys.balance(56, "0xAbc", ""))
+
+	// 同一地址在不同链上使用不同的 key
+	assert.NotEqual(t, keys.balance(1, "0xabc", "0xdef"), keys.balance(56, "0xabc", "0xdef"))
+	// 大小写不同的地址共用一个 key
+	assert.Equal(t, keys.balance(1, "0xABC", "0xDEF"), keys.balance(1, "0xabc", "0xdef"))
+
+	assert.Equal(t, "wt:v2:wallet:1:0xabc", keys.walletIndex(1, "0xABC"))
+}
+
+func TestKeyspace_IsStale(t *testing.T) {
+	keys := newKeyspace("wt", 2)
+
+	assert.True(t, keys.isStale("wt:v1:balance:1:0xabc:native"))
+	assert.True(t, keys.isStale("wt:v1:wallet:1:0xabc"))
+	assert.False(t, keys.isStale("wt:v2:balance:1:0xabc:native"))
+	assert.False(t, keys.isStale("other:v1:balance:1:0xabc:native"))
+	// v2 不能匹配 v20
+	assert.True(t, newKeyspace("wt", 20).isStale("wt:v2:balance:1:0xabc:native"))
+}
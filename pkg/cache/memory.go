@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/internal/model"
+)
+
+const defaultMaxEntries = 10000
+
+type memoryEntry struct {
+	key string
+	// model.TokenBalance 或价格字符串
+	value interface{}
+	// 为零值时不过期，只会被 LRU 淘汰
+	expiresAt time.Time
+}
+
+// MemoryCache 是进程内的 LRU 缓存，条目数超过上限时淘汰最久未使用的条目
+type MemoryCache struct {
+	ttl      time.Duration
+	priceTTL time.Duration
+	// 历史价格的过期时间
+	historyPriceTTL time.Duration
+	maxEntries      int
+	keys            keyspace
+	now             func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+func NewMemoryCache(cacheCfg *config.CacheConfig) *MemoryCache {
+	ttl, err := time.ParseDuration(cacheCfg.TokenBalanceTTL)
+	if err != nil {
+		ttl = 24 * time.Hour // 默认24小时
+	}
+
+	m := newMemoryCache(keyspaceFromConfig(cacheCfg), ttl, cacheCfg.MaxEntries)
+	m.priceTTL = priceTTL(cacheCfg)
+	m.historyPriceTTL = historyPriceTTL(cacheCfg)
+	return m
+}
+
+func newMemoryCache(keys keyspace, ttl time.Duration, maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxEntries
+	}
+
+	return &MemoryCache{
+		ttl:             ttl,
+		priceTTL:        defaultPriceTTL,
+		historyPriceTTL: defaultHistoryPriceTTL,
+		maxEntries:      maxEntries,
+		keys:            keys,
+		now:             time.Now,
+		entries:         make(map[string]*list.Element),
+		lru:             list.New(),
+	}
+}
+
+func (m *MemoryCache) SetTokenBalance(ctx context.Context, chainID int, walletAddress, tokenAddress string, balance *model.TokenBalance) error {
+	m.set(m.keys.balance(chainID, walletAddress, tokenAddress), *balance, m.ttl)
+	return nil
+}
+
+func (m *MemoryCache) GetTokenBalance(ctx context.Context, chainID int, walletAddress, tokenAddress string) (*model.TokenBalance, error) {
+	return m.getBalance(m.keys.balance(chainID, walletAddress, tokenAddress))
+}
+
+func (m *MemoryCache) SetNativeBalance(ctx context.Context, chainID int, walletAddress string, balance *model.TokenBalance) error {
+	m.set(m.keys.balance(chainID, walletAddress, ""), *balance, m.ttl)
+	return nil
+}
+
+func (m *MemoryCache) GetNativeBalance(ctx context.Context, chainID int, walletAddress string) (*model.TokenBalance, error) {
+	return m.getBalance(m.keys.balance(chainID, walletAddress, ""))
+}
+
+func (m *MemoryCache) SetHistoricalBalance(ctx context.Context, chainID int, blockNumber uint64, walletAddress, tokenAddress string, balance *model.TokenBalance) error {
+	m.set(m.keys.history(chainID, blockNumber, walletAddress, tokenAddress), *balance, 0)
+	return nil
+}
+
+func (m *MemoryCache) GetHistoricalBalance(ctx context.Context, chainID int, blockNumber uint64, walletAddress, tokenAddress string) (*model.TokenBalance, error) {
+	return m.getBalance(m.keys.history(chainID, blockNumber, walletAddress, tokenAddress))
+}
+
+// SetPrice 写入价格，at 为 0 表示当前价格，按 price_ttl 过期；历史价格按 history_price_ttl 过期
+func (m *MemoryCache) SetPrice(ctx context.Context, currency string, chainID int, tokenAddress string, at int64, price string) error {
+	ttl := m.priceTTL
+	if at != 0 {
+		ttl = m.historyPriceTTL
+	}
+	m.set(m.keys.price(currency, chainID, tokenAddress, at), price, ttl)
+	return nil
+}
+
+func (m *MemoryCache) GetPrice(ctx context.Context, currency string, chainID int, tokenAddress string, at int64) (string, error) {
+	value, err := m.get(m.keys.price(currency, chainID, tokenAddress, at))
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+func (m *MemoryCache) DeleteTokenBalance(ctx context.Context, chainID int, walletAddress, tokenAddress string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, exists := m.entries[m.keys.balance(chainID, walletAddress, tokenAddress)]; exists {
+		m.remove(elem)
+	}
+	return nil
+}
+
+func (m *MemoryCache) DeleteUserBalances(ctx context.Context, wallets []model.Wallet) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefixes := make([]string, len(wallets))
+	for i, wallet := range wallets {
+		prefixes[i] = m.keys.walletBalances(wallet.ChainID, wallet.Address)
+	}
+
+	for key, elem := range m.entries {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				m.remove(elem)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Len 返回当前缓存的条目数，包括已过期但尚未淘汰的条目
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len()
+}
+
+// set 写入条目，ttl 为 0 时不过期
+func (m *MemoryCache) set(key string, value interface{}, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = m.now().Add(ttl)
+	}
+	if elem, exists := m.entries[key]; exists {
+		entry := elem.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		m.lru.MoveToFront(elem)
+		return
+	}
+
+	m.entries[key] = m.lru.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for m.lru.Len() > m.maxEntries {
+		m.remove(m.lru.Back())
+	}
+}
+
+// getBalance 返回余额的副本，调用方修改结果不会影响缓存
+func (m *MemoryCache) getBalance(key string) (*model.TokenBalance, error) {
+	value, err := m.get(key)
+	if err != nil {
+		return nil, err
+	}
+	balance := value.(model.TokenBalance)
+	return &balance, nil
+}
+
+func (m *MemoryCache) get(key string) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, exists := m.entries[key]
+	if !exists {
+		return nil, ErrCacheMiss
+	}
+
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && !m.now().Before(entry.expiresAt) {
+		m.remove(elem)
+		return nil, ErrCacheMiss
+	}
+
+	m.lru.MoveToFront(elem)
+	return entry.value, nil
+}
+
+func (m *MemoryCache) remove(elem *list.Element) {
+	m.lru.Remove(elem)
+	delete(m.entries, elem.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMemoryCache(ttl time.Duration, maxEntries int) (*MemoryCache, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := newMemoryCache(newKeyspace("wt", 1), ttl, maxEntries)
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+func TestMemoryCache_SetGet(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestMemoryCache(time.Minute, 10)
+
+	_, err := m.GetTokenBalance(ctx, 1, "0xWallet", "0xToken")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	require.NoError(t, m.SetTokenBalance(ctx, 1, "0xWallet", "0xToken", &model.TokenBalance{Balance: "1.5"}))
+	require.NoError(t, m.SetNativeBalance(ctx, 1, "0xWallet", &model.TokenBalance{Balance: "2"}))
+
+	// 地址大小写不影响命中
+	balance, err := m.GetTokenBalance(ctx, 1, "0xwallet", "0xTOKEN")
+	require.NoError(t, err)
+	assert.Equal(t, "1.5", balance.Balance)
+
+	native, err := m.GetNativeBalance(ctx, 1, "0xWallet")
+	require.NoError(t, err)
+	assert.Equal(t, "2", native.Balance)
+
+	// 不同链互不影响
+	_, err = m.GetTokenBalance(ctx, 56, "0xWallet", "0xToken")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	// 修改返回值不影响缓存中的条目
+	balance.Status = model.BalanceStatusCached
+	again, err := m.GetTokenBalance(ctx, 1, "0xWallet", "0xToken")
+	require.NoError(t, err)
+	assert.Empty(t, again.Status)
+}
+
+func TestMemoryCache_Expiry(t *testing.T) {
+	ctx := context.Background()
+	m, now := newTestMemoryCache(time.Minute, 10)
+
+	require.NoError(t, m.SetNativeBalance(ctx, 1, "0xWallet", &model.TokenBalance{Balance: "1"}))
+
+	*now = now.Add(59 * time.Second)
+	_, err := m.GetNativeBalance(ctx, 1, "0xWallet")
+	require.NoError(t, err)
+
+	*now = now.Add(time.Second)
+	_, err = m.GetNativeBalance(ctx, 1, "0xWallet")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Zero(t, m.Len())
+}
+
+func TestMemoryCache_HistoricalBalances(t *testing.T) {
+	ctx := context.Background()
+	m, now := newTestMemoryCache(time.Minute, 10)
+
+	require.NoError(t, m.SetHistoricalBalance(ctx, 1, 100, "0xWallet", "", &model.TokenBalance{Balance: "1"}))
+	require.NoError(t, m.SetHistoricalBalance(ctx, 1, 200, "0xWallet", "", &model.TokenBalance{Balance: "2"}))
+
+	// 历史余额不过期，也不受清除钱包缓存的影响
+	*now = now.Add(365 * 24 * time.Hour)
+	require.NoError(t, m.DeleteUserBalances(ctx, []model.Wallet{{Address: "0xWallet", ChainID: 1}}))
+
+	balance, err := m.GetHistoricalBalance(ctx, 1, 100, "0xwallet", "")
+	require.NoError(t, err)
+	assert.Equal(t, "1", balance.Balance)
+
+	_, err = m.GetHistoricalBalance(ctx, 1, 300, "0xWallet", "")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = m.GetNativeBalance(ctx, 1, "0xWallet")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestMemoryCache_Prices(t *testing.T) {
+	ctx := context.Background()
+	m, now := newTestMemoryCache(time.Hour, 10)
+	m.priceTTL = time.Minute
+	m.historyPriceTTL = time.Hour
+
+	require.NoError(t, m.SetPrice(ctx, "usd", 1, "0xToken", 0, "1.01"))
+	require.NoError(t, m.SetPrice(ctx, "usd", 1, "", 1700000000, "2000"))
+	// 空字符串缓存“没有价格”
+	require.NoError(t, m.SetPrice(ctx, "usd", 56, "", 0, ""))
+
+	price, err := m.GetPrice(ctx, "USD", 1, "0xTOKEN", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "1.01", price)
+
+	price, err = m.GetPrice(ctx, "usd", 56, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "", price)
+
+	_, err = m.GetPrice(ctx, "eur", 1, "0xToken", 0)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	// 当前价格按 price_ttl 过期，历史价格按 history_price_ttl 过期
+	*now = now.Add(2 * time.Minute)
+	_, err = m.GetPrice(ctx, "usd", 1, "0xToken", 0)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	price, err = m.GetPrice(ctx, "usd", 1, "", 1700000000)
+	require.NoError(t, err)
+	assert.Equal(t, "2000", price)
+
+	*now = now.Add(time.Hour)
+	_, err = m.GetPrice(ctx, "usd", 1, "", 1700000000)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestMemoryCache(time.Minute, 2)
+
+	require.NoError(t, m.SetNativeBalance(ctx, 1, "0xA", &model.TokenBalance{Balance: "1"}))
+	require.NoError(t, m.SetNativeBalance(ctx, 1, "0xB", &model.TokenBalance{Balance: "2"}))
+
+	// 访问 A 之后 B 成为最久未使用的条目
+	_, err := m.GetNativeBalance(ctx, 1, "0xA")
+	require.NoError(t, err)
+	require.NoError(t, m.SetNativeBalance(ctx, 1, "0xC", &model.TokenBalance{Balance: "3"}))
+
+	assert.Equal(t, 2, m.Len())
+	_, err = m.GetNativeBalance(ctx, 1, "0xB")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = m.GetNativeBalance(ctx, 1, "0xA")
+	assert.NoError(t, err)
+	_, err = m.GetNativeBalance(ctx, 1, "0xC")
+	assert.NoError(t, err)
+}
+
+func TestMemoryCache_DeleteUserBalances(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestMemoryCache(time.Minute, 10)
+
+	require.NoError(t, m.SetNativeBalance(ctx, 1, "0xA", &model.TokenBalance{}))
+	require.NoError(t, m.SetTokenBalance(ctx, 1, "0xA", "0xToken", &model.TokenBalance{}))
+	require.NoError(t, m.SetNativeBalance(ctx, 56, "0xA", &model.TokenBalance{}))
+	require.NoError(t, m.SetNativeBalance(ctx, 1, "0xAB", &model.TokenBalance{}))
+
+	require.NoError(t, m.DeleteUserBalances(ctx, []model.Wallet{{Address: "0xa", ChainID: 1}}))
+
+	_, err := m.GetNativeBalance(ctx, 1, "0xA")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = m.GetTokenBalance(ctx, 1, "0xA", "0xToken")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	// 其它链和前缀相同的其它钱包不受影响
+	_, err = m.GetNativeBalance(ctx, 56, "0xA")
+	assert.NoError(t, err)
+	_, err = m.GetNativeBalance(ctx, 1, "0xAB")
+	assert.NoError(t, err)
+}
+
+func TestTieredCache(t *testing.T) {
+	ctx := context.Background()
+	remote, _ := newTestMemoryCache(time.Hour, 10)
+	tiered := NewTieredCache(&config.CacheConfig{LocalTTL: "1m"}, remote)
+
+	// 远端命中后回填本地层
+	require.NoError(t, remote.SetNativeBalance(ctx, 1, "0xA", &model.TokenBalance{Balance: "1"}))
+	balance, err := tiered.GetNativeBalance(ctx, 1, "0xA")
+	require.NoError(t, err)
+	assert.Equal(t, "1", balance.Balance)
+	assert.Equal(t, 1, tiered.local.Len())
+
+	// 写入同时到达两层
+	require.NoError(t, tiered.SetTokenBalance(ctx, 1, "0xA", "0xToken", &model.TokenBalance{Balance: "2"}))
+	_, err = remote.GetTokenBalance(ctx, 1, "0xA", "0xToken")
+	assert.NoError(t, err)
+
+	// 删除同时作用于两层
+	require.NoError(t, tiered.DeleteUserBalances(ctx, []model.Wallet{{Address: "0xA", ChainID: 1}}))
+	_, err = tiered.GetNativeBalance(ctx, 1, "0xA")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Zero(t, remote.Len())
+}
+
+func TestNew_SelectsBackend(t *testing.T) {
+	_, err := New(&config.RedisConfig{}, &config.CacheConfig{Backend: "memcached"})
+	assert.Error(t, err)
+
+	c, err := New(&config.RedisConfig{}, &config.CacheConfig{Backend: BackendMemory})
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryCache{}, c)
+}
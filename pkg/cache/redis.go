@@ -12,10 +12,19 @@ import (
 	"github.com/go-redis/redis/v8"
 )
 
+// 旧版本未带命名空间的 key
+const legacyBalancePattern = "balance:*"
+
+// 每次 SCAN 和批量删除的 key 数量
+const scanBatchSize = 500
+
 type RedisClient struct {
-	client *redis.Client
-	ctx    context.Context
-	ttl    time.Duration
+	client   *redis.Client
+	ttl      time.Duration
+	priceTTL time.Duration
+	// 历史价格的过期时间
+	historyPriceTTL time.Duration
+	keys            keyspace
 }
 
 func NewRedisClient(cfg *config.RedisConfig, cacheCfg *config.CacheConfig) (*RedisClient, error) {
@@ -25,7 +34,8 @@ func NewRedisClient(cfg *config.RedisConfig, cacheCfg *config.CacheConfig) (*Red
 		DB:       cfg.DB,
 	})
 
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
 	// 测试连接
 	if err := rdb.Ping(ctx).Err(); err != nil {
@@ -38,24 +48,54 @@ func NewRedisClient(cfg *config.RedisConfig, cacheCfg *config.CacheConfig) (*Red
 	}
 
 	return &RedisClient{
-		client: rdb,
-		ctx:    ctx,
-		ttl:    ttl,
+		client:          rdb,
+		ttl:             ttl,
+		priceTTL:        priceTTL(cacheCfg),
+		historyPriceTTL: historyPriceTTL(cacheCfg),
+		keys:            keyspaceFromConfig(cacheCfg),
 	}, nil
 }
 
-func (r *RedisClient) SetTokenBalance(walletAddress, tokenAddress string, balance *model.TokenBalance) error {
-	key := fmt.Sprintf("balance:%s:%s", walletAddress, tokenAddress)
+func (r *RedisClient) SetTokenBalance(ctx context.Context, chainID int, walletAddress, tokenAddress string, balance *model.TokenBalance) error {
+	return r.setBalance(ctx, chainID, walletAddress, tokenAddress, balance)
+}
+
+func (r *RedisClient) GetTokenBalance(ctx context.Context, chainID int, walletAddress, tokenAddress string) (*model.TokenBalance, error) {
+	return r.getBalance(ctx, r.keys.balance(chainID, walletAddress, tokenAddress))
+}
+
+func (r *RedisClient) SetNativeBalance(ctx context.Context, chainID int, walletAddress string, balance *model.TokenBalance) error {
+	return r.setBalance(ctx, chainID, walletAddress, "", balance)
+}
+
+func (r *RedisClient) GetNativeBalance(ctx context.Context, chainID int, walletAddress string) (*model.TokenBalance, error) {
+	return r.getBalance(ctx, r.keys.balance(chainID, walletAddress, ""))
+}
+
+// setBalance 写入余额，并把 key 记录到钱包的索引集合中，失效时无需扫描整个 keyspace
+func (r *RedisClient) setBalance(ctx context.Context, chainID int, walletAddress, tokenAddress string, balance *model.TokenBalance) error {
 	data, err := json.Marshal(balance)
 	if err != nil {
 		return err
 	}
-	return r.client.Set(r.ctx, key, data, r.ttl).Err()
+
+	key := r.keys.balance(chainID, walletAddress, tokenAddress)
+	index := r.keys.walletIndex(chainID, walletAddress)
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, data, r.ttl)
+		pipe.SAdd(ctx, index, key)
+		pipe.Expire(ctx, index, r.ttl)
+		return nil
+	})
+	return err
 }
 
-func (r *RedisClient) GetTokenBalance(walletAddress, tokenAddress string) (*model.TokenBalance, error) {
-	key := fmt.Sprintf("balance:%s:%s", walletAddress, tokenAddress)
-	data, err := r.client.Get(r.ctx, key).Result()
+func (r *RedisClient) getBalance(ctx context.Context, key string) (*model.TokenBalance, error) {
+	data, err := r.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, ErrCacheMiss
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -68,24 +108,110 @@ func (r *RedisClient) GetTokenBalance(walletAddress, tokenAddress string) (*mode
 	return &balance, nil
 }
 
-func (r *RedisClient) DeleteTokenBalance(walletAddress, tokenAddress string) error {
-	key := fmt.Sprintf("balance:%s:%s", walletAddress, tokenAddress)
-	return r.client.Del(r.ctx, key).Err()
+// SetHistoricalBalance 写入不过期的历史余额，不加入钱包索引，清除钱包缓存时保留
+func (r *RedisClient) SetHistoricalBalance(ctx context.Context, chainID int, blockNumber uint64, walletAddress, tokenAddress string, balance *model.TokenBalance) error {
+	data, err := json.Marshal(balance)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.keys.history(chainID, blockNumber, walletAddress, tokenAddress), data, 0).Err()
+}
+
+func (r *RedisClient) GetHistoricalBalance(ctx context.Context, chainID int, blockNumber uint64, walletAddress, tokenAddress string) (*model.TokenBalance, error) {
+	return r.getBalance(ctx, r.keys.history(chainID, blockNumber, walletAddress, tokenAddress))
 }
 
-func (r *RedisClient) DeleteUserBalances(walletAddresses []string) error {
+func (r *RedisClient) SetPrice(ctx context.Context, currency string, chainID int, tokenAddress string, at int64, price string) error {
+	ttl := r.priceTTL
+	if at != 0 {
+		ttl = r.historyPriceTTL
+	}
+	return r.client.Set(ctx, r.keys.price(currency, chainID, tokenAddress, at), price, ttl).Err()
+}
+
+func (r *RedisClient) GetPrice(ctx context.Context, currency string, chainID int, tokenAddress string, at int64) (string, error) {
+	price, err := r.client.Get(ctx, r.keys.price(currency, chainID, tokenAddress, at)).Result()
+	if err == redis.Nil {
+		return "", ErrCacheMiss
+	}
+	return price, err
+}
+
+func (r *RedisClient) DeleteTokenBalance(ctx context.Context, chainID int, walletAddress, tokenAddress string) error {
+	key := r.keys.balance(chainID, walletAddress, tokenAddress)
+	index := r.keys.walletIndex(chainID, walletAddress)
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.SRem(ctx, index, key)
+		return nil
+	})
+	return err
+}
+
+// DeleteUserBalances 通过索引集合删除这些钱包缓存的所有余额
+func (r *RedisClient) DeleteUserBalances(ctx context.Context, wallets []model.Wallet) error {
 	var keys []string
-	for _, address := range walletAddresses {
-		pattern := fmt.Sprintf("balance:%s:*", address)
-		matchedKeys, err := r.client.Keys(r.ctx, pattern).Result()
+	for _, wallet := range wallets {
+		index := r.keys.walletIndex(wallet.ChainID, wallet.Address)
+		members, err := r.client.SMembers(ctx, index).Result()
 		if err != nil {
-			continue
+			return err
 		}
-		keys = append(keys, matchedKeys...)
+		keys = append(keys, members...)
+		keys = append(keys, index)
 	}
 
-	if len(keys) > 0 {
-		return r.client.Del(r.ctx, keys...).Err()
+	for start := 0; start < len(keys); start += scanBatchSize {
+		end := start + scanBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := r.client.Del(ctx, keys[start:end]...).Err(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+// PurgeStaleKeys 用 SCAN 删除旧版本命名空间以及未带命名空间的旧 key，返回删除的数量。
+// 提升 cache.version 后在启动时调用，旧 key 即使不清理也会在 TTL 后过期
+func (r *RedisClient) PurgeStaleKeys(ctx context.Context) (int, error) {
+	deleted := 0
+
+	for _, pattern := range []string{r.keys.namespace + ":*", legacyBalancePattern} {
+		var batch []string
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := r.client.Unlink(ctx, batch...).Err(); err != nil {
+				return err
+			}
+			deleted += len(batch)
+			batch = batch[:0]
+			return nil
+		}
+
+		iter := r.client.Scan(ctx, 0, pattern, scanBatchSize).Iterator()
+		for iter.Next(ctx) {
+			key := iter.Val()
+			if pattern == legacyBalancePattern || r.keys.isStale(key) {
+				batch = append(batch, key)
+			}
+			if len(batch) >= scanBatchSize {
+				if err := flush(); err != nil {
+					return deleted, err
+				}
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return deleted, err
+		}
+		if err := flush(); err != nil {
+			return deleted, err
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/internal/model"
+)
+
+// TieredCache 在远端缓存前加一层进程内 LRU，热点余额不需要访问网络。
+// 本地层的 TTL 较短，多实例部署时各实例之间的不一致只会持续很短时间
+type TieredCache struct {
+	local  *MemoryCache
+	remote Cache
+}
+
+func NewTieredCache(cacheCfg *config.CacheConfig, remote Cache) *TieredCache {
+	localTTL, err := time.ParseDuration(cacheCfg.LocalTTL)
+	if err != nil {
+		localTTL = time.Minute
+	}
+
+	local := newMemoryCache(keyspaceFromConfig(cacheCfg), localTTL, cacheCfg.MaxEntries)
+	// 本地层的价格不比余额保存得更久
+	local.priceTTL = priceTTL(cacheCfg)
+	if localTTL < local.priceTTL {
+		local.priceTTL = localTTL
+	}
+	local.historyPriceTTL = historyPriceTTL(cacheCfg)
+	if localTTL < local.historyPriceTTL {
+		local.historyPriceTTL = localTTL
+	}
+
+	return &TieredCache{
+		local:  local,
+		remote: remote,
+	}
+}
+
+func (t *TieredCache) SetTokenBalance(ctx context.Context, chainID int, walletAddress, tokenAddress string, balance *model.TokenBalance) error {
+	t.local.SetTokenBalance(ctx, chainID, walletAddress, tokenAddress, balance)
+	return t.remote.SetTokenBalance(ctx, chainID, walletAddress, tokenAddress, balance)
+}
+
+func (t *TieredCache) GetTokenBalance(ctx context.Context, chainID int, walletAddress, tokenAddress string) (*model.TokenBalance, error) {
+	if balance, err := t.local.GetTokenBalance(ctx, chainID, walletAddress, tokenAddress); err == nil {
+		return balance, nil
+	}
+
+	balance, err := t.remote.GetTokenBalance(ctx, chainID, walletAddress, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	t.local.SetTokenBalance(ctx, chainID, walletAddress, tokenAddress, balance)
+	return balance, nil
+}
+
+func (t *TieredCache) SetNativeBalance(ctx context.Context, chainID int, walletAddress string, balance *model.TokenBalance) error {
+	t.local.SetNativeBalance(ctx, chainID, walletAddress, balance)
+	return t.remote.SetNativeBalance(ctx, chainID, walletAddress, balance)
+}
+
+func (t *TieredCache) GetNativeBalance(ctx context.Context, chainID int, walletAddress string) (*model.TokenBalance, error) {
+	if balance, err := t.local.GetNativeBalance(ctx, chainID, walletAddress); err == nil {
+		return balance, nil
+	}
+
+	balance, err := t.remote.GetNativeBalance(ctx, chainID, walletAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	t.local.SetNativeBalance(ctx, chainID, walletAddress, balance)
+	return balance, nil
+}
+
+func (t *TieredCache) SetHistoricalBalance(ctx context.Context, chainID int, blockNumber uint64, walletAddress, tokenAddress string, balance *model.TokenBalance) error {
+	t.local.SetHistoricalBalance(ctx, chainID, blockNumber, walletAddress, tokenAddress, balance)
+	return t.remote.SetHistoricalBalance(ctx, chainID, blockNumber, walletAddress, tokenAddress, balance)
+}
+
+func (t *TieredCache) GetHistoricalBalance(ctx context.Context, chainID int, blockNumber uint64, walletAddress, tokenAddress string) (*model.TokenBalance, error) {
+	if balance, err := t.local.GetHistoricalBalance(ctx, chainID, blockNumber, walletAddress, tokenAddress); err == nil {
+		return balance, nil
+	}
+
+	balance, err := t.remote.GetHistoricalBalance(ctx, chainID, blockNumber, walletAddress, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	t.local.SetHistoricalBalance(ctx, chainID, blockNumber, walletAddress, tokenAddress, balance)
+	return balance, nil
+}
+
+func (t *TieredCache) SetPrice(ctx context.Context, currency string, chainID int, tokenAddress string, at int64, price string) error {
+	t.local.SetPrice(ctx, currency, chainID, tokenAddress, at, price)
+	return t.remote.SetPrice(ctx, currency, chainID, tokenAddress, at, price)
+}
+
+func (t *TieredCache) GetPrice(ctx context.Context, currency string, chainID int, tokenAddress string, at int64) (string, error) {
+	if price, err := t.local.GetPrice(ctx, currency, chainID, tokenAddress, at); err == nil {
+		return price, nil
+	}
+
+	price, err := t.remote.GetPrice(ctx, currency, chainID, tokenAddress, at)
+	if err != nil {
+		return "", err
+	}
+
+	t.local.SetPrice(ctx, currency, chainID, tokenAddress, at, price)
+	return price, nil
+}
+
+func (t *TieredCache) DeleteTokenBalance(ctx context.Context, chainID int, walletAddress, tokenAddress string) error {
+	t.local.DeleteTokenBalance(ctx, chainID, walletAddress, tokenAddress)
+	return t.remote.DeleteTokenBalance(ctx, chainID, walletAddress, tokenAddress)
+}
+
+func (t *TieredCache) DeleteUserBalances(ctx context.Context, wallets []model.Wallet) error {
+	t.local.DeleteUserBalances(ctx, wallets)
+	return t.remote.DeleteUserBalances(ctx, wallets)
+}
+
+// PurgeStaleKeys 只需要清理远端缓存
+func (t *TieredCache) PurgeStaleKeys(ctx context.Context) (int, error) {
+	if purger, ok := t.remote.(Purger); ok {
+		return purger.PurgeStaleKeys(ctx)
+	}
+	return 0, nil
+}
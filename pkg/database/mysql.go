@@ -2,11 +2,15 @@ package database
 
 import (
 	"fmt"
+	"time"
+
 	"wallet-tracker/internal/config"
 	"wallet-tracker/internal/model"
 
+	"github.com/ethereum/go-ethereum/common"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func NewMySQLConnection(cfg *config.DatabaseConfig) (*gorm.DB, error) {
@@ -23,15 +27,93 @@ func NewMySQLConnection(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 		return nil, err
 	}
 
-	// 自动迁移
-	err = db.AutoMigrate(
+	if err := Migrate(db); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Migrate 自动迁移表结构，并把旧版本的数据迁移到新结构
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
 		&model.User{},
 		&model.Wallet{},
+		&model.Token{},
 		&model.WalletToken{},
+		&model.TokenList{},
+		&model.DiscoveryCursor{},
+		&model.TokenSuggestion{},
+		&model.NFTItem{},
+		&model.NFTSync{},
+		&model.BalanceSnapshot{},
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return db, nil
+	return migrateWalletTokenMetadata(db)
+}
+
+// migrateWalletTokenMetadata 把旧版本保存在 wallet_tokens 中的 token 元数据
+// 迁移到 tokens 表，然后删除这些列
+func migrateWalletTokenMetadata(db *gorm.DB) error {
+	legacyColumns := []string{"token_symbol", "token_name", "token_decimals"}
+	if !db.Migrator().HasColumn(&model.WalletToken{}, legacyColumns[0]) {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			ID            uint
+			ChainID       int
+			TokenAddress  string
+			TokenSymbol   string
+			TokenName     string
+			TokenDecimals int
+		}
+
+		err := tx.Table("wallet_tokens").
+			Select("wallet_tokens.id, wallets.chain_id, wallet_tokens.token_address, wallet_tokens.token_symbol, wallet_tokens.token_name, wallet_tokens.token_decimals").
+			Joins("JOIN wallets ON wallets.id = wallet_tokens.wallet_id").
+			Where("wallet_tokens.token_id = 0 OR wallet_tokens.token_id IS NULL").
+			Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			token := model.Token{
+				ChainID:     row.ChainID,
+				Address:     common.HexToAddress(row.TokenAddress).Hex(),
+				Symbol:      row.TokenSymbol,
+				Name:        row.TokenName,
+				Decimals:    row.TokenDecimals,
+				Status:      model.TokenStatusUnverified,
+				FirstSeenAt: time.Now(),
+			}
+			// 同一个 token 被多个钱包跟踪时只保留第一条元数据
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&token).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("chain_id = ? AND address = ?", token.ChainID, token.Address).First(&token).Error; err != nil {
+				return err
+			}
+
+			err := tx.Table("wallet_tokens").Where("id = ?", row.ID).Updates(map[string]interface{}{
+				"token_id":      token.ID,
+				"token_address": token.Address,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, column := range legacyColumns {
+			if err := tx.Migrator().DropColumn(&model.WalletToken{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
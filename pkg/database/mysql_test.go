@@ -0,0 +1,75 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// legacyWalletToken 是元数据仍保存在 wallet_tokens 中时的表结构
+type legacyWalletToken struct {
+	ID            uint `gorm:"primarykey"`
+	WalletID      uint
+	TokenAddress  string
+	TokenSymbol   string
+	TokenName     string
+	TokenDecimals int
+	IsActive      bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt
+}
+
+func (legacyWalletToken) TableName() string {
+	return "wallet_tokens"
+}
+
+func TestMigrate_MovesLegacyTokenMetadata(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Wallet{}, &legacyWalletToken{}))
+
+	user := &model.User{Username: "legacy", Email: "legacy@example.com", Password: "x"}
+	require.NoError(t, db.Create(user).Error)
+	eth := &model.Wallet{UserID: user.ID, Address: "0x1", ChainID: 1, ChainName: "Ethereum"}
+	other := &model.Wallet{UserID: user.ID, Address: "0x2", ChainID: 1, ChainName: "Ethereum"}
+	bsc := &model.Wallet{UserID: user.ID, Address: "0x1", ChainID: 56, ChainName: "BSC"}
+	require.NoError(t, db.Create([]*model.Wallet{eth, other, bsc}).Error)
+
+	usdc := "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	require.NoError(t, db.Create([]*legacyWalletToken{
+		{WalletID: eth.ID, TokenAddress: usdc, TokenSymbol: "USDC", TokenName: "USD Coin", TokenDecimals: 6, IsActive: true},
+		{WalletID: other.ID, TokenAddress: usdc, TokenSymbol: "usdc typo", TokenName: "USD Coin", TokenDecimals: 18, IsActive: true},
+		{WalletID: bsc.ID, TokenAddress: usdc, TokenSymbol: "USDC", TokenName: "USD Coin", TokenDecimals: 18, IsActive: true},
+	}).Error)
+
+	require.NoError(t, Migrate(db))
+
+	// 同一条链上的同一个 token 只保存一份元数据，不同链各自一份
+	var tokens []model.Token
+	require.NoError(t, db.Order("chain_id").Find(&tokens).Error)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", tokens[0].Address)
+	assert.Equal(t, "USDC", tokens[0].Symbol)
+	assert.Equal(t, 6, tokens[0].Decimals)
+	assert.Equal(t, 56, tokens[1].ChainID)
+
+	var walletTokens []model.WalletToken
+	require.NoError(t, db.Preload("Token").Order("id").Find(&walletTokens).Error)
+	require.Len(t, walletTokens, 3)
+	assert.Equal(t, tokens[0].ID, walletTokens[0].TokenID)
+	assert.Equal(t, tokens[0].ID, walletTokens[1].TokenID)
+	assert.Equal(t, tokens[1].ID, walletTokens[2].TokenID)
+	assert.Equal(t, "USD Coin", walletTokens[2].Token.Name)
+
+	assert.False(t, db.Migrator().HasColumn(&model.WalletToken{}, "token_symbol"))
+
+	// 再次迁移不做任何事
+	require.NoError(t, Migrate(db))
+}
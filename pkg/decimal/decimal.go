@@ -0,0 +1,210 @@
+// Package decimal 提供精确的定点小数运算，用于余额和金额的换算与汇总。
+// 与 big.Float 不同，所有运算都不会舍入，格式化结果也不会出现科学计数法
+package decimal
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Decimal 表示 value × 10^-scale，零值等于 0
+type Decimal struct {
+	value *big.Int
+	scale int
+}
+
+var ten = big.NewInt(10)
+
+// New 以最小单位金额和小数位创建 Decimal，例如 New(1500000, 6) 等于 1.5
+func New(value *big.Int, scale int) Decimal {
+	if scale < 0 {
+		value = new(big.Int).Mul(value, pow10(-scale))
+		scale = 0
+	}
+	return Decimal{value: new(big.Int).Set(value), scale: scale}
+}
+
+// NewFromInt64 以整数创建 Decimal
+func NewFromInt64(value int64) Decimal {
+	return Decimal{value: big.NewInt(value)}
+}
+
+// Parse 解析 "123"、"-0.05"、"1.500" 形式的十进制字符串
+func Parse(s string) (Decimal, error) {
+	text := strings.TrimSpace(s)
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "-"), "+")
+
+	intPart, fracPart, hasPoint := strings.Cut(text, ".")
+	if intPart == "" && fracPart == "" || hasPoint && fracPart == "" {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	for _, r := range intPart + fracPart {
+		if r < '0' || r > '9' {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+		}
+	}
+
+	value, _ := new(big.Int).SetString(intPart+fracPart, 10)
+	if negative {
+		value.Neg(value)
+	}
+	return Decimal{value: value, scale: len(fracPart)}, nil
+}
+
+// MustParse 与 Parse 相同，解析失败时 panic，只用于常量
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func (d Decimal) bigInt() *big.Int {
+	if d.value == nil {
+		return new(big.Int)
+	}
+	return d.value
+}
+
+// Scale 返回小数位数
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Raw 返回按 scale 放大后的整数，例如 1.5（scale 6）返回 1500000
+func (d Decimal) Raw() *big.Int {
+	return new(big.Int).Set(d.bigInt())
+}
+
+func (d Decimal) Add(o Decimal) Decimal {
+	a, b, scale := align(d, o)
+	return Decimal{value: a.Add(a, b), scale: scale}
+}
+
+func (d Decimal) Sub(o Decimal) Decimal {
+	a, b, scale := align(d, o)
+	return Decimal{value: a.Sub(a, b), scale: scale}
+}
+
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{value: new(big.Int).Mul(d.bigInt(), o.bigInt()), scale: d.scale + o.scale}
+}
+
+// Cmp 比较大小，返回 -1、0 或 1
+func (d Decimal) Cmp(o Decimal) int {
+	a, b, _ := align(d, o)
+	return a.Cmp(b)
+}
+
+func (d Decimal) Sign() int {
+	return d.bigInt().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Round 按四舍五入保留 places 位小数，只用于展示
+func (d Decimal) Round(places int) Decimal {
+	if places >= d.scale {
+		return d
+	}
+
+	divisor := pow10(d.scale - places)
+	quotient, remainder := new(big.Int).QuoRem(d.bigInt(), divisor, new(big.Int))
+
+	// 余数的两倍不小于除数时进位，负数向远离 0 的方向进位
+	remainder.Abs(remainder).Lsh(remainder, 1)
+	if remainder.Cmp(divisor) >= 0 {
+		if d.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	return Decimal{value: quotient, scale: places}
+}
+
+// String 返回不含指数的完整十进制表示，去掉小数部分末尾的 0
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.bigInt()).String()
+	sign := ""
+	if d.Sign() < 0 {
+		sign = "-"
+	}
+
+	if d.scale == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= d.scale {
+		digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+	}
+	intPart := digits[:len(digits)-d.scale]
+	fracPart := strings.TrimRight(digits[len(digits)-d.scale:], "0")
+
+	if fracPart == "" {
+		return sign + intPart
+	}
+	return sign + intPart + "." + fracPart
+}
+
+// StringFixed 返回恰好 places 位小数的表示，必要时四舍五入
+func (d Decimal) StringFixed(places int) string {
+	rounded := d.Round(places)
+	s := rounded.String()
+	if places <= 0 {
+		return s
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	return intPart + "." + fracPart + strings.Repeat("0", places-len(fracPart))
+}
+
+// MarshalJSON 以字符串输出，避免 JSON 数字的精度损失
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	parsed, err := Parse(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Sum 返回所有值的和
+func Sum(values ...Decimal) Decimal {
+	var total Decimal
+	for _, v := range values {
+		total = total.Add(v)
+	}
+	return total
+}
+
+// align 把两个数放大到相同的小数位，返回可修改的副本
+func align(a, b Decimal) (*big.Int, *big.Int, int) {
+	x := new(big.Int).Set(a.bigInt())
+	y := new(big.Int).Set(b.bigInt())
+
+	switch {
+	case a.scale < b.scale:
+		x.Mul(x, pow10(b.scale-a.scale))
+		return x, y, b.scale
+	case a.scale > b.scale:
+		y.Mul(y, pow10(a.scale-b.scale))
+		return x, y, a.scale
+	default:
+		return x, y, a.scale
+	}
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(ten, big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,119 @@
+package decimal
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bigInt(t *testing.T, s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 10)
+	require.True(t, ok)
+	return v
+}
+
+func TestNew_String(t *testing.T) {
+	tests := []struct {
+		raw   string
+		scale int
+		want  string
+	}{
+		{"0", 18, "0"},
+		{"1500000", 6, "1.5"},
+		{"1", 18, "0.000000000000000001"},
+		{"1234500000000", 6, "1234500"},
+		{"-250", 2, "-2.5"},
+		{"42", 0, "42"},
+		{"7", -2, "700"},
+		// 大额 18 位小数代币：big.Float 默认精度会丢失末位并输出指数
+		{"123456789012345678901234567890123", 18, "123456789012345.678901234567890123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, New(bigInt(t, tt.raw), tt.scale).String())
+		})
+	}
+
+	assert.Equal(t, "0", Decimal{}.String())
+}
+
+func TestParse(t *testing.T) {
+	for input, want := range map[string]string{
+		"0":          "0",
+		"1.500":      "1.5",
+		"-0.05":      "-0.05",
+		"+3":         "3",
+		".5":         "0.5",
+		" 12.34 ":    "12.34",
+		"1000000000": "1000000000",
+	} {
+		d, err := Parse(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, d.String(), input)
+	}
+
+	for _, input := range []string{"", "-", "1.", "1e5", "abc", "1.2.3", "0x10"} {
+		_, err := Parse(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a := MustParse("0.1")
+	b := MustParse("0.2")
+
+	assert.Equal(t, "0.3", a.Add(b).String())
+	assert.Equal(t, "-0.1", a.Sub(b).String())
+	assert.Equal(t, "0.02", a.Mul(b).String())
+	assert.Equal(t, -1, a.Cmp(b))
+	assert.Equal(t, 0, MustParse("1.50").Cmp(MustParse("1.5")))
+	assert.True(t, MustParse("0.000").IsZero())
+
+	// 不同小数位的余额相加不会产生误差
+	total := Sum(
+		New(bigInt(t, "1000000000000000001"), 18),
+		New(bigInt(t, "2500000"), 6),
+		MustParse("0.1"),
+	)
+	assert.Equal(t, "3.600000000000000001", total.String())
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		input  string
+		places int
+		want   string
+	}{
+		{"1.005", 2, "1.01"},
+		{"1.004", 2, "1"},
+		{"-1.005", 2, "-1.01"},
+		{"2.5", 0, "3"},
+		{"1.2", 4, "1.2"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MustParse(tt.input).Round(tt.places).String(), tt.input)
+	}
+
+	assert.Equal(t, "1.50", MustParse("1.5").StringFixed(2))
+	assert.Equal(t, "0.00", Decimal{}.StringFixed(2))
+	assert.Equal(t, "1234.57", MustParse("1234.5678").StringFixed(2))
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Value Decimal `json:"value"`
+	}{MustParse("12.50")})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"value":"12.5"}`, string(data))
+
+	var decoded struct {
+		Value Decimal `json:"value"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"value":"0.000001"}`), &decoded))
+	assert.Equal(t, "0.000001", decoded.Value.String())
+}
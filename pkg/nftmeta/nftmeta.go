@@ -0,0 +1,270 @@
+// Package nftmeta 下载 tokenURI 指向的 NFT 元数据，ipfs:// 和 ar:// 通过配置的网关读取
+package nftmeta
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"wallet-tracker/internal/config"
+)
+
+const (
+	DefaultIPFSGateway     = "https://ipfs.io"
+	DefaultArweaveGateway  = "https://arweave.net"
+	DefaultMetadataTimeout = 10 * time.Second
+
+	// 元数据 JSON 的大小上限，超过的按错误处理
+	maxMetadataSize = 1 << 20
+)
+
+var (
+	ErrUnsupportedURI = errors.New("unsupported metadata URI")
+	// tokenURI 由合约提供，不允许指向内网、本机或链路本地地址
+	ErrForbiddenAddress = errors.New("metadata URI resolves to a non-public address")
+)
+
+// IsGlobalUnicast 之外还需要拒绝的保留地址段
+var reservedNets = mustParseCIDRs(
+	"0.0.0.0/8",     // 本网络
+	"100.64.0.0/10", // 运营商级 NAT
+	"192.0.0.0/24",  // IETF 协议分配
+	"198.18.0.0/15", // 基准测试
+	"64:ff9b::/96",  // NAT64，可映射到内网 IPv4
+)
+
+// Metadata 是 ERC-721 / ERC-1155 元数据 JSON 中常用的字段
+type Metadata struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Image       string          `json:"image"`
+	ExternalURL string          `json:"external_url"`
+	Attributes  json.RawMessage `json:"attributes,omitempty"`
+}
+
+// Fetcher 下载并解析元数据，可以并发使用
+type Fetcher struct {
+	ipfsGateway    string
+	arweaveGateway string
+	client         *http.Client
+
+	dialer *net.Dialer
+	// 配置的网关是可信的，可以是本地的 IPFS 节点，按 host:port 匹配
+	trusted map[string]bool
+}
+
+func NewFetcher(cfg *config.NFTConfig) (*Fetcher, error) {
+	timeout := DefaultMetadataTimeout
+	if cfg.MetadataTimeout != "" {
+		parsed, err := time.ParseDuration(cfg.MetadataTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("nft.metadata_timeout: %w", err)
+		}
+		timeout = parsed
+	}
+
+	ipfsGateway := cfg.IPFSGateway
+	if ipfsGateway == "" {
+		ipfsGateway = DefaultIPFSGateway
+	}
+	arweaveGateway := cfg.ArweaveGateway
+	if arweaveGateway == "" {
+		arweaveGateway = DefaultArweaveGateway
+	}
+
+	f := &Fetcher{
+		ipfsGateway:    strings.TrimRight(ipfsGateway, "/"),
+		arweaveGateway: strings.TrimRight(arweaveGateway, "/"),
+		dialer:         &net.Dialer{Timeout: timeout},
+		trusted:        make(map[string]bool),
+	}
+	for _, gateway := range []string{f.ipfsGateway, f.arweaveGateway} {
+		if addr, ok := dialAddress(gateway); ok {
+			f.trusted[addr] = true
+		}
+	}
+
+	// 不使用环境变量中的代理，所有连接都经过 dialContext 的检查，包括重定向
+	f.client = &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: f.dialContext, ForceAttemptHTTP2: true},
+	}
+	return f, nil
+}
+
+// dialContext 解析主机并拒绝非公网地址，防止合约通过 tokenURI 让服务端访问内网（SSRF）。
+// 检查通过后直接连接解析得到的地址，避免再次解析时得到不同的结果
+func (f *Fetcher) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if f.trusted[strings.ToLower(addr)] {
+		return f.dialer.DialContext(ctx, network, addr)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip.IP) {
+			return nil, fmt.Errorf("%w: %s (%s)", ErrForbiddenAddress, host, ip.IP)
+		}
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := f.dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// isPublicIP 判断地址是否是公网单播地址
+func isPublicIP(ip net.IP) bool {
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, reserved := range reservedNets {
+		if reserved.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialAddress 返回 URL 连接时使用的 host:port
+func dialAddress(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return "", false
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return strings.ToLower(net.JoinHostPort(u.Hostname(), port)), true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// ResolveURI 把 ipfs:// 和 ar:// 改写为网关上的 HTTP 地址，其它 URI 原样返回
+func (f *Fetcher) ResolveURI(uri string) string {
+	uri = strings.TrimSpace(uri)
+	switch {
+	case strings.HasPrefix(uri, "ipfs://"):
+		path := strings.TrimPrefix(uri, "ipfs://")
+		// 部分合约写成 ipfs://ipfs/<cid>
+		path = strings.TrimPrefix(path, "ipfs/")
+		return f.ipfsGateway + "/ipfs/" + path
+	case strings.HasPrefix(uri, "/ipfs/"):
+		return f.ipfsGateway + uri
+	case strings.HasPrefix(uri, "ar://"):
+		return f.arweaveGateway + "/" + strings.TrimPrefix(uri, "ar://")
+	default:
+		return uri
+	}
+}
+
+// Fetch 读取并解析元数据。支持 http(s)、ipfs、ar 和 data: URI
+func (f *Fetcher) Fetch(ctx context.Context, uri string) (*Metadata, error) {
+	var data []byte
+	var err error
+
+	resolved := f.ResolveURI(uri)
+	switch {
+	case strings.HasPrefix(resolved, "data:"):
+		data, err = decodeDataURI(resolved)
+	case strings.HasPrefix(resolved, "http://"), strings.HasPrefix(resolved, "https://"):
+		data, err = f.get(ctx, resolved)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedURI, uri)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		Metadata
+		ImageURL string `json:"image_url"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("decode metadata: %w", err)
+	}
+
+	metadata := raw.Metadata
+	if metadata.Image == "" {
+		metadata.Image = raw.ImageURL
+	}
+	metadata.Image = f.ResolveURI(metadata.Image)
+	return &metadata, nil
+}
+
+func (f *Fetcher) get(ctx context.Context, uri string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch metadata: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxMetadataSize {
+		return nil, fmt.Errorf("fetch metadata: response larger than %d bytes", maxMetadataSize)
+	}
+	return data, nil
+}
+
+// decodeDataURI 解析链上存储的 data:application/json[;base64],... 元数据
+func decodeDataURI(uri string) ([]byte, error) {
+	header, payload, found := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !found {
+		return nil, fmt.Errorf("%w: malformed data URI", ErrUnsupportedURI)
+	}
+
+	if strings.HasSuffix(header, ";base64") {
+		return base64.StdEncoding.DecodeString(payload)
+	}
+	decoded, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(decoded), nil
+}
@@ -0,0 +1,128 @@
+package nftmeta
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wallet-tracker/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveURI(t *testing.T) {
+	fetcher, err := NewFetcher(&config.NFTConfig{IPFSGateway: "https://gateway.example/"})
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		"ipfs://QmHash/1.json":                   "https://gateway.example/ipfs/QmHash/1.json",
+		"ipfs://ipfs/QmHash/1.json":              "https://gateway.example/ipfs/QmHash/1.json",
+		"/ipfs/QmHash":                           "https://gateway.example/ipfs/QmHash",
+		"ar://tx-id":                             "https://arweave.net/tx-id",
+		"https://api.example.com/token/1":        "https://api.example.com/token/1",
+		"data:application/json,{\"name\":\"x\"}": "data:application/json,{\"name\":\"x\"}",
+	}
+	for uri, want := range tests {
+		assert.Equal(t, want, fetcher.ResolveURI(uri), uri)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ipfs/QmHash/1":
+			w.Write([]byte(`{"name":"Punk #1","description":"A punk","image":"ipfs://QmImage/1.png","attributes":[{"trait_type":"Hat","value":"Cap"}]}`))
+		case "/image-url":
+			w.Write([]byte(`{"name":"Legacy","image_url":"https://example.com/1.png"}`))
+		case "/garbage":
+			w.Write([]byte(`<html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher, err := NewFetcher(&config.NFTConfig{IPFSGateway: server.URL})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	metadata, err := fetcher.Fetch(ctx, "ipfs://QmHash/1")
+	require.NoError(t, err)
+	assert.Equal(t, "Punk #1", metadata.Name)
+	assert.Equal(t, server.URL+"/ipfs/QmImage/1.png", metadata.Image)
+	assert.JSONEq(t, `[{"trait_type":"Hat","value":"Cap"}]`, string(metadata.Attributes))
+
+	metadata, err = fetcher.Fetch(ctx, server.URL+"/image-url")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/1.png", metadata.Image)
+
+	// 链上存储的元数据
+	encoded := base64.StdEncoding.EncodeToString([]byte(`{"name":"On-chain"}`))
+	metadata, err = fetcher.Fetch(ctx, "data:application/json;base64,"+encoded)
+	require.NoError(t, err)
+	assert.Equal(t, "On-chain", metadata.Name)
+
+	metadata, err = fetcher.Fetch(ctx, `data:application/json,%7B%22name%22%3A%22Plain%22%7D`)
+	require.NoError(t, err)
+	assert.Equal(t, "Plain", metadata.Name)
+
+	_, err = fetcher.Fetch(ctx, server.URL+"/missing")
+	assert.Error(t, err)
+	_, err = fetcher.Fetch(ctx, server.URL+"/garbage")
+	assert.Error(t, err)
+	_, err = fetcher.Fetch(ctx, "ftp://example.com/1")
+	assert.ErrorIs(t, err, ErrUnsupportedURI)
+}
+
+func TestFetch_ForbiddenAddress(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"internal"}`))
+	}))
+	defer target.Close()
+
+	// 配置的网关可以是本地地址，但重定向到其它内网地址时同样被拒绝
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(target.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer gateway.Close()
+
+	fetcher, err := NewFetcher(&config.NFTConfig{IPFSGateway: gateway.URL})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, uri := range []string{
+		target.URL + "/1",
+		strings.Replace(target.URL, "127.0.0.1", "localhost", 1) + "/1",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/1",
+		"ipfs://QmHash/1",
+	} {
+		_, err := fetcher.Fetch(ctx, uri)
+		assert.ErrorIs(t, err, ErrForbiddenAddress, uri)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for ip, public := range map[string]bool{
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		assert.Equal(t, public, isPublicIP(net.ParseIP(ip)), ip)
+	}
+}
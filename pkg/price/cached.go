@@ -0,0 +1,91 @@
+package price
+
+import (
+	"context"
+	"time"
+
+	"wallet-tracker/pkg/cache"
+	"wallet-tracker/pkg/decimal"
+)
+
+const (
+	// 距今不到 historySettle 的历史价格可能还会被行情源修正，不写入缓存
+	historySettle = time.Hour
+	// 历史价格按整点取，同一资产每小时只有一个缓存条目
+	historyBucket = time.Hour
+)
+
+// CachedProvider 在价格来源前加一层缓存。当前价格按 cache.price_ttl 过期，
+// 没有价格的结果也会缓存，避免每次请求都查询行情源。历史价格取所在小时整点的价格，
+// 按 cache.history_price_ttl 过期
+type CachedProvider struct {
+	provider PriceProvider
+	cache    cache.Cache
+	now      func() time.Time
+}
+
+func NewCachedProvider(provider PriceProvider, c cache.Cache) *CachedProvider {
+	return &CachedProvider{provider: provider, cache: c, now: time.Now}
+}
+
+// Preload 转给被缓存的来源
+func (cp *CachedProvider) Preload(ctx context.Context, currency string, from, to time.Time, assets []Asset) error {
+	if preloader, ok := cp.provider.(Preloader); ok {
+		return preloader.Preload(ctx, currency, from.Truncate(historyBucket), to, assets)
+	}
+	return nil
+}
+
+func (cp *CachedProvider) Prices(ctx context.Context, currency string, at time.Time, assets []Asset) (map[Asset]decimal.Decimal, error) {
+	var stamp int64
+	if !at.IsZero() {
+		at = at.Truncate(historyBucket)
+		stamp = at.Unix()
+	}
+	// 历史价格只缓存已经确定的，负结果不缓存
+	cacheable := at.IsZero() || cp.now().Sub(at) >= historySettle
+
+	prices := make(map[Asset]decimal.Decimal)
+	var missing []Asset
+	for _, asset := range assets {
+		cached, err := cp.cache.GetPrice(ctx, currency, asset.ChainID, asset.Address, stamp)
+		if err != nil {
+			missing = append(missing, asset)
+			continue
+		}
+		if cached == "" {
+			continue
+		}
+		if price, err := decimal.Parse(cached); err == nil {
+			prices[asset] = price
+		} else {
+			missing = append(missing, asset)
+		}
+	}
+	if len(missing) == 0 {
+		return prices, nil
+	}
+
+	found, err := cp.provider.Prices(ctx, currency, at, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, asset := range missing {
+		price, ok := found[asset]
+		if ok {
+			prices[asset] = price
+		}
+		if !cacheable || (!ok && !at.IsZero()) {
+			continue
+		}
+
+		value := ""
+		if ok {
+			value = price.String()
+		}
+		// 缓存写入失败只影响下一次请求
+		cp.cache.SetPrice(ctx, currency, asset.ChainID, asset.Address, stamp, value)
+	}
+	return prices, nil
+}
@@ -0,0 +1,98 @@
+package price
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/pkg/cache"
+	"wallet-tracker/pkg/decimal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingProvider 记录每次被查询的资产
+type countingProvider struct {
+	prices map[Asset]decimal.Decimal
+	calls  [][]Asset
+	err    error
+}
+
+func (cp *countingProvider) Prices(ctx context.Context, currency string, at time.Time, assets []Asset) (map[Asset]decimal.Decimal, error) {
+	cp.calls = append(cp.calls, assets)
+	if cp.err != nil {
+		return nil, cp.err
+	}
+	prices := make(map[Asset]decimal.Decimal)
+	for _, asset := range assets {
+		if price, ok := cp.prices[asset]; ok {
+			prices[asset] = price
+		}
+	}
+	return prices, nil
+}
+
+func TestCachedProvider(t *testing.T) {
+	ctx := context.Background()
+	eth := NewAsset(1, "")
+	unknown := NewAsset(56, "")
+	source := &countingProvider{prices: map[Asset]decimal.Decimal{eth: decimal.MustParse("2000")}}
+	provider := NewCachedProvider(source, cache.NewMemoryCache(&config.CacheConfig{}))
+
+	for i := 0; i < 2; i++ {
+		prices, err := provider.Prices(ctx, "usd", time.Time{}, []Asset{eth, unknown})
+		require.NoError(t, err)
+		assert.Equal(t, "2000", prices[eth].String())
+		assert.NotContains(t, prices, unknown)
+	}
+	// 第二次请求全部命中缓存，包括没有价格的资产
+	assert.Len(t, source.calls, 1)
+
+	// 确定的历史价格缓存，刚过去的时间不缓存
+	old := time.Now().Add(-24 * time.Hour)
+	recent := time.Now().Add(-time.Minute)
+	for i := 0; i < 2; i++ {
+		_, err := provider.Prices(ctx, "usd", old, []Asset{eth})
+		require.NoError(t, err)
+		_, err = provider.Prices(ctx, "usd", recent, []Asset{eth})
+		require.NoError(t, err)
+	}
+	assert.Len(t, source.calls, 4)
+}
+
+func TestCachedProvider_HistoryBuckets(t *testing.T) {
+	ctx := context.Background()
+	eth := NewAsset(1, "")
+	source := &recordingTimes{countingProvider: countingProvider{prices: map[Asset]decimal.Decimal{eth: decimal.MustParse("2000")}}}
+	memory := cache.NewMemoryCache(&config.CacheConfig{})
+	provider := NewCachedProvider(source, memory)
+
+	// 同一小时内的时间点共用一个缓存条目，按整点查询
+	hour := time.Now().Add(-48 * time.Hour).Truncate(time.Hour)
+	for _, minutes := range []int{0, 15, 59} {
+		prices, err := provider.Prices(ctx, "usd", hour.Add(time.Duration(minutes)*time.Minute), []Asset{eth})
+		require.NoError(t, err)
+		assert.Equal(t, "2000", prices[eth].String())
+	}
+	require.Len(t, source.times, 1)
+	assert.True(t, source.times[0].Equal(hour))
+	assert.Equal(t, 1, memory.Len())
+
+	_, err := provider.Prices(ctx, "usd", hour.Add(time.Hour), []Asset{eth})
+	require.NoError(t, err)
+	assert.Len(t, source.times, 2)
+	assert.Equal(t, 2, memory.Len())
+}
+
+// recordingTimes 记录每次查询的时间
+type recordingTimes struct {
+	countingProvider
+	times []time.Time
+}
+
+func (rt *recordingTimes) Prices(ctx context.Context, currency string, at time.Time, assets []Asset) (map[Asset]decimal.Decimal, error) {
+	rt.times = append(rt.times, at)
+	return rt.countingProvider.Prices(ctx, currency, at, assets)
+}
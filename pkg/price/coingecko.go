@@ -0,0 +1,394 @@
+package price
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/pkg/decimal"
+)
+
+const (
+	defaultCoinGeckoURL    = "https://api.coingecko.com/api/v3"
+	defaultCoinGeckoHeader = "x-cg-demo-api-key"
+	// 一次 token_price 请求最多包含的合约地址数
+	coinGeckoBatchSize = 50
+	// 历史价格在 at 之前这段时间内查找最近的一个点
+	coinGeckoHistoryWindow = 48 * time.Hour
+)
+
+// 接口上没有这种资产
+var errCoinGeckoNotFound = errors.New("not found")
+
+// coinGeckoChain 是一条链在 CoinGecko 中的 asset platform 和原生币的 coin id
+type coinGeckoChain struct {
+	platform string
+	nativeID string
+}
+
+// coinGeckoSeries 是一种资产在 [from, to] 内的历史价格，按时间排列
+type coinGeckoSeries struct {
+	from   time.Time
+	to     time.Time
+	points []coinGeckoPoint
+}
+
+type coinGeckoPoint struct {
+	time  time.Time
+	price decimal.Decimal
+}
+
+type seriesKey struct {
+	currency string
+	asset    Asset
+}
+
+// CoinGeckoProvider 从 CoinGecko 兼容的接口读取价格。当前价格使用 /simple 接口批量查询；
+// 历史价格使用 market_chart/range 按资产查询一段时间，保存在内存中，同一范围内的其它时间点不再请求
+type CoinGeckoProvider struct {
+	baseURL    string
+	apiKey     string
+	header     string
+	httpClient *http.Client
+	chains     map[int]coinGeckoChain
+	now        func() time.Time
+
+	mu sync.Mutex
+	// 支持的计价货币，第一次成功查询后不再更新
+	currencies map[string]bool
+	// 每种资产只保留最近读取的一段
+	series map[seriesKey]*coinGeckoSeries
+}
+
+func NewCoinGeckoProvider(cfg *config.CoinGeckoConfig, chains []config.ChainConfig) *CoinGeckoProvider {
+	cp := &CoinGeckoProvider{
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:     cfg.APIKey,
+		header:     cfg.APIKeyHeader,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		chains:     make(map[int]coinGeckoChain),
+		now:        time.Now,
+		series:     make(map[seriesKey]*coinGeckoSeries),
+	}
+	if cp.baseURL == "" {
+		cp.baseURL = defaultCoinGeckoURL
+	}
+	if cp.header == "" {
+		cp.header = defaultCoinGeckoHeader
+	}
+	if d, err := time.ParseDuration(cfg.Timeout); err == nil && d > 0 {
+		cp.httpClient.Timeout = d
+	}
+
+	for _, chain := range chains {
+		if chain.CoinGeckoPlatform != "" || chain.CoinGeckoID != "" {
+			cp.chains[chain.ChainID] = coinGeckoChain{platform: chain.CoinGeckoPlatform, nativeID: chain.CoinGeckoID}
+		}
+	}
+	return cp
+}
+
+func (cp *CoinGeckoProvider) Prices(ctx context.Context, currency string, at time.Time, assets []Asset) (map[Asset]decimal.Decimal, error) {
+	currency = strings.ToLower(currency)
+	if err := cp.checkCurrency(ctx, currency); err != nil {
+		return nil, err
+	}
+
+	if at.IsZero() {
+		return cp.currentPrices(ctx, currency, assets)
+	}
+	return cp.historicalPrices(ctx, currency, at, assets)
+}
+
+// checkCurrency 确认接口支持该计价货币，不支持的货币会返回空结果而不是错误
+func (cp *CoinGeckoProvider) checkCurrency(ctx context.Context, currency string) error {
+	cp.mu.Lock()
+	currencies := cp.currencies
+	cp.mu.Unlock()
+
+	if currencies == nil {
+		var list []string
+		if err := cp.get(ctx, "/simple/supported_vs_currencies", nil, &list); err != nil {
+			return err
+		}
+		currencies = make(map[string]bool, len(list))
+		for _, c := range list {
+			currencies[strings.ToLower(c)] = true
+		}
+
+		cp.mu.Lock()
+		cp.currencies = currencies
+		cp.mu.Unlock()
+	}
+
+	if !currencies[currency] {
+		return fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+	return nil
+}
+
+func (cp *CoinGeckoProvider) currentPrices(ctx context.Context, currency string, assets []Asset) (map[Asset]decimal.Decimal, error) {
+	prices := make(map[Asset]decimal.Decimal)
+
+	// 原生币按 coin id 一次查询，token 按链分组
+	natives := make(map[string][]Asset)
+	tokens := make(map[string][]Asset)
+	var platforms []string
+	for _, asset := range assets {
+		chain, exists := cp.chains[asset.ChainID]
+		if !exists {
+			continue
+		}
+		switch {
+		case asset.Address == "" && chain.nativeID != "":
+			natives[chain.nativeID] = append(natives[chain.nativeID], asset)
+		case asset.Address != "" && chain.platform != "":
+			if _, seen := tokens[chain.platform]; !seen {
+				platforms = append(platforms, chain.platform)
+			}
+			tokens[chain.platform] = append(tokens[chain.platform], asset)
+		}
+	}
+
+	if len(natives) > 0 {
+		ids := make([]string, 0, len(natives))
+		for id := range natives {
+			ids = append(ids, id)
+		}
+
+		var result map[string]map[string]json.Number
+		query := url.Values{"ids": {strings.Join(ids, ",")}, "vs_currencies": {currency}}
+		if err := cp.get(ctx, "/simple/price", query, &result); err != nil {
+			return nil, err
+		}
+		for id, assets := range natives {
+			if price, ok := parseQuote(result[id], currency); ok {
+				for _, asset := range assets {
+					prices[asset] = price
+				}
+			}
+		}
+	}
+
+	for _, platform := range platforms {
+		platformAssets := tokens[platform]
+		for start := 0; start < len(platformAssets); start += coinGeckoBatchSize {
+			end := start + coinGeckoBatchSize
+			if end > len(platformAssets) {
+				end = len(platformAssets)
+			}
+			batch := platformAssets[start:end]
+
+			addresses := make([]string, len(batch))
+			for i, asset := range batch {
+				addresses[i] = strings.ToLower(asset.Address)
+			}
+
+			var result map[string]map[string]json.Number
+			query := url.Values{"contract_addresses": {strings.Join(addresses, ",")}, "vs_currencies": {currency}}
+			if err := cp.get(ctx, "/simple/token_price/"+url.PathEscape(platform), query, &result); err != nil {
+				return nil, err
+			}
+
+			// 返回的地址大小写不固定
+			quotes := make(map[string]map[string]json.Number, len(result))
+			for address, quote := range result {
+				quotes[strings.ToLower(address)] = quote
+			}
+			for i, asset := range batch {
+				if price, ok := parseQuote(quotes[addresses[i]], currency); ok {
+					prices[asset] = price
+				}
+			}
+		}
+	}
+
+	return prices, nil
+}
+
+// Preload 一次读取资产在 [from, to] 内的历史价格，之后这段时间内的 Prices 直接从内存中查找
+func (cp *CoinGeckoProvider) Preload(ctx context.Context, currency string, from, to time.Time, assets []Asset) error {
+	currency = strings.ToLower(currency)
+	if err := cp.checkCurrency(ctx, currency); err != nil {
+		return err
+	}
+
+	for _, asset := range assets {
+		if cp.lookup(currency, asset, from) != nil && cp.lookup(currency, asset, to) != nil {
+			continue
+		}
+		if _, err := cp.loadSeries(ctx, currency, asset, from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// historicalPrices 取 at 之前 coinGeckoHistoryWindow 内最近的一个价格点，没有数据的资产没有价格
+func (cp *CoinGeckoProvider) historicalPrices(ctx context.Context, currency string, at time.Time, assets []Asset) (map[Asset]decimal.Decimal, error) {
+	prices := make(map[Asset]decimal.Decimal)
+	for _, asset := range assets {
+		series := cp.lookup(currency, asset, at)
+		if series == nil {
+			var err error
+			if series, err = cp.loadSeries(ctx, currency, asset, at, at); err != nil {
+				return nil, err
+			}
+			if series == nil {
+				continue
+			}
+		}
+		if price, ok := series.at(at); ok {
+			prices[asset] = price
+		}
+	}
+	return prices, nil
+}
+
+// lookup 返回已经读取的、包含 at 的历史价格
+func (cp *CoinGeckoProvider) lookup(currency string, asset Asset, at time.Time) *coinGeckoSeries {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	series := cp.series[seriesKey{currency, asset}]
+	if series == nil || at.Before(series.from) || at.After(series.to) {
+		return nil
+	}
+	return series
+}
+
+// loadSeries 读取资产在 [from - coinGeckoHistoryWindow, to] 内的价格，CoinGecko 上没有的资产返回 nil
+func (cp *CoinGeckoProvider) loadSeries(ctx context.Context, currency string, asset Asset, from, to time.Time) (*coinGeckoSeries, error) {
+	chain, exists := cp.chains[asset.ChainID]
+	if !exists {
+		return nil, nil
+	}
+
+	var path string
+	switch {
+	case asset.Address == "" && chain.nativeID != "":
+		path = "/coins/" + url.PathEscape(chain.nativeID) + "/market_chart/range"
+	case asset.Address != "" && chain.platform != "":
+		path = "/coins/" + url.PathEscape(chain.platform) + "/contract/" + strings.ToLower(asset.Address) + "/market_chart/range"
+	default:
+		return nil, nil
+	}
+
+	// 还没有到的时间没有数据，不能视为已读取
+	if now := cp.now(); to.After(now) {
+		to = now
+	}
+	start := from.Add(-coinGeckoHistoryWindow)
+	query := url.Values{
+		"vs_currency": {currency},
+		"from":        {fmt.Sprint(start.Unix())},
+		"to":          {fmt.Sprint(to.Unix())},
+	}
+
+	var chart struct {
+		Prices [][2]json.Number `json:"prices"`
+	}
+	// 没有收录的 token 记为没有价格，同样保存，避免重复请求
+	series := &coinGeckoSeries{from: from, to: to}
+	if err := cp.get(ctx, path, query, &chart); err != nil && !errors.Is(err, errCoinGeckoNotFound) {
+		return nil, err
+	}
+
+	for _, point := range chart.Prices {
+		ms, err := point[0].Float64()
+		if err != nil {
+			continue
+		}
+		price, err := parseNumber(point[1])
+		if err != nil || price.Sign() <= 0 {
+			continue
+		}
+		series.points = append(series.points, coinGeckoPoint{time: time.UnixMilli(int64(ms)), price: price})
+	}
+	sort.Slice(series.points, func(i, j int) bool { return series.points[i].time.Before(series.points[j].time) })
+
+	cp.mu.Lock()
+	cp.series[seriesKey{currency, asset}] = series
+	cp.mu.Unlock()
+	return series, nil
+}
+
+// at 返回不晚于 t、且不早于 t - coinGeckoHistoryWindow 的最后一个价格
+func (s *coinGeckoSeries) at(t time.Time) (decimal.Decimal, bool) {
+	i := sort.Search(len(s.points), func(i int) bool { return s.points[i].time.After(t) })
+	if i == 0 || s.points[i-1].time.Before(t.Add(-coinGeckoHistoryWindow)) {
+		return decimal.Decimal{}, false
+	}
+	return s.points[i-1].price, true
+}
+
+// get 请求接口并解析 JSON，非 200 的响应作为错误返回
+func (cp *CoinGeckoProvider) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	endpoint := cp.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if cp.apiKey != "" {
+		req.Header.Set(cp.header, cp.apiKey)
+	}
+
+	resp, err := cp.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("coingecko %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("coingecko %s: %w", path, errCoinGeckoNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("coingecko %s: unexpected status %s", path, resp.Status)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("coingecko %s: %w", path, err)
+	}
+	return nil
+}
+
+func parseQuote(quote map[string]json.Number, currency string) (decimal.Decimal, bool) {
+	n, exists := quote[currency]
+	if !exists {
+		return decimal.Decimal{}, false
+	}
+	price, err := parseNumber(n)
+	if err != nil || price.Sign() <= 0 {
+		return decimal.Decimal{}, false
+	}
+	return price, true
+}
+
+// parseNumber 把 JSON 数字转为精确小数，很小的价格会以 1.2e-05 这样的科学计数法出现
+func parseNumber(n json.Number) (decimal.Decimal, error) {
+	text := n.String()
+	if strings.ContainsAny(text, "eE") {
+		f, _, err := big.ParseFloat(text, 10, 256, big.ToNearestEven)
+		if err != nil {
+			return decimal.Decimal{}, err
+		}
+		text = f.Text('f', -1)
+	}
+	return decimal.Parse(text)
+}
@@ -0,0 +1,134 @@
+package price
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"wallet-tracker/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUSDC = "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"
+
+// newTestCoinGecko 启动一个本地的 CoinGecko 兼容接口
+func newTestCoinGecko(t *testing.T) (*CoinGeckoProvider, *[]string) {
+	var requests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/simple/supported_vs_currencies", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `["usd","eur"]`)
+	})
+	mux.HandleFunc("/simple/price", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ethereum", r.URL.Query().Get("ids"))
+		fmt.Fprintf(w, `{"ethereum":{"%s":2501.37}}`, r.URL.Query().Get("vs_currencies"))
+	})
+	mux.HandleFunc("/simple/token_price/ethereum", func(w http.ResponseWriter, r *http.Request) {
+		// 很小的价格以科学计数法返回，地址大小写与请求不同
+		fmt.Fprint(w, `{"0xA0B86991C6218B36C1D19D4A2E9EB0CE3606EB48":{"usd":1.0001},"0x95ad61b0a150d79219dcf64e1e6cc01f0b64c4ce":{"usd":1.2e-05}}`)
+	})
+	mux.HandleFunc("/coins/ethereum/market_chart/range", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"prices":[[1767222000000,1990.5],[1767225600000,2000],[1767229200000,2010]]}`)
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("x-cg-pro-api-key"))
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	provider := NewCoinGeckoProvider(&config.CoinGeckoConfig{
+		BaseURL:      server.URL,
+		APIKey:       "secret",
+		APIKeyHeader: "x-cg-pro-api-key",
+	}, []config.ChainConfig{
+		{ChainID: 1, CoinGeckoPlatform: "ethereum", CoinGeckoID: "ethereum"},
+	})
+	provider.now = func() time.Time { return time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC) }
+	return provider, &requests
+}
+
+func TestCoinGeckoProvider_Current(t *testing.T) {
+	provider, requests := newTestCoinGecko(t)
+	eth := NewAsset(1, "")
+	usdc := NewAsset(1, testUSDC)
+	shib := NewAsset(1, "0x95aD61b0a150d79219dCF64E1E6Cc01f0B64C4cE")
+	unknown := NewAsset(56, "")
+
+	prices, err := provider.Prices(context.Background(), "USD", time.Time{}, []Asset{eth, usdc, shib, unknown})
+	require.NoError(t, err)
+	assert.Len(t, prices, 3)
+	assert.Equal(t, "2501.37", prices[eth].String())
+	assert.Equal(t, "1.0001", prices[usdc].String())
+	assert.Equal(t, "0.000012", prices[shib].String())
+
+	_, err = provider.Prices(context.Background(), "jpy", time.Time{}, []Asset{eth})
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+
+	// 支持的货币只查询一次
+	count := 0
+	for _, path := range *requests {
+		if path == "/simple/supported_vs_currencies" {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestCoinGeckoProvider_Historical(t *testing.T) {
+	provider, _ := newTestCoinGecko(t)
+	eth := NewAsset(1, "")
+
+	// 00:30 之前最近的点是 00:00 的 2000，01:00 的点晚于 at
+	at := time.Date(2026, 1, 1, 0, 30, 0, 0, time.UTC)
+	prices, err := provider.Prices(context.Background(), "usd", at, []Asset{eth})
+	require.NoError(t, err)
+	assert.Equal(t, "2000", prices[eth].String())
+
+	// CoinGecko 没有收录的 token 没有价格
+	prices, err = provider.Prices(context.Background(), "usd", at, []Asset{NewAsset(1, testUSDC)})
+	require.NoError(t, err)
+	assert.Empty(t, prices)
+}
+
+func TestCoinGeckoProvider_Preload(t *testing.T) {
+	provider, requests := newTestCoinGecko(t)
+	ctx := context.Background()
+	eth := NewAsset(1, "")
+	usdc := NewAsset(1, testUSDC)
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, provider.Preload(ctx, "usd", from, from.Add(2*time.Hour), []Asset{eth, usdc}))
+
+	// 范围内的每个点都从已读取的数据中查找
+	for at, want := range map[time.Duration]string{
+		0:                "2000",
+		30 * time.Minute: "2000",
+		time.Hour:        "2010",
+		2 * time.Hour:    "2010",
+	} {
+		prices, err := provider.Prices(ctx, "usd", from.Add(at), []Asset{eth, usdc})
+		require.NoError(t, err)
+		assert.Equal(t, want, prices[eth].String(), at)
+		assert.NotContains(t, prices, usdc)
+	}
+
+	ranges := 0
+	for _, path := range *requests {
+		if strings.HasSuffix(path, "/market_chart/range") {
+			ranges++
+		}
+	}
+	assert.Equal(t, 2, ranges)
+
+	// 范围之外的时间重新读取
+	_, err := provider.Prices(ctx, "usd", from.Add(-30*time.Minute), []Asset{eth})
+	require.NoError(t, err)
+	assert.Equal(t, "/coins/ethereum/market_chart/range", (*requests)[len(*requests)-1])
+}
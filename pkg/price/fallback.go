@@ -0,0 +1,81 @@
+package price
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wallet-tracker/pkg/decimal"
+)
+
+// FallbackProvider 按顺序询问多个来源，前面的来源没有价格的资产交给后面的来源。
+// 单个来源失败或不支持该货币时跳过，所有来源都失败时才返回错误
+type FallbackProvider struct {
+	providers []PriceProvider
+}
+
+func NewFallbackProvider(providers ...PriceProvider) *FallbackProvider {
+	return &FallbackProvider{providers: providers}
+}
+
+// Preload 转给所有支持预加载的来源，全部失败时返回第一个错误
+func (fp *FallbackProvider) Preload(ctx context.Context, currency string, from, to time.Time, assets []Asset) error {
+	var firstErr error
+	loaded := false
+	for _, provider := range fp.providers {
+		preloader, ok := provider.(Preloader)
+		if !ok {
+			continue
+		}
+		if err := preloader.Preload(ctx, currency, from, to, assets); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		loaded = true
+	}
+
+	if !loaded {
+		return firstErr
+	}
+	return nil
+}
+
+func (fp *FallbackProvider) Prices(ctx context.Context, currency string, at time.Time, assets []Asset) (map[Asset]decimal.Decimal, error) {
+	prices := make(map[Asset]decimal.Decimal)
+	missing := assets
+
+	var firstErr error
+	answered := false
+	for _, provider := range fp.providers {
+		if len(missing) == 0 {
+			break
+		}
+
+		found, err := provider.Prices(ctx, currency, at, missing)
+		if err != nil {
+			// 不支持的货币优先级最低，只有没有其它错误时才返回
+			if firstErr == nil || errors.Is(firstErr, ErrUnsupportedCurrency) {
+				firstErr = err
+			}
+			continue
+		}
+		answered = true
+
+		var rest []Asset
+		for _, asset := range missing {
+			if price, ok := found[asset]; ok {
+				prices[asset] = price
+			} else {
+				rest = append(rest, asset)
+			}
+		}
+		missing = rest
+	}
+
+	if !answered && firstErr != nil {
+		return nil, firstErr
+	}
+	return prices, nil
+}
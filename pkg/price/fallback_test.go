@@ -0,0 +1,55 @@
+package price
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-tracker/pkg/decimal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallbackProvider(t *testing.T) {
+	ctx := context.Background()
+	eth := NewAsset(1, "")
+	usdc := NewAsset(1, testUSDC)
+
+	usdOnly := &countingProvider{err: ErrUnsupportedCurrency}
+	first := &countingProvider{prices: map[Asset]decimal.Decimal{eth: decimal.MustParse("2000")}}
+	second := &countingProvider{prices: map[Asset]decimal.Decimal{eth: decimal.MustParse("1"), usdc: decimal.MustParse("1")}}
+	provider := NewFallbackProvider(usdOnly, first, second)
+
+	prices, err := provider.Prices(ctx, "eur", time.Time{}, []Asset{eth, usdc})
+	require.NoError(t, err)
+	assert.Equal(t, "2000", prices[eth].String())
+	assert.Equal(t, "1", prices[usdc].String())
+	// 后面的来源只查询前面没有价格的资产
+	assert.Equal(t, []Asset{usdc}, second.calls[0])
+
+	// 所有来源都不支持时返回 ErrUnsupportedCurrency
+	_, err = NewFallbackProvider(usdOnly).Prices(ctx, "eur", time.Time{}, []Asset{eth})
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+}
+
+// preloadingProvider 记录 Preload 的调用
+type preloadingProvider struct {
+	countingProvider
+	preloaded []Asset
+}
+
+func (pp *preloadingProvider) Preload(ctx context.Context, currency string, from, to time.Time, assets []Asset) error {
+	pp.preloaded = append(pp.preloaded, assets...)
+	return nil
+}
+
+func TestPreload_PassesThrough(t *testing.T) {
+	source := &preloadingProvider{}
+	provider := NewCachedProvider(NewFallbackProvider(&countingProvider{}, source), nil)
+
+	eth := NewAsset(1, "")
+	now := time.Now()
+	require.NoError(t, provider.Preload(context.Background(), "usd", now.Add(-time.Hour), now, []Asset{eth}))
+	assert.Equal(t, []Asset{eth}, source.preloaded)
+}
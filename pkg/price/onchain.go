@@ -0,0 +1,70 @@
+package price
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/pkg/decimal"
+)
+
+// FeedReader 读取链上价格源在某个时间的价格，at 为零值时读取最新价格
+type FeedReader interface {
+	ReadFeed(ctx context.Context, chainID int, feed string, at time.Time) (decimal.Decimal, error)
+}
+
+// OnChainProvider 从 Chainlink 这样的链上价格源读取价格。价格源都以美元计价，
+// 其它计价货币返回 ErrUnsupportedCurrency，由后面的来源处理
+type OnChainProvider struct {
+	reader FeedReader
+	feeds  map[Asset]string
+}
+
+func NewOnChainProvider(feeds []config.PriceFeedConfig, reader FeedReader) (*OnChainProvider, error) {
+	op := &OnChainProvider{reader: reader, feeds: make(map[Asset]string)}
+	for i, feed := range feeds {
+		if feed.ChainID <= 0 || feed.Feed == "" {
+			return nil, fmt.Errorf("prices.feeds[%d]: chain_id and feed are required", i)
+		}
+		asset := NewAsset(feed.ChainID, feed.Address)
+		if _, exists := op.feeds[asset]; exists {
+			return nil, fmt.Errorf("prices.feeds[%d]: duplicate asset %d:%s", i, feed.ChainID, feed.Address)
+		}
+		op.feeds[asset] = feed.Feed
+	}
+	return op, nil
+}
+
+// Prices 逐个读取价格源，单个价格源读取失败时该资产没有价格；全部失败时返回第一个错误
+func (op *OnChainProvider) Prices(ctx context.Context, currency string, at time.Time, assets []Asset) (map[Asset]decimal.Decimal, error) {
+	if strings.ToLower(currency) != DefaultCurrency {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+
+	prices := make(map[Asset]decimal.Decimal)
+	var firstErr error
+	read := 0
+	for _, asset := range assets {
+		feed, exists := op.feeds[NewAsset(asset.ChainID, asset.Address)]
+		if !exists {
+			continue
+		}
+
+		price, err := op.reader.ReadFeed(ctx, asset.ChainID, feed, at)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("price feed %s on chain %d: %w", feed, asset.ChainID, err)
+			}
+			continue
+		}
+		prices[asset] = price
+		read++
+	}
+
+	if read == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return prices, nil
+}
@@ -0,0 +1,93 @@
+// Package price 提供 token 的法币价格，用于计算余额的价值
+package price
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"wallet-tracker/internal/config"
+	"wallet-tracker/pkg/decimal"
+)
+
+// DefaultCurrency 是未设置计价货币时使用的货币
+const DefaultCurrency = "usd"
+
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// Asset 是一条链上的一种资产，Address 为空表示原生币
+type Asset struct {
+	ChainID int
+	Address string
+}
+
+// NewAsset 规范化地址，同一资产不同大小写的地址得到相同的 Asset
+func NewAsset(chainID int, address string) Asset {
+	return Asset{ChainID: chainID, Address: strings.ToLower(address)}
+}
+
+// PriceProvider 返回资产在某个时间以 currency 计价的单价。at 为零值时返回当前价格；
+// 没有价格的资产不出现在结果中，只有整体请求失败时才返回错误
+type PriceProvider interface {
+	Prices(ctx context.Context, currency string, at time.Time, assets []Asset) (map[Asset]decimal.Decimal, error)
+}
+
+// Preloader 由能一次读取一段时间内价格的来源实现。逐点查询 [from, to] 内的历史价格之前调用，
+// 之后这些资产在该范围内的 Prices 不再逐点访问外部接口
+type Preloader interface {
+	Preload(ctx context.Context, currency string, from, to time.Time, assets []Asset) error
+}
+
+// 价格来源
+const (
+	ProviderCoinGecko = "coingecko"
+	ProviderOnChain   = "onchain"
+	ProviderStatic    = "static"
+)
+
+// New 按 prices.providers 的顺序组合价格来源。未配置 providers 时只使用价格文件，
+// 没有价格文件时所有资产都没有价格。feeds 只在使用 onchain 时需要
+func New(cfg *config.PriceConfig, chains []config.ChainConfig, feeds FeedReader) (PriceProvider, error) {
+	if len(cfg.Providers) == 0 {
+		return newStatic(cfg)
+	}
+
+	var providers []PriceProvider
+	for _, name := range cfg.Providers {
+		switch strings.ToLower(name) {
+		case ProviderCoinGecko:
+			providers = append(providers, NewCoinGeckoProvider(&cfg.CoinGecko, chains))
+		case ProviderOnChain:
+			if feeds == nil {
+				return nil, fmt.Errorf("prices.providers: %s requires blockchain access", name)
+			}
+			provider, err := NewOnChainProvider(cfg.Feeds, feeds)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, provider)
+		case ProviderStatic:
+			provider, err := newStatic(cfg)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, provider)
+		default:
+			return nil, fmt.Errorf("prices.providers: unknown provider %q", name)
+		}
+	}
+
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	return NewFallbackProvider(providers...), nil
+}
+
+func newStatic(cfg *config.PriceConfig) (PriceProvider, error) {
+	if cfg.File == "" {
+		return &StaticProvider{}, nil
+	}
+	return LoadStaticFile(cfg.File)
+}
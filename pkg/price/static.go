@@ -0,0 +1,145 @@
+package price
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"wallet-tracker/pkg/decimal"
+)
+
+// StaticFile 是价格文件的格式。价格以美元计，rates 给出 1 美元折合其它货币的数量；
+// history 中的价格在下一个点之前有效，早于第一个点的时间没有价格
+type StaticFile struct {
+	Rates  map[string]decimal.Decimal `json:"rates"`
+	Prices []StaticPrice              `json:"prices"`
+}
+
+type StaticPrice struct {
+	ChainID int `json:"chain_id"`
+	// 为空表示原生币
+	Address string           `json:"address"`
+	USD     *decimal.Decimal `json:"usd"`
+	History []StaticPoint    `json:"history"`
+}
+
+type StaticPoint struct {
+	Time time.Time       `json:"time"`
+	USD  decimal.Decimal `json:"usd"`
+}
+
+// StaticProvider 从价格文件中读取价格，适合测试和没有外部行情源的部署
+type StaticProvider struct {
+	rates  map[string]decimal.Decimal
+	prices map[Asset]staticSeries
+}
+
+type staticSeries struct {
+	current *decimal.Decimal
+	// 按时间排列
+	history []StaticPoint
+}
+
+// LoadStaticFile 读取 JSON 格式的价格文件
+func LoadStaticFile(path string) (*StaticProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file StaticFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("price file %s: %w", path, err)
+	}
+	return NewStaticProvider(&file)
+}
+
+func NewStaticProvider(file *StaticFile) (*StaticProvider, error) {
+	sp := &StaticProvider{
+		rates:  make(map[string]decimal.Decimal),
+		prices: make(map[Asset]staticSeries),
+	}
+
+	for currency, rate := range file.Rates {
+		if rate.Sign() <= 0 {
+			return nil, fmt.Errorf("price file: rate of %s must be positive", currency)
+		}
+		sp.rates[strings.ToLower(currency)] = rate
+	}
+
+	for i, p := range file.Prices {
+		if p.ChainID <= 0 {
+			return nil, fmt.Errorf("price file: prices[%d]: chain_id must be positive", i)
+		}
+		asset := NewAsset(p.ChainID, p.Address)
+		if _, exists := sp.prices[asset]; exists {
+			return nil, fmt.Errorf("price file: prices[%d]: duplicate asset %d:%s", i, p.ChainID, p.Address)
+		}
+
+		history := append([]StaticPoint(nil), p.History...)
+		sort.Slice(history, func(a, b int) bool { return history[a].Time.Before(history[b].Time) })
+		sp.prices[asset] = staticSeries{current: p.USD, history: history}
+	}
+
+	return sp, nil
+}
+
+func (sp *StaticProvider) Prices(ctx context.Context, currency string, at time.Time, assets []Asset) (map[Asset]decimal.Decimal, error) {
+	rate, err := sp.rate(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make(map[Asset]decimal.Decimal)
+	for _, asset := range assets {
+		series, exists := sp.prices[NewAsset(asset.ChainID, asset.Address)]
+		if !exists {
+			continue
+		}
+		if usd, ok := series.at(at); ok {
+			prices[asset] = usd.Mul(rate)
+		}
+	}
+	return prices, nil
+}
+
+// rate 返回 1 美元折合 currency 的数量
+func (sp *StaticProvider) rate(currency string) (decimal.Decimal, error) {
+	currency = strings.ToLower(currency)
+	if currency == DefaultCurrency {
+		return decimal.NewFromInt64(1), nil
+	}
+	rate, exists := sp.rates[currency]
+	if !exists {
+		return decimal.Decimal{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+	return rate, nil
+}
+
+// at 返回 t 时的美元价格，t 为零值时优先使用当前价格
+func (s staticSeries) at(t time.Time) (decimal.Decimal, bool) {
+	if t.IsZero() {
+		if s.current != nil {
+			return *s.current, true
+		}
+		if len(s.history) == 0 {
+			return decimal.Decimal{}, false
+		}
+		return s.history[len(s.history)-1].USD, true
+	}
+
+	// 最后一个不晚于 t 的点
+	i := sort.Search(len(s.history), func(i int) bool { return s.history[i].Time.After(t) })
+	if i == 0 {
+		// 没有历史时，当前价格对所有时间都有效
+		if len(s.history) == 0 && s.current != nil {
+			return *s.current, true
+		}
+		return decimal.Decimal{}, false
+	}
+	return s.history[i-1].USD, true
+}
@@ -0,0 +1,73 @@
+package price
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPriceFile = `{
+  "rates": {"eur": "0.9"},
+  "prices": [
+    {"chain_id": 1, "usd": "2500", "history": [
+      {"time": "2026-02-01T00:00:00Z", "usd": "2200"},
+      {"time": "2026-01-01T00:00:00Z", "usd": "2000"}
+    ]},
+    {"chain_id": 1, "address": "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", "usd": "1"}
+  ]
+}`
+
+func loadTestPrices(t *testing.T) *StaticProvider {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	require.NoError(t, os.WriteFile(path, []byte(testPriceFile), 0o644))
+
+	provider, err := LoadStaticFile(path)
+	require.NoError(t, err)
+	return provider
+}
+
+func TestStaticProvider(t *testing.T) {
+	provider := loadTestPrices(t)
+	eth := NewAsset(1, "")
+	usdc := Asset{ChainID: 1, Address: "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"}
+	unknown := NewAsset(56, "")
+	assets := []Asset{eth, usdc, unknown}
+
+	prices, err := provider.Prices(context.Background(), "usd", time.Time{}, assets)
+	require.NoError(t, err)
+	assert.Equal(t, "2500", prices[eth].String())
+	assert.Equal(t, "1", prices[usdc].String())
+	assert.NotContains(t, prices, unknown)
+
+	// 历史价格在下一个点之前有效，没有历史的资产使用当前价格
+	prices, err = provider.Prices(context.Background(), "USD", time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), assets)
+	require.NoError(t, err)
+	assert.Equal(t, "2000", prices[eth].String())
+	assert.Equal(t, "1", prices[usdc].String())
+
+	// 早于第一个点没有价格
+	prices, err = provider.Prices(context.Background(), "usd", time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), assets)
+	require.NoError(t, err)
+	assert.NotContains(t, prices, eth)
+
+	prices, err = provider.Prices(context.Background(), "eur", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), assets)
+	require.NoError(t, err)
+	assert.Equal(t, "1980", prices[eth].String())
+	assert.Equal(t, "0.9", prices[usdc].String())
+
+	_, err = provider.Prices(context.Background(), "jpy", time.Time{}, assets)
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+}
+
+func TestNewStaticProvider_Invalid(t *testing.T) {
+	_, err := NewStaticProvider(&StaticFile{Prices: []StaticPrice{{ChainID: 0}}})
+	assert.Error(t, err)
+
+	_, err = NewStaticProvider(&StaticFile{Prices: []StaticPrice{{ChainID: 1}, {ChainID: 1}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,257 @@
+// Package spam 根据启发式规则给 token 打分，识别空投的垃圾 token 和仿冒 token
+package spam
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// 评分达到 Threshold 的 token 视为垃圾 token
+const Threshold = 50
+
+// 打分原因
+const (
+	ReasonURL               = "url_in_metadata"
+	ReasonClaimText         = "claim_text"
+	ReasonUnverified        = "unverified"
+	ReasonImpersonation     = "impersonates_known_token"
+	ReasonNativeSymbol      = "native_symbol"
+	ReasonZeroValueTransfer = "zero_value_transfer"
+	ReasonUnsolicited       = "unsolicited_transfer"
+	ReasonNonStandard       = "non_standard_behavior"
+	ReasonDenyList          = "deny_list"
+)
+
+// 从转账记录等行为中观察到的信号，由调用方记录在 token 上
+const (
+	SignalZeroValueTransfer   = "zero_value_transfer"
+	SignalUnsolicitedTransfer = "unsolicited_transfer"
+	SignalNonStandardTransfer = "non_standard_transfer"
+)
+
+// 名单
+const (
+	ListAllow = "allow"
+	ListDeny  = "deny"
+)
+
+var weights = map[string]int{
+	ReasonURL:               60,
+	ReasonClaimText:         30,
+	ReasonImpersonation:     50,
+	ReasonNativeSymbol:      30,
+	ReasonZeroValueTransfer: 40,
+	ReasonNonStandard:       20,
+	ReasonUnsolicited:       15,
+	ReasonUnverified:        10,
+}
+
+var (
+	urlPattern = regexp.MustCompile(`(?i)(https?://|www\.|t\.me/)`)
+	// 不带协议的域名。很多正常项目的名字就是域名（yearn.finance），只有同时出现 claim 类文案时才算 URL
+	domainPattern = regexp.MustCompile(`(?i)[a-z0-9-]+\.(com|io|org|net|xyz|app|finance|site|online|top|vip|fun|cc|co|me|gift|claims?|network|pro|info|biz|link|club|live)\b`)
+	// claim 类文案：Visit xxx to claim、Reward、Airdrop 等
+	claimPattern = regexp.MustCompile(`(?i)(claim|visit|reward|airdrop|voucher|free\s|bonus|redeem|eligible)`)
+	// 比较 symbol 时忽略大小写、空白和常见的零宽字符
+	symbolCleaner = regexp.MustCompile(`[\s.$\x{200B}\x{200C}\x{200D}\x{FEFF}]`)
+)
+
+// Token 是打分需要的 token 信息
+type Token struct {
+	ChainID int
+	Address string
+	Symbol  string
+	Name    string
+	// 管理员核验过或出现在导入的 Token List 中
+	Trusted bool
+	// 管理员名单：allow、deny 或空
+	List string
+	// 元数据缺失等警告，见 blockchain.Warning*
+	Warnings []string
+	// 观察到的行为信号，见 Signal*
+	Signals []string
+}
+
+// Result 是打分结果
+type Result struct {
+	Score   int
+	Reasons []string
+	Spam    bool
+}
+
+// Scorer 保存打分需要的参考数据：知名 token 的地址和配置中的名单，可以并发使用
+type Scorer struct {
+	mu sync.RWMutex
+	// 规范化的 symbol -> 链 -> 合法地址
+	known map[string]map[int]map[common.Address]bool
+	// 链 -> 原生币的规范化 symbol
+	natives map[int]string
+
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+// NewScorer 创建 Scorer，allow / deny 的条目格式为 "chainId:address"
+func NewScorer(allow, deny []string) (*Scorer, error) {
+	s := &Scorer{
+		known:   make(map[string]map[int]map[common.Address]bool),
+		natives: make(map[int]string),
+		allow:   make(map[string]bool),
+		deny:    make(map[string]bool),
+	}
+
+	for _, entries := range []struct {
+		list  []string
+		index map[string]bool
+	}{{allow, s.allow}, {deny, s.deny}} {
+		for _, entry := range entries.list {
+			key, err := parseListEntry(entry)
+			if err != nil {
+				return nil, err
+			}
+			entries.index[key] = true
+		}
+	}
+
+	return s, nil
+}
+
+func parseListEntry(entry string) (string, error) {
+	parts := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("spam list entry %q: expected chainId:address", entry)
+	}
+	chainID, err := strconv.Atoi(parts[0])
+	if err != nil || chainID <= 0 {
+		return "", fmt.Errorf("spam list entry %q: invalid chain ID", entry)
+	}
+	if !common.IsHexAddress(parts[1]) {
+		return "", fmt.Errorf("spam list entry %q: invalid address", entry)
+	}
+	return listKey(chainID, parts[1]), nil
+}
+
+func listKey(chainID int, address string) string {
+	return fmt.Sprintf("%d:%s", chainID, strings.ToLower(common.HexToAddress(address).Hex()))
+}
+
+func normalizeSymbol(symbol string) string {
+	return strings.ToUpper(symbolCleaner.ReplaceAllString(symbol, ""))
+}
+
+// SetKnownTokens 替换知名 token 的参考数据；同一链上 symbol 相同但地址不同的 token 视为仿冒。
+// natives 是各链原生币的 symbol，同一链上不在 tokens 中的 ERC-20 使用这个 symbol 时加分
+func (s *Scorer) SetKnownTokens(tokens []Token, natives map[int]string) {
+	known := make(map[string]map[int]map[common.Address]bool)
+	for _, token := range tokens {
+		symbol := normalizeSymbol(token.Symbol)
+		if symbol == "" {
+			continue
+		}
+		if known[symbol] == nil {
+			known[symbol] = make(map[int]map[common.Address]bool)
+		}
+		if known[symbol][token.ChainID] == nil {
+			known[symbol][token.ChainID] = make(map[common.Address]bool)
+		}
+		known[symbol][token.ChainID][common.HexToAddress(token.Address)] = true
+	}
+
+	nativeSymbols := make(map[int]string)
+	for chainID, symbol := range natives {
+		nativeSymbols[chainID] = normalizeSymbol(symbol)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.known = known
+	s.natives = nativeSymbols
+}
+
+// Score 给 token 打分。allow 名单中的 token 不标记，deny 名单中的 token 总是标记；
+// 可信的 token 只检查名单
+func (s *Scorer) Score(token Token) Result {
+	key := listKey(token.ChainID, token.Address)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if token.List == ListAllow || (token.List == "" && s.allow[key]) {
+		return Result{Reasons: []string{}}
+	}
+	if token.List == ListDeny || s.deny[key] {
+		return Result{Score: 100, Reasons: []string{ReasonDenyList}, Spam: true}
+	}
+
+	result := Result{Reasons: []string{}}
+	if token.Trusted {
+		return result
+	}
+
+	add := func(reason string) {
+		result.Reasons = append(result.Reasons, reason)
+		result.Score += weights[reason]
+	}
+
+	metadata := token.Symbol + " " + token.Name
+	claimText := claimPattern.MatchString(metadata)
+	if urlPattern.MatchString(metadata) || (claimText && domainPattern.MatchString(metadata)) {
+		add(ReasonURL)
+	}
+	if claimText {
+		add(ReasonClaimText)
+	}
+	if reason := s.impersonation(token); reason != "" {
+		add(reason)
+	}
+
+	signals := make(map[string]bool)
+	for _, signal := range token.Signals {
+		signals[signal] = true
+	}
+	// 地址投毒的零金额转账由真实合约发出，任何 token 都可能出现，
+	// 只有元数据可疑或仿冒时才计入，不能单独把 token 标记为垃圾
+	if signals[SignalZeroValueTransfer] && len(result.Reasons) > 0 {
+		add(ReasonZeroValueTransfer)
+	}
+	if signals[SignalNonStandardTransfer] || len(token.Warnings) > 0 {
+		add(ReasonNonStandard)
+	}
+	if signals[SignalUnsolicitedTransfer] {
+		add(ReasonUnsolicited)
+	}
+	add(ReasonUnverified)
+
+	if result.Score > 100 {
+		result.Score = 100
+	}
+	result.Spam = result.Score >= Threshold
+	return result
+}
+
+// impersonation 判断 token 是否使用了知名 token 或原生币的 symbol 而地址不是其合法地址，返回对应的原因。
+// L2 上的 ETH 这类桥接的原生币是正常的 ERC-20，所以原生币 symbol 只是一个加分项，不能单独标记
+func (s *Scorer) impersonation(token Token) string {
+	symbol := normalizeSymbol(token.Symbol)
+	if symbol == "" {
+		return ""
+	}
+
+	// 只有在同一条链上有合法地址时才能判断；其它链上的同名 token 可能是桥接版本
+	if addresses, exists := s.known[symbol][token.ChainID]; exists {
+		if addresses[common.HexToAddress(token.Address)] {
+			return ""
+		}
+		return ReasonImpersonation
+	}
+
+	if s.natives[token.ChainID] == symbol {
+		return ReasonNativeSymbol
+	}
+	return ""
+}
@@ -0,0 +1,169 @@
+package spam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	usdc     = "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"
+	fakeUSDC = "0x1111111111111111111111111111111111111111"
+	other    = "0x2222222222222222222222222222222222222222"
+	// Optimism 上以 ERC-20 形式存在的 ETH
+	optimismETH = "0xDeadDeAddeAddEAddeadDEaDDEAdDeaDDeAD0000"
+)
+
+func newTestScorer(t *testing.T) *Scorer {
+	scorer, err := NewScorer([]string{"1:" + other}, []string{"1:0x3333333333333333333333333333333333333333"})
+	require.NoError(t, err)
+	scorer.SetKnownTokens([]Token{
+		{ChainID: 1, Address: usdc, Symbol: "USDC"},
+		{ChainID: 10, Address: optimismETH, Symbol: "ETH"},
+	}, map[int]string{1: "ETH", 10: "ETH", 56: "BNB"})
+	return scorer
+}
+
+func TestScore(t *testing.T) {
+	scorer := newTestScorer(t)
+
+	tests := []struct {
+		name    string
+		token   Token
+		spam    bool
+		reasons []string
+	}{
+		{
+			name:    "url in name",
+			token:   Token{ChainID: 1, Address: fakeUSDC, Symbol: "$ Reward", Name: "Visit usdc-gift.com to claim"},
+			spam:    true,
+			reasons: []string{ReasonURL, ReasonClaimText, ReasonUnverified},
+		},
+		{
+			name:    "link without a domain suffix",
+			token:   Token{ChainID: 1, Address: fakeUSDC, Symbol: "ABC", Name: "t.me/abcgroup"},
+			spam:    true,
+			reasons: []string{ReasonURL, ReasonUnverified},
+		},
+		{
+			name:    "project named after its domain",
+			token:   Token{ChainID: 1, Address: fakeUSDC, Symbol: "YFI", Name: "yearn.finance", Signals: []string{SignalZeroValueTransfer}},
+			spam:    false,
+			reasons: []string{ReasonUnverified},
+		},
+		{
+			name:    "domain in the symbol",
+			token:   Token{ChainID: 1, Address: fakeUSDC, Symbol: "Aave.io", Name: "Aave Token"},
+			spam:    false,
+			reasons: []string{ReasonUnverified},
+		},
+		{
+			name:    "network in the name",
+			token:   Token{ChainID: 1, Address: fakeUSDC, Symbol: "LDO", Name: "Lido.network DAO"},
+			spam:    false,
+			reasons: []string{ReasonUnverified},
+		},
+		{
+			name:    "impersonates USDC",
+			token:   Token{ChainID: 1, Address: fakeUSDC, Symbol: "usdc", Name: "USD Coin"},
+			spam:    true,
+			reasons: []string{ReasonImpersonation, ReasonUnverified},
+		},
+		{
+			name:    "native coin symbol",
+			token:   Token{ChainID: 1, Address: fakeUSDC, Symbol: "E T H", Name: "Ether"},
+			spam:    false,
+			reasons: []string{ReasonNativeSymbol, ReasonUnverified},
+		},
+		{
+			name:    "native coin symbol with zero value transfers",
+			token:   Token{ChainID: 1, Address: fakeUSDC, Symbol: "ETH", Name: "Ether", Signals: []string{SignalZeroValueTransfer}},
+			spam:    true,
+			reasons: []string{ReasonNativeSymbol, ReasonZeroValueTransfer, ReasonUnverified},
+		},
+		{
+			name:    "verified token with the native symbol",
+			token:   Token{ChainID: 10, Address: optimismETH, Symbol: "ETH", Name: "Ether"},
+			spam:    false,
+			reasons: []string{ReasonUnverified},
+		},
+		{
+			name:    "another token with the native symbol next to a verified one",
+			token:   Token{ChainID: 10, Address: fakeUSDC, Symbol: "ETH", Name: "Ether"},
+			spam:    true,
+			reasons: []string{ReasonImpersonation, ReasonUnverified},
+		},
+		{
+			name:    "bridged native coin of another chain",
+			token:   Token{ChainID: 56, Address: fakeUSDC, Symbol: "ETH", Name: "Ethereum Token"},
+			spam:    false,
+			reasons: []string{ReasonUnverified},
+		},
+		{
+			name:    "same symbol on a chain without a known address",
+			token:   Token{ChainID: 10, Address: fakeUSDC, Symbol: "USDC", Name: "USD Coin"},
+			spam:    false,
+			reasons: []string{ReasonUnverified},
+		},
+		{
+			name:    "the real USDC",
+			token:   Token{ChainID: 1, Address: usdc, Symbol: "USDC", Name: "USD Coin"},
+			spam:    false,
+			reasons: []string{ReasonUnverified},
+		},
+		{
+			name:    "zero value transfers of a token with clean metadata",
+			token:   Token{ChainID: 1, Address: fakeUSDC, Symbol: "ABC", Name: "ABC", Signals: []string{SignalZeroValueTransfer, SignalUnsolicitedTransfer}},
+			spam:    false,
+			reasons: []string{ReasonUnsolicited, ReasonUnverified},
+		},
+		{
+			name:    "zero value transfers with claim text",
+			token:   Token{ChainID: 1, Address: fakeUSDC, Symbol: "ABC", Name: "ABC Reward", Signals: []string{SignalZeroValueTransfer}},
+			spam:    true,
+			reasons: []string{ReasonClaimText, ReasonZeroValueTransfer, ReasonUnverified},
+		},
+		{
+			name:    "trusted token",
+			token:   Token{ChainID: 1, Address: fakeUSDC, Symbol: "www.x.com", Trusted: true},
+			spam:    false,
+			reasons: []string{},
+		},
+		{
+			name:    "allow list",
+			token:   Token{ChainID: 1, Address: other, Symbol: "claim.xyz"},
+			spam:    false,
+			reasons: []string{},
+		},
+		{
+			name:    "admin deny overrides trust",
+			token:   Token{ChainID: 1, Address: usdc, Symbol: "USDC", Trusted: true, List: ListDeny},
+			spam:    true,
+			reasons: []string{ReasonDenyList},
+		},
+		{
+			name:    "configured deny list",
+			token:   Token{ChainID: 1, Address: "0x3333333333333333333333333333333333333333", Symbol: "OK"},
+			spam:    true,
+			reasons: []string{ReasonDenyList},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := scorer.Score(tt.token)
+			assert.Equal(t, tt.spam, result.Spam)
+			assert.Equal(t, tt.reasons, result.Reasons)
+			assert.LessOrEqual(t, result.Score, 100)
+		})
+	}
+}
+
+func TestNewScorer_InvalidEntry(t *testing.T) {
+	_, err := NewScorer([]string{"0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"}, nil)
+	assert.Error(t, err)
+
+	_, err = NewScorer(nil, []string{"1:not-an-address"})
+	assert.Error(t, err)
+}
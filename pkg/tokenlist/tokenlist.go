@@ -0,0 +1,181 @@
+// Package tokenlist 解析并校验 tokenlists.org 格式的 Token List JSON
+package tokenlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 与 tokenlists.org JSON schema 一致的限制
+const (
+	maxNameLength      = 30
+	maxTokens          = 10000
+	maxTokenNameLength = 40
+	maxSymbolLength    = 20
+	maxDecimals        = 255
+)
+
+var (
+	listNamePattern = regexp.MustCompile(`^[\w ]+$`)
+	addressPattern  = regexp.MustCompile(`^0x[a-fA-F0-9]{40}$`)
+	symbolPattern   = regexp.MustCompile(`^\S+$`)
+)
+
+type Version struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare 按语义化版本比较，返回 -1、0 或 1
+func (v Version) Compare(o Version) int {
+	for _, diff := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if diff < 0 {
+			return -1
+		}
+		if diff > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+type Token struct {
+	ChainID  int      `json:"chainId"`
+	Address  string   `json:"address"`
+	Name     string   `json:"name"`
+	Symbol   string   `json:"symbol"`
+	Decimals int      `json:"decimals"`
+	LogoURI  string   `json:"logoURI,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+type List struct {
+	Name      string    `json:"name"`
+	Timestamp time.Time `json:"timestamp"`
+	Version   Version   `json:"version"`
+	Tokens    []Token   `json:"tokens"`
+	LogoURI   string    `json:"logoURI,omitempty"`
+	Keywords  []string  `json:"keywords,omitempty"`
+}
+
+// ValidationError 列出所有不符合 schema 的字段
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid token list: " + strings.Join(e.Problems, "; ")
+}
+
+// Parse 解析并校验 Token List
+func Parse(data []byte) (*List, error) {
+	// 先检查必填字段是否存在，零值无法区分缺失和 0
+	var raw struct {
+		Name      *string          `json:"name"`
+		Timestamp *string          `json:"timestamp"`
+		Version   *json.RawMessage `json:"version"`
+		Tokens    *json.RawMessage `json:"tokens"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, &ValidationError{Problems: []string{err.Error()}}
+	}
+
+	var problems []string
+	if raw.Name == nil {
+		problems = append(problems, "name is required")
+	}
+	if raw.Timestamp == nil {
+		problems = append(problems, "timestamp is required")
+	}
+	if raw.Version == nil {
+		problems = append(problems, "version is required")
+	}
+	if raw.Tokens == nil {
+		problems = append(problems, "tokens is required")
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	var list List
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, &ValidationError{Problems: []string{err.Error()}}
+	}
+
+	if err := list.Validate(); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Validate 按 tokenlists.org schema 校验列表
+func (l *List) Validate() error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if l.Name == "" || len(l.Name) > maxNameLength || !listNamePattern.MatchString(l.Name) {
+		addf("name must be 1-%d letters, digits, underscores or spaces", maxNameLength)
+	}
+	if l.Timestamp.IsZero() {
+		addf("timestamp must be an RFC 3339 date-time")
+	}
+	if l.Version.Major < 0 || l.Version.Minor < 0 || l.Version.Patch < 0 {
+		addf("version numbers must not be negative")
+	}
+	if len(l.Tokens) == 0 || len(l.Tokens) > maxTokens {
+		addf("tokens must contain 1-%d entries", maxTokens)
+	}
+
+	seen := make(map[string]bool)
+	for i, token := range l.Tokens {
+		if token.ChainID < 1 {
+			addf("tokens[%d].chainId must be a positive integer", i)
+		}
+		if !addressPattern.MatchString(token.Address) {
+			addf("tokens[%d].address must be a 0x-prefixed 20-byte hex address", i)
+		}
+		if token.Decimals < 0 || token.Decimals > maxDecimals {
+			addf("tokens[%d].decimals must be between 0 and %d", i, maxDecimals)
+		}
+		if len(token.Name) > maxTokenNameLength {
+			addf("tokens[%d].name must be at most %d characters", i, maxTokenNameLength)
+		}
+		if token.Symbol == "" || len(token.Symbol) > maxSymbolLength || !symbolPattern.MatchString(token.Symbol) {
+			addf("tokens[%d].symbol must be 1-%d characters without spaces", i, maxSymbolLength)
+		}
+
+		key := fmt.Sprintf("%d:%s", token.ChainID, strings.ToLower(token.Address))
+		if seen[key] {
+			addf("tokens[%d] duplicates chain %d address %s", i, token.ChainID, token.Address)
+		}
+		seen[key] = true
+
+		// 问题太多时没有必要全部列出
+		if len(problems) >= 20 {
+			addf("...")
+			break
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// IsValidationError 判断错误是否由列表内容不合法引起
+func IsValidationError(err error) bool {
+	var validationErr *ValidationError
+	return errors.As(err, &validationErr)
+}
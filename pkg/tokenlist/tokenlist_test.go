@@ -0,0 +1,65 @@
+package tokenlist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validList = `{
+  "name": "Test List",
+  "timestamp": "2024-01-01T00:00:00.000Z",
+  "version": {"major": 1, "minor": 2, "patch": 3},
+  "tokens": [
+    {"chainId": 1, "address": "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", "name": "USD Coin", "symbol": "USDC", "decimals": 6, "logoURI": "ipfs://usdc"},
+    {"chainId": 56, "address": "0x8AC76a51cc950d9822D68b83fE1Ad97B32Cd580d", "name": "USD Coin", "symbol": "USDC", "decimals": 18}
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	list, err := Parse([]byte(validList))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Test List", list.Name)
+	assert.Equal(t, "1.2.3", list.Version.String())
+	require.Len(t, list.Tokens, 2)
+	assert.Equal(t, 6, list.Tokens[0].Decimals)
+	assert.Equal(t, "ipfs://usdc", list.Tokens[0].LogoURI)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		problem string
+	}{
+		{"not json", `{`, "invalid token list"},
+		{"missing fields", `{"name": "X"}`, "timestamp is required"},
+		{"bad name", `{"name": "bad/name", "timestamp": "2024-01-01T00:00:00Z", "version": {"major": 1, "minor": 0, "patch": 0}, "tokens": [{"chainId": 1, "address": "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", "symbol": "A", "decimals": 1}]}`, "name must be"},
+		{"empty tokens", `{"name": "X", "timestamp": "2024-01-01T00:00:00Z", "version": {"major": 1, "minor": 0, "patch": 0}, "tokens": []}`, "tokens must contain"},
+		{"bad address", `{"name": "X", "timestamp": "2024-01-01T00:00:00Z", "version": {"major": 1, "minor": 0, "patch": 0}, "tokens": [{"chainId": 1, "address": "0x123", "symbol": "A", "decimals": 1}]}`, "tokens[0].address"},
+		{"bad decimals", `{"name": "X", "timestamp": "2024-01-01T00:00:00Z", "version": {"major": 1, "minor": 0, "patch": 0}, "tokens": [{"chainId": 1, "address": "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", "symbol": "A", "decimals": 300}]}`, "tokens[0].decimals"},
+		{"duplicate token", `{"name": "X", "timestamp": "2024-01-01T00:00:00Z", "version": {"major": 1, "minor": 0, "patch": 0}, "tokens": [
+			{"chainId": 1, "address": "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", "symbol": "A", "decimals": 1},
+			{"chainId": 1, "address": "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", "symbol": "A", "decimals": 1}]}`, "tokens[1] duplicates"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			require.Error(t, err)
+			assert.True(t, IsValidationError(err))
+			assert.Contains(t, err.Error(), tt.problem)
+		})
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	v := Version{Major: 1, Minor: 2, Patch: 3}
+
+	assert.Equal(t, 0, v.Compare(Version{1, 2, 3}))
+	assert.Equal(t, 1, v.Compare(Version{1, 2, 2}))
+	assert.Equal(t, -1, v.Compare(Version{1, 10, 0}))
+	assert.Equal(t, -1, v.Compare(Version{2, 0, 0}))
+}